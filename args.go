@@ -0,0 +1,45 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// formatArg converts a command argument to the bulk string that redigo would
+// write in the wire, so the mock can handle it like the Redis server would
+func formatArg(arg interface{}) []byte {
+	switch arg := arg.(type) {
+	case string:
+		return []byte(arg)
+	case []byte:
+		return arg
+	case int:
+		return []byte(strconv.FormatInt(int64(arg), 10))
+	case int64:
+		return []byte(strconv.FormatInt(arg, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(arg, 'g', -1, 64))
+	case bool:
+		if arg {
+			return []byte("1")
+		}
+		return []byte("0")
+	case nil:
+		return []byte{}
+	case redis.Argument:
+		return formatArg(arg.RedisArg())
+	default:
+		return []byte(fmt.Sprint(arg))
+	}
+}
+
+// argString works like formatArg, but returns the argument as a string
+func argString(arg interface{}) string {
+	return string(formatArg(arg))
+}
@@ -0,0 +1,63 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+// CallHandler dynamic handles the response for the provided call, like
+// ResponseHandler but with access to the context of the call (see HandleCtx)
+type CallHandler func(call *Call) (interface{}, error)
+
+// Call is a command execution handled by a CallHandler. The handler runs while
+// the connection is locked, so it must not call the connection methods
+type Call struct {
+	Name    string                 // Name of the command
+	Args    []interface{}          // Arguments of the command
+	Index   int                    // Number of previous calls of the registered command, starting at 0
+	History []CommandCall          // Commands previously executed by the connection, oldest first
+	Store   map[string]interface{} // Values kept by the connection between calls, shared by all its handlers
+}
+
+// CommandCall is a command executed by the connection with its reply, like
+// the ones in the history of a Call
+type CommandCall struct {
+	Name  string        // Name of the command
+	Args  []interface{} // Arguments of the command
+	Reply interface{}   // Reply returned by the command
+	Err   error         // Error returned by the command
+}
+
+// newCall builds the context of the command execution, creating the store of
+// the connection when needed
+//
+// Caller must hold c.mu.
+func (c *Conn) newCall(commandName string, args []interface{}, index int) *Call {
+	if c.store == nil {
+		c.store = make(map[string]interface{})
+	}
+
+	return &Call{
+		Name:    commandName,
+		Args:    args,
+		Index:   index,
+		History: c.history[:len(c.history):len(c.history)],
+		Store:   c.store,
+	}
+}
+
+// recordCall appends the executed command to the history of the connection
+//
+// Caller must hold c.mu.
+func (c *Conn) recordCall(commandName string, args []interface{}, reply interface{}, err error) {
+	c.history = append(c.history, CommandCall{Name: commandName, Args: args, Reply: reply, Err: err})
+}
+
+// History returns the commands executed by the connection, oldest first
+func (c *Conn) History() []CommandCall {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	history := make([]CommandCall, len(c.history))
+	copy(history, c.history)
+	return history
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestHandleCtx(t *testing.T) {
+	c := NewConn()
+	c.Command("SELECT", 1).Expect("OK")
+	c.GenericCommand("INCR").HandleCtx(func(call *Call) (interface{}, error) {
+		key := fmt.Sprint(call.Args[0])
+		for _, previous := range call.History {
+			if previous.Name == "SELECT" {
+				key = fmt.Sprint(previous.Args[0]) + ":" + key
+			}
+		}
+
+		n, _ := call.Store[key].(int64)
+		n++
+		call.Store[key] = n
+		return n, nil
+	})
+	c.Command("GET", "k").HandleCtx(func(call *Call) (interface{}, error) {
+		if call.Index == 0 {
+			return nil, nil
+		}
+		return fmt.Sprintf("call %d", call.Index), nil
+	})
+
+	for i := int64(1); i <= 2; i++ {
+		if value, err := redis.Int64(c.Do("INCR", "counter")); err != nil || value != i {
+			t.Errorf("Expected %d and got %d (%v)", i, value, err)
+		}
+	}
+	if _, err := c.Do("SELECT", 1); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := redis.Int64(c.Do("INCR", "counter")); err != nil || value != 1 {
+		t.Errorf("Expected 1 in the selected database and got %d (%v)", value, err)
+	}
+
+	if value, err := c.Do("GET", "k"); err != nil || value != nil {
+		t.Errorf("Expected nil in the first call and got %v (%v)", value, err)
+	}
+	if value, err := redis.String(c.Do("GET", "k")); err != nil || value != "call 1" {
+		t.Errorf("Expected 'call 1' and got '%s' (%v)", value, err)
+	}
+
+	history := c.History()
+	if len(history) != 6 {
+		t.Fatalf("Expected 6 commands in the history and got %d", len(history))
+	}
+	if history[3].Name != "INCR" || history[3].Reply != int64(1) {
+		t.Errorf("Unexpected command in the history %+v", history[3])
+	}
+
+	c.Clear()
+	if history := c.History(); len(history) != 0 {
+		t.Errorf("Expected empty history after Clear and got %v", history)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock used by the connection for time-based behaviours. It
+// starts at the current time and only moves when the test advances it
+type Clock struct {
+	start time.Time  // Time when the clock was created
+	now   time.Time  // Current time of the clock
+	mu    sync.Mutex // Hold while accessing now
+}
+
+// NewClock returns a new fake clock starting at the given time
+func NewClock(start time.Time) *Clock {
+	return &Clock{
+		start: start,
+		now:   start,
+	}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Elapsed returns the time passed since the clock was created
+func (c *Clock) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now.Sub(c.start)
+}
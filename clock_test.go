@@ -0,0 +1,29 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("Expected clock to start at '%s' and got '%s'", start, now)
+	}
+
+	clock.Advance(time.Minute)
+
+	if now := clock.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected clock to be at '%s' and got '%s'", start.Add(time.Minute), now)
+	}
+
+	if elapsed := clock.Elapsed(); elapsed != time.Minute {
+		t.Errorf("Expected 1 minute elapsed and got '%s'", elapsed)
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// clusterSlots is the number of hash slots of a Redis Cluster
+const clusterSlots = 16384
+
+// Cluster simulates a Redis Cluster composed of nodes, where each node is a
+// Pool with its own registered commands. Commands with keys are routed by hash
+// slot: a node replies MOVED when the slot is served by another node, ASK
+// while the slot is migrated to another node (see MigrateSlot) and CROSSSLOT
+// when the keys hash to different slots. The CLUSTER SLOTS, SHARDS, NODES,
+// KEYSLOT, MYID and INFO commands are answered with the cluster topology,
+// unless they are registered.
+type Cluster struct {
+	nodes     []*ClusterNode             // Nodes in the order they were created
+	slots     [clusterSlots]*ClusterNode // Node serving each slot
+	migrating map[int]*ClusterNode       // Nodes importing the slots being migrated
+	mu        sync.Mutex                 // Hold while accessing any mutable fields
+}
+
+// ClusterNode is a node of the simulated cluster. Commands answered by the
+// node are registered in the embedded Pool, and the node connections can be
+// dialed with it or with Cluster.Dial
+type ClusterNode struct {
+	*Pool          // Pool where the commands answered by the node are registered
+	ID      string // Node identifier, derived from the address
+	Addr    string // Node address, returned in redirections
+	cluster *Cluster
+}
+
+// NewCluster returns a cluster with one node for each address, splitting the
+// hash slots evenly among them
+func NewCluster(addrs ...string) *Cluster {
+	cl := &Cluster{migrating: make(map[int]*ClusterNode)}
+
+	for _, addr := range addrs {
+		digest := sha1.Sum([]byte(addr))
+		node := &ClusterNode{
+			Pool:    NewPool(),
+			ID:      hex.EncodeToString(digest[:]),
+			Addr:    addr,
+			cluster: cl,
+		}
+		node.Conn.node = node
+		cl.nodes = append(cl.nodes, node)
+	}
+
+	for i, node := range cl.nodes {
+		from := i * clusterSlots / len(cl.nodes)
+		to := (i+1)*clusterSlots/len(cl.nodes) - 1
+		for slot := from; slot <= to; slot++ {
+			cl.slots[slot] = node
+		}
+	}
+	return cl
+}
+
+// Nodes returns the nodes of the cluster
+func (cl *Cluster) Nodes() []*ClusterNode {
+	nodes := make([]*ClusterNode, len(cl.nodes))
+	copy(nodes, cl.nodes)
+	return nodes
+}
+
+// Node returns the node with the address, or nil if there's no such node
+func (cl *Cluster) Node(addr string) *ClusterNode {
+	for _, node := range cl.nodes {
+		if node.Addr == addr {
+			return node
+		}
+	}
+	return nil
+}
+
+// NodeForKey returns the node serving the hash slot of the key
+func (cl *Cluster) NodeForKey(key string) *ClusterNode {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	return cl.slots[HashSlot(key)]
+}
+
+// Dial returns a connection to the node with the address, with the same
+// signature of redis.Dial, so it can replace it in cluster clients. The
+// options are ignored
+func (cl *Cluster) Dial(network, addr string, options ...redis.DialOption) (redis.Conn, error) {
+	node := cl.Node(addr)
+	if node == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("unknown cluster node %s", addr)}
+	}
+	return node.Dial()
+}
+
+// AssignSlots makes the node serve the hash slots in the range, inclusive. An
+// error is returned if the range is out of the hash slots
+func (cl *Cluster) AssignSlots(node *ClusterNode, from, to int) error {
+	if from < 0 || to >= clusterSlots || from > to {
+		return fmt.Errorf("invalid hash slot range %d-%d", from, to)
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	for slot := from; slot <= to; slot++ {
+		cl.slots[slot] = node
+		delete(cl.migrating, slot)
+	}
+	return nil
+}
+
+// MigrateSlot starts the migration of the hash slot to the node. Until the
+// migration is finished (see FinishMigration), the node serving the slot
+// replies ASK to commands with keys in the slot, and the importing node
+// accepts them only after the ASKING command. Keys stored in the key space of
+// the nodes are moved when the migration starts
+func (cl *Cluster) MigrateSlot(slot int, to *ClusterNode) {
+	cl.mu.Lock()
+	from := cl.slots[slot]
+	if from == nil || from == to {
+		cl.mu.Unlock()
+		return
+	}
+	cl.migrating[slot] = to
+	cl.mu.Unlock()
+
+	// the key spaces are retrieved without holding cl.mu, as the connections
+	// hold their locks while routing commands
+	moveSlotKeys(slot, from.KeySpace(), to.KeySpace())
+}
+
+// FinishMigration makes the importing node serve the migrated hash slot, so
+// the previous node replies MOVED to commands with keys in the slot
+func (cl *Cluster) FinishMigration(slot int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if to, ok := cl.migrating[slot]; ok {
+		cl.slots[slot] = to
+		delete(cl.migrating, slot)
+	}
+}
+
+// route checks if the command must be redirected to another node, returning
+// the redirection error. If the command is served by the node ok is false.
+// Commands without keys are never redirected
+func (cl *Cluster) route(node *ClusterNode, asking bool, commandName string, args []interface{}) (reply replyElement, ok bool) {
+	keys := commandKeys(commandName, args)
+	if len(keys) == 0 {
+		return replyElement{}, false
+	}
+
+	slot := HashSlot(keys[0])
+	for _, key := range keys[1:] {
+		if HashSlot(key) != slot {
+			return replyElement{err: CrossSlotError()}, true
+		}
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	owner, importer := cl.slots[slot], cl.migrating[slot]
+	switch {
+	case owner == nil:
+		return replyElement{err: redis.Error("CLUSTERDOWN Hash slot not served")}, true
+	case owner == node && importer != nil:
+		return replyElement{err: AskError(slot, importer.Addr)}, true
+	case owner == node, importer == node && asking:
+		return replyElement{}, false
+	}
+	return replyElement{err: MovedError(slot, owner.Addr)}, true
+}
+
+// command answers the CLUSTER subcommands with the cluster topology. If the
+// subcommand isn't supported ok is false
+func (cl *Cluster) command(node *ClusterNode, args []interface{}) (reply replyElement, ok bool) {
+	if len(args) == 0 {
+		return replyElement{}, false
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	switch strings.ToLower(argString(args[0])) {
+	case "slots":
+		return replyElement{reply: cl.slotsReply()}, true
+	case "shards":
+		return replyElement{reply: cl.shardsReply()}, true
+	case "nodes":
+		return replyElement{reply: []byte(cl.nodesReply(node))}, true
+	case "keyslot":
+		if len(args) != 2 {
+			return replyElement{err: errWrongArgs("cluster|keyslot")}, true
+		}
+		return replyElement{reply: int64(HashSlot(argString(args[1])))}, true
+	case "myid":
+		return replyElement{reply: []byte(node.ID)}, true
+	case "info":
+		return replyElement{reply: []byte(cl.infoReply())}, true
+	}
+	return replyElement{}, false
+}
+
+// slotRange is a range of hash slots served by the same node
+type slotRange struct {
+	from, to int
+	node     *ClusterNode
+}
+
+// ranges returns the ranges of hash slots served by the nodes
+//
+// Caller must hold cl.mu.
+func (cl *Cluster) ranges() []slotRange {
+	var ranges []slotRange
+	for slot, node := range cl.slots {
+		if node == nil {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].node == node && ranges[n-1].to == slot-1 {
+			ranges[n-1].to = slot
+			continue
+		}
+		ranges = append(ranges, slotRange{from: slot, to: slot, node: node})
+	}
+	return ranges
+}
+
+// slotsReply returns the reply of CLUSTER SLOTS
+//
+// Caller must hold cl.mu.
+func (cl *Cluster) slotsReply() []interface{} {
+	reply := []interface{}{}
+	for _, r := range cl.ranges() {
+		host, port := splitAddr(r.node.Addr)
+		reply = append(reply, []interface{}{
+			int64(r.from),
+			int64(r.to),
+			[]interface{}{[]byte(host), port, []byte(r.node.ID)},
+		})
+	}
+	return reply
+}
+
+// shardsReply returns the reply of CLUSTER SHARDS
+//
+// Caller must hold cl.mu.
+func (cl *Cluster) shardsReply() []interface{} {
+	reply := []interface{}{}
+	for _, node := range cl.nodes {
+		slots := []interface{}{}
+		for _, r := range cl.ranges() {
+			if r.node == node {
+				slots = append(slots, int64(r.from), int64(r.to))
+			}
+		}
+
+		host, port := splitAddr(node.Addr)
+		reply = append(reply, RESP3Map{
+			"slots": slots,
+			"nodes": []interface{}{RESP3Map{
+				"id":                 []byte(node.ID),
+				"port":               port,
+				"ip":                 []byte(host),
+				"endpoint":           []byte(host),
+				"role":               []byte("master"),
+				"replication-offset": int64(0),
+				"health":             []byte("online"),
+			}},
+		})
+	}
+	return reply
+}
+
+// nodesReply returns the reply of CLUSTER NODES, as seen by the node
+//
+// Caller must hold cl.mu.
+func (cl *Cluster) nodesReply(myself *ClusterNode) string {
+	var lines []string
+	for _, node := range cl.nodes {
+		flags := "master"
+		if node == myself {
+			flags = "myself,master"
+		}
+
+		host, port := splitAddr(node.Addr)
+		fields := []string{node.ID, fmt.Sprintf("%s:%d@%d", host, port, port+10000), flags, "-", "0", "0", "0", "connected"}
+		for _, r := range cl.ranges() {
+			if r.node != node {
+				continue
+			}
+			if r.from == r.to {
+				fields = append(fields, strconv.Itoa(r.from))
+			} else {
+				fields = append(fields, fmt.Sprintf("%d-%d", r.from, r.to))
+			}
+		}
+
+		slots := make([]int, 0, len(cl.migrating))
+		for slot := range cl.migrating {
+			slots = append(slots, slot)
+		}
+		sort.Ints(slots)
+
+		for _, slot := range slots {
+			switch node {
+			case cl.slots[slot]:
+				fields = append(fields, fmt.Sprintf("[%d->-%s]", slot, cl.migrating[slot].ID))
+			case cl.migrating[slot]:
+				fields = append(fields, fmt.Sprintf("[%d-<-%s]", slot, cl.slots[slot].ID))
+			}
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// infoReply returns the reply of CLUSTER INFO
+//
+// Caller must hold cl.mu.
+func (cl *Cluster) infoReply() string {
+	assigned := 0
+	for _, node := range cl.slots {
+		if node != nil {
+			assigned++
+		}
+	}
+
+	state := "ok"
+	if assigned < clusterSlots {
+		state = "fail"
+	}
+
+	return fmt.Sprintf("cluster_state:%s\r\ncluster_slots_assigned:%d\r\ncluster_slots_ok:%d\r\ncluster_known_nodes:%d\r\ncluster_size:%d\r\n",
+		state, assigned, assigned, len(cl.nodes), len(cl.nodes))
+}
+
+// splitAddr splits the address, returning port 0 if it's invalid
+func splitAddr(addr string) (string, int64) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+
+	n, _ := strconv.ParseInt(port, 10, 64)
+	return host, n
+}
+
+// clusterNode returns the cluster node served by the connection, falling back
+// to the one of the connection that it shares the registry with (see Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) clusterNode() *ClusterNode {
+	return fromRegistry(c, func(conn *Conn) *ClusterNode {
+		return conn.node
+	})
+}
+
+// redirect checks if the command must be redirected to another cluster node,
+// handling the ASKING command. If the connection isn't a cluster node or the
+// command is served by it ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) redirect(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	node := c.clusterNode()
+	if node == nil {
+		return replyElement{}, false
+	}
+
+	if strings.EqualFold(commandName, "ASKING") {
+		c.asking = true
+		return replyElement{reply: "OK"}, true
+	}
+
+	// ASKING is valid only for the next command
+	asking := c.asking
+	c.asking = false
+
+	return node.cluster.route(node, asking, commandName, args)
+}
+
+// clusterCommand handles the CLUSTER command that wasn't registered. If the
+// connection isn't a cluster node or the command isn't supported ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) clusterCommand(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	node := c.clusterNode()
+	if node == nil || !strings.EqualFold(commandName, "CLUSTER") {
+		return replyElement{}, false
+	}
+	return node.cluster.command(node, args)
+}
+
+// HashSlot returns the cluster hash slot of the key, the CRC16 of the key
+// modulo 16384. When the key has a hash tag, a non-empty substring between the
+// first "{" and the next "}", only the hash tag is hashed
+func HashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by Redis Cluster
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// commandKeys returns the keys of the command, used to route it in the
+// cluster. The keys are found by the key specifications of the Redis command
+// table, so commands missing from it don't have keys
+func commandKeys(commandName string, args []interface{}) []string {
+	spec, ok := commandSpecs[strings.ToUpper(commandName)]
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	for _, ks := range spec.keySpecs {
+		keys = append(keys, ks.keys(args)...)
+	}
+	return keys
+}
+
+// moveSlotKeys moves the keys of the hash slot between the key spaces
+func moveSlotKeys(slot int, from, to *KeySpace) {
+	if from == nil || to == nil || from == to {
+		return
+	}
+
+	from.mu.Lock()
+	defer from.mu.Unlock()
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	for name, e := range from.keys {
+		if HashSlot(name) == slot {
+			to.keys[name] = e
+			delete(from.keys, name)
+		}
+	}
+}
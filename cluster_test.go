@@ -0,0 +1,223 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestHashSlot(t *testing.T) {
+	data := []struct {
+		key  string
+		slot int
+	}{
+		{key: "foo", slot: 12182},
+		{key: "bar", slot: 5061},
+		{key: "hello", slot: 866},
+		{key: "{foo}.bar", slot: 12182},
+		{key: "a{foo}{bar}", slot: 12182},
+	}
+
+	for _, item := range data {
+		if slot := HashSlot(item.key); slot != item.slot {
+			t.Errorf("Expected slot %d for key '%s' and got %d", item.slot, item.key, slot)
+		}
+	}
+
+	if HashSlot("{user1000}.following") != HashSlot("{user1000}.followers") {
+		t.Error("Expected keys with the same hash tag to share the slot")
+	}
+	if HashSlot("{}foo") == HashSlot("foo") {
+		t.Error("Expected empty hash tag to be ignored")
+	}
+}
+
+func TestClusterRouting(t *testing.T) {
+	cluster := NewCluster("127.0.0.1:7000", "127.0.0.1:7001", "127.0.0.1:7002")
+	nodes := cluster.Nodes()
+
+	if node := cluster.NodeForKey("foo"); node != nodes[2] {
+		t.Fatalf("Expected key foo to be served by %s and got %s", nodes[2].Addr, node.Addr)
+	}
+
+	nodes[2].Command("GET", "foo").Expect("bar")
+
+	conn, err := cluster.Dial("tcp", "127.0.0.1:7002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := redis.String(conn.Do("GET", "foo")); err != nil || value != "bar" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+
+	conn, err = cluster.Dial("tcp", "127.0.0.1:7000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("GET", "foo"); err == nil || err.Error() != "MOVED 12182 127.0.0.1:7002" {
+		t.Errorf("Expected MOVED error and got %v", err)
+	}
+	if _, err := conn.Do("MGET", "foo", "bar"); err == nil || !strings.HasPrefix(err.Error(), "CROSSSLOT") {
+		t.Errorf("Expected CROSSSLOT error and got %v", err)
+	}
+
+	if _, err := cluster.Dial("tcp", "127.0.0.1:7003"); err == nil {
+		t.Error("Expected error when dialing unknown node")
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	data := []struct {
+		commandName string
+		args        []interface{}
+		keys        []string
+	}{
+		{commandName: "GET", args: []interface{}{"a"}, keys: []string{"a"}},
+		{commandName: "set", args: []interface{}{"a", "1", "EX", 10}, keys: []string{"a"}},
+		{commandName: "DEL", args: []interface{}{"a", "b", "c"}, keys: []string{"a", "b", "c"}},
+		{commandName: "MSET", args: []interface{}{"a", 1, "b", 2}, keys: []string{"a", "b"}},
+		{commandName: "BLPOP", args: []interface{}{"a", "b", 0}, keys: []string{"a", "b"}},
+		{commandName: "RENAME", args: []interface{}{"a", "b"}, keys: []string{"a", "b"}},
+		{commandName: "EVAL", args: []interface{}{"return 1", 2, "a", "b", "x"}, keys: []string{"a", "b"}},
+		{commandName: "EVALSHA", args: []interface{}{"sha", 0, "x"}},
+		{commandName: "PING"},
+		{commandName: "CLUSTER", args: []interface{}{"SLOTS"}},
+	}
+
+	for _, item := range data {
+		if keys := commandKeys(item.commandName, item.args); !reflect.DeepEqual(keys, item.keys) {
+			t.Errorf("Expected keys %v for %s %v and got %v", item.keys, item.commandName, item.args, keys)
+		}
+	}
+}
+
+func TestClusterAssignSlots(t *testing.T) {
+	cluster := NewCluster("127.0.0.1:7000", "127.0.0.1:7001")
+	nodes := cluster.Nodes()
+
+	if err := cluster.AssignSlots(nodes[1], 0, clusterSlots-1); err != nil {
+		t.Fatal(err)
+	}
+	if node := cluster.NodeForKey("foo"); node != nodes[1] {
+		t.Errorf("Expected key foo to be served by %s and got %s", nodes[1].Addr, node.Addr)
+	}
+
+	for _, r := range [][2]int{{-1, 10}, {0, clusterSlots}, {10, 5}} {
+		if err := cluster.AssignSlots(nodes[0], r[0], r[1]); err == nil {
+			t.Errorf("Expected error when assigning slots %d-%d", r[0], r[1])
+		}
+	}
+	if node := cluster.NodeForKey("foo"); node != nodes[1] {
+		t.Errorf("Expected invalid ranges to keep the slots and got %s", node.Addr)
+	}
+}
+
+func TestClusterMigration(t *testing.T) {
+	cluster := NewCluster("127.0.0.1:7000", "127.0.0.1:7001")
+	source := cluster.NodeForKey("foo")
+	target := cluster.Node("127.0.0.1:7000")
+
+	source.UseKeySpace(NewKeySpace())
+	target.UseKeySpace(NewKeySpace())
+
+	sourceConn, err := source.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sourceConn.Do("SET", "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	slot := HashSlot("foo")
+	cluster.MigrateSlot(slot, target)
+
+	if _, err := sourceConn.Do("GET", "foo"); err == nil || err.Error() != "ASK 12182 127.0.0.1:7000" {
+		t.Errorf("Expected ASK error and got %v", err)
+	}
+
+	targetConn, err := target.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := targetConn.Do("GET", "foo"); err == nil || err.Error() != "MOVED 12182 127.0.0.1:7001" {
+		t.Errorf("Expected MOVED error without ASKING and got %v", err)
+	}
+
+	if _, err := targetConn.Do("ASKING"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := redis.String(targetConn.Do("GET", "foo")); err != nil || value != "bar" {
+		t.Errorf("Unexpected GET reply after ASKING '%s' (%v)", value, err)
+	}
+	if _, err := targetConn.Do("GET", "foo"); err == nil {
+		t.Error("Expected ASKING to be valid only for the next command")
+	}
+
+	cluster.FinishMigration(slot)
+
+	if value, err := redis.String(targetConn.Do("GET", "foo")); err != nil || value != "bar" {
+		t.Errorf("Unexpected GET reply after migration '%s' (%v)", value, err)
+	}
+	if _, err := sourceConn.Do("GET", "foo"); err == nil || err.Error() != "MOVED 12182 127.0.0.1:7000" {
+		t.Errorf("Expected MOVED error after migration and got %v", err)
+	}
+}
+
+func TestClusterCommand(t *testing.T) {
+	cluster := NewCluster("127.0.0.1:7000", "127.0.0.1:7001")
+	nodes := cluster.Nodes()
+
+	conn, err := cluster.Dial("tcp", "127.0.0.1:7001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{
+		[]interface{}{int64(0), int64(8191), []interface{}{[]byte("127.0.0.1"), int64(7000), []byte(nodes[0].ID)}},
+		[]interface{}{int64(8192), int64(16383), []interface{}{[]byte("127.0.0.1"), int64(7001), []byte(nodes[1].ID)}},
+	}
+	if !reflect.DeepEqual(slots, expected) {
+		t.Errorf("Unexpected CLUSTER SLOTS reply %#v", slots)
+	}
+
+	cluster.MigrateSlot(100, nodes[1])
+
+	lines, err := redis.String(conn.Do("CLUSTER", "NODES"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLines := nodes[0].ID + " 127.0.0.1:7000@17000 master - 0 0 0 connected 0-8191 [100->-" + nodes[1].ID + "]\n" +
+		nodes[1].ID + " 127.0.0.1:7001@17001 myself,master - 0 0 0 connected 8192-16383 [100-<-" + nodes[0].ID + "]\n"
+	if lines != expectedLines {
+		t.Errorf("Unexpected CLUSTER NODES reply:\n%s", lines)
+	}
+
+	shards, err := redis.Values(conn.Do("CLUSTER", "SHARDS"))
+	if err != nil || len(shards) != 2 {
+		t.Errorf("Unexpected CLUSTER SHARDS reply %#v (%v)", shards, err)
+	}
+
+	if slot, err := redis.Int(conn.Do("CLUSTER", "KEYSLOT", "foo")); err != nil || slot != 12182 {
+		t.Errorf("Unexpected CLUSTER KEYSLOT reply %d (%v)", slot, err)
+	}
+	if id, err := redis.String(conn.Do("CLUSTER", "MYID")); err != nil || id != nodes[1].ID {
+		t.Errorf("Unexpected CLUSTER MYID reply '%s' (%v)", id, err)
+	}
+
+	nodes[1].GenericCommand("CLUSTER").Expect("custom")
+	if value, err := redis.String(conn.Do("CLUSTER", "INFO")); err != nil || value != "custom" {
+		t.Errorf("Expected registered CLUSTER command to be used and got '%s' (%v)", value, err)
+	}
+}
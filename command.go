@@ -5,9 +5,13 @@
 package redigomock
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // response struct that represents single response from `Do` call.
@@ -24,16 +28,33 @@ type ResponseHandler func(args []interface{}) (interface{}, error)
 // when request by a command execution
 type Cmd struct {
 	// name and args must not be mutated after creation.
-	name      string        // Name of the command
-	args      []interface{} // Arguments of the command
-	responses []response    // Slice of returned responses
-	called    bool          // State for this command called or not
-	mu        sync.Mutex    // hold while accessing responses and called
+	name       string         // Name of the command
+	args       []interface{}  // Arguments of the command
+	responses  []response     // Slice of returned responses
+	next       int            // Position of the next response to return
+	policy     ResponsePolicy // Policy once all the responses were returned, when hasPolicy is set
+	hasPolicy  bool           // State for this command having its own response policy or not
+	called     bool           // State for this command called or not
+	calls      int            // Number of times that the command was called
+	times      int            // Expected number of calls, when checkTimes is set
+	checkTimes bool           // State for this command limiting the number of calls or not
+	delay      time.Duration  // Time to wait before returning a response
+	wire       bool           // Arguments are matched as they are sent to the Redis server (see Expectations)
+	blocks     []argBlock     // Repeated arguments matched regardless of their order, in the order of the arguments
+	mu         sync.Mutex     // hold while accessing any mutable fields
 }
 
 // cmdHash stores a unique identifier of the command
 type cmdHash string
 
+// argBlock is a range of the arguments with blocks of the same size, like the
+// field and value pairs of HSET, that can be sent in any order
+type argBlock struct {
+	start int // Position of the first argument
+	size  int // Number of arguments of each block
+	count int // Number of blocks
+}
+
 // equal verify if a command/arguments is related to a registered command
 func equal(commandName string, args []interface{}, cmd *Cmd) bool {
 	if commandName != cmd.name || len(args) != len(cmd.args) {
@@ -45,6 +66,11 @@ func equal(commandName string, args []interface{}, cmd *Cmd) bool {
 			if reflect.TypeOf(cmd.args[pos]) != reflect.TypeOf(args[pos]) {
 				return false
 			}
+			// matchers with different parameters, like regular expressions,
+			// aren't related
+			if fmt.Sprint(cmd.args[pos]) != fmt.Sprint(args[pos]) {
+				return false
+			}
 		} else if implementsFuzzy(cmd.args[pos]) || implementsFuzzy(args[pos]) {
 			return false
 		} else {
@@ -75,6 +101,83 @@ func match(commandName string, args []interface{}, cmd *Cmd) bool {
 	return true
 }
 
+// matchWire works like match, but compares the command name ignoring the case
+// and the arguments as they are sent to the Redis server, as commands received
+// from the wire only have string arguments (see Server). Fuzzy matchers also
+// receive numeric arguments converted to int64 or float64
+func matchWire(commandName string, args []interface{}, cmd *Cmd) bool {
+	if !strings.EqualFold(commandName, cmd.name) || len(args) != len(cmd.args) {
+		return false
+	}
+
+	blocks := cmd.blocks
+	for pos := 0; pos < len(cmd.args); {
+		if len(blocks) > 0 && pos == blocks[0].start {
+			if !matchWireBlocks(cmd.args, args, blocks[0]) {
+				return false
+			}
+			pos += blocks[0].size * blocks[0].count
+			blocks = blocks[1:]
+			continue
+		}
+
+		if !matchWireArgs(cmd.args[pos:pos+1], args[pos:pos+1]) {
+			return false
+		}
+		pos++
+	}
+	return true
+}
+
+// matchWireArgs compares the arguments as they are sent to the Redis server
+// (see matchWire)
+func matchWireArgs(expected, args []interface{}) bool {
+	for pos := range expected {
+		if matcher, ok := expected[pos].(FuzzyMatcher); ok {
+			if !matcher.Match(args[pos]) && !matcher.Match(wireNumber(args[pos])) {
+				return false
+			}
+		} else if !bytes.Equal(formatArg(expected[pos]), formatArg(args[pos])) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWireBlocks checks if every block of the arguments matches a different
+// block of the expected arguments, regardless of their order
+func matchWireBlocks(expected, args []interface{}, block argBlock) bool {
+	used := make([]bool, block.count)
+	for i := 0; i < block.count; i++ {
+		start := block.start + i*block.size
+
+		found := false
+		for j := 0; j < block.count && !found; j++ {
+			expectedStart := block.start + j*block.size
+			if !used[j] && matchWireArgs(expected[expectedStart:expectedStart+block.size], args[start:start+block.size]) {
+				used[j], found = true, true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// wireNumber converts an argument received from the wire to int64 or float64
+// when it is a number, otherwise the argument is returned unchanged
+func wireNumber(arg interface{}) interface{} {
+	data := formatArg(arg)
+	if n, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(string(data), 64); err == nil {
+		return f
+	}
+	return arg
+}
+
 // Expect sets a response for this command. Every time a Do or Receive method
 // is executed for a registered command this response or error will be
 // returned. Expect call returns a pointer to Cmd struct, so you can chain
@@ -145,6 +248,60 @@ func (c *Cmd) Handle(fn ResponseHandler) *Cmd {
 	return c
 }
 
+// HandleCtx works in the same way of the Handle command, but the function also
+// receives the context of the call, like the number of previous calls of the
+// command and the commands executed by the connection (see Call)
+func (c *Cmd) HandleCtx(fn CallHandler) *Cmd {
+	c.expect(response{fn, nil, nil})
+	return c
+}
+
+// Policy sets what the command returns once all its responses were returned,
+// overriding the policy of the connection (see Conn.ResponsePolicy)
+func (c *Cmd) Policy(policy ResponsePolicy) *Cmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.policy, c.hasPolicy = policy, true
+	return c
+}
+
+// responsePolicy returns the policy of the command, falling back to the given
+// one when it doesn't have its own
+func (c *Cmd) responsePolicy(fallback ResponsePolicy) ResponsePolicy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasPolicy {
+		return c.policy
+	}
+	return fallback
+}
+
+// Times sets the exact number of times that the command must be called.
+// Calls beyond that number fail, and ExpectationsWereMet reports the command
+// when it was called fewer times. Use zero to ensure that the command is never
+// called
+func (c *Cmd) Times(n int) *Cmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.times, c.checkTimes = n, true
+	return c
+}
+
+// Delay makes the connection wait before returning the responses of the
+// command, simulating a slow server. The wait is interrupted by the timeout or
+// the context of DoWithTimeout, DoContext, ReceiveWithTimeout and
+// ReceiveContext
+func (c *Cmd) Delay(d time.Duration) *Cmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delay = d
+	return c
+}
+
 // hash generates a unique identifier for the command
 func (c *Cmd) hash() cmdHash {
 	output := c.name
@@ -162,19 +319,57 @@ func (c *Cmd) Called() bool {
 	return c.called
 }
 
-// getResponse marks the command as used, and gets the next response to return.
-func (c *Cmd) getResponse() *response {
+// expectedCalls returns the number of times that the command must be called
+// and the number of times that it was called. If the number of calls isn't
+// limited (see Times) limited is false
+func (c *Cmd) expectedCalls() (times, calls int, limited bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.called = true
-	if len(c.responses) == 0 {
-		return nil
+	return c.times, c.calls, c.checkTimes
+}
+
+// getDelay returns the time to wait before returning a response
+func (c *Cmd) getDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.delay
+}
+
+// call marks the command as used and gets the next response to return, with
+// the number of previous calls, checking if the command was called more times
+// than expected (see Times).
+// Once all the responses were returned, the next one depends on the policy,
+// and exhausted is set for FailWhenExhausted and FallThrough. With
+// FallThrough the call isn't counted, as it belongs to another command
+func (c *Cmd) call(policy ResponsePolicy) (resp *response, index int, exceeded, exhausted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pos := c.next
+	if n := len(c.responses); n > 0 && pos >= n {
+		switch policy {
+		case Cycle:
+			pos %= n
+		case FailWhenExhausted:
+			exhausted = true
+		case FallThrough:
+			return nil, c.calls, false, true
+		default:
+			pos = n - 1
+		}
 	}
 
-	resp := c.responses[0]
-	if len(c.responses) > 1 {
-		c.responses = c.responses[1:]
+	c.called = true
+	index = c.calls
+	c.calls++
+	exceeded = c.checkTimes && c.calls > c.times
+	if len(c.responses) == 0 || exhausted {
+		return nil, index, exceeded, exhausted
 	}
-	return &resp
+
+	c.next++
+	next := c.responses[pos]
+	return &next, index, exceeded, false
 }
@@ -0,0 +1,858 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Code generated by gen_commands.go from commands.json. DO NOT EDIT.
+
+package redigomock
+
+// commandSpecs are the specifications of the commands, used to validate
+// their arguments (see Conn.ValidateCommands), to find their keys and to
+// reject the write commands on replicas
+var commandSpecs = map[string]*commandSpec{
+	"APPEND": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"BLPOP": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -2, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+			{name: "timeout", kind: "double"},
+		},
+	},
+	"BRPOP": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -2, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+			{name: "timeout", kind: "double"},
+		},
+	},
+	"DBSIZE": {
+		arity: 1,
+	},
+	"DECR": {
+		arity: 2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"DECRBY": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "decrement", kind: "integer"},
+		},
+	},
+	"DEL": {
+		arity: -2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -1, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+		},
+	},
+	"ECHO": {
+		arity: 2,
+		arguments: []argSpec{
+			{name: "message", kind: "string"},
+		},
+	},
+	"EVAL": {
+		arity: -3,
+		keySpecs: []keySpec{
+			{pos: 2, keyNum: true},
+		},
+		arguments: []argSpec{
+			{name: "script", kind: "string"},
+			{name: "numkeys", kind: "integer"},
+			{name: "key", kind: "key", optional: true, multiple: true},
+			{name: "arg", kind: "string", optional: true, multiple: true},
+		},
+	},
+	"EVALSHA": {
+		arity: -3,
+		keySpecs: []keySpec{
+			{pos: 2, keyNum: true},
+		},
+		arguments: []argSpec{
+			{name: "sha1", kind: "string"},
+			{name: "numkeys", kind: "integer"},
+			{name: "key", kind: "key", optional: true, multiple: true},
+			{name: "arg", kind: "string", optional: true, multiple: true},
+		},
+	},
+	"EXISTS": {
+		arity: -2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -1, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+		},
+	},
+	"EXPIRE": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "seconds", kind: "integer"},
+			{name: "condition", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "nx", kind: "pure-token", token: "NX"},
+				{name: "xx", kind: "pure-token", token: "XX"},
+				{name: "gt", kind: "pure-token", token: "GT"},
+				{name: "lt", kind: "pure-token", token: "LT"},
+			}},
+		},
+	},
+	"EXPIREAT": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "unix-time-seconds", kind: "unix-time"},
+			{name: "condition", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "nx", kind: "pure-token", token: "NX"},
+				{name: "xx", kind: "pure-token", token: "XX"},
+				{name: "gt", kind: "pure-token", token: "GT"},
+				{name: "lt", kind: "pure-token", token: "LT"},
+			}},
+		},
+	},
+	"FLUSHDB": {
+		arity: -1,
+		write: true,
+		arguments: []argSpec{
+			{name: "flush-type", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "async", kind: "pure-token", token: "ASYNC"},
+				{name: "sync", kind: "pure-token", token: "SYNC"},
+			}},
+		},
+	},
+	"GET": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"GETDEL": {
+		arity: 2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"GETSET": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"HDEL": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string", multiple: true},
+		},
+	},
+	"HEXISTS": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string"},
+		},
+	},
+	"HGET": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string"},
+		},
+	},
+	"HGETALL": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"HINCRBY": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string"},
+			{name: "increment", kind: "integer"},
+		},
+	},
+	"HKEYS": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"HLEN": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"HMGET": {
+		arity: -3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string", multiple: true},
+		},
+	},
+	"HMSET": {
+		arity: -4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "data", kind: "block", multiple: true, arguments: []argSpec{
+				{name: "field", kind: "string"},
+				{name: "value", kind: "string"},
+			}},
+		},
+	},
+	"HSET": {
+		arity: -4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "data", kind: "block", multiple: true, arguments: []argSpec{
+				{name: "field", kind: "string"},
+				{name: "value", kind: "string"},
+			}},
+		},
+	},
+	"HSETNX": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "field", kind: "string"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"HVALS": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"INCR": {
+		arity: 2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"INCRBY": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "increment", kind: "integer"},
+		},
+	},
+	"INCRBYFLOAT": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "increment", kind: "double"},
+		},
+	},
+	"KEYS": {
+		arity: 2,
+		arguments: []argSpec{
+			{name: "pattern", kind: "pattern"},
+		},
+	},
+	"LINDEX": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "index", kind: "integer"},
+		},
+	},
+	"LLEN": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"LPOP": {
+		arity: -2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "count", kind: "integer", optional: true},
+		},
+	},
+	"LPUSH": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "element", kind: "string", multiple: true},
+		},
+	},
+	"LRANGE": {
+		arity: 4,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "start", kind: "integer"},
+			{name: "stop", kind: "integer"},
+		},
+	},
+	"LREM": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "count", kind: "integer"},
+			{name: "element", kind: "string"},
+		},
+	},
+	"LTRIM": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "start", kind: "integer"},
+			{name: "stop", kind: "integer"},
+		},
+	},
+	"MGET": {
+		arity: -2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -1, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+		},
+	},
+	"MSET": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -1, step: 2},
+		},
+		arguments: []argSpec{
+			{name: "data", kind: "block", multiple: true, arguments: []argSpec{
+				{name: "key", kind: "key"},
+				{name: "value", kind: "string"},
+			}},
+		},
+	},
+	"PERSIST": {
+		arity: 2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"PEXPIRE": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "milliseconds", kind: "integer"},
+			{name: "condition", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "nx", kind: "pure-token", token: "NX"},
+				{name: "xx", kind: "pure-token", token: "XX"},
+				{name: "gt", kind: "pure-token", token: "GT"},
+				{name: "lt", kind: "pure-token", token: "LT"},
+			}},
+		},
+	},
+	"PING": {
+		arity: -1,
+		arguments: []argSpec{
+			{name: "message", kind: "string", optional: true},
+		},
+	},
+	"PSETEX": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "milliseconds", kind: "integer"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"PTTL": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"PUBLISH": {
+		arity: 3,
+		arguments: []argSpec{
+			{name: "channel", kind: "string"},
+			{name: "message", kind: "string"},
+		},
+	},
+	"RENAME": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+			{pos: 2, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "newkey", kind: "key"},
+		},
+	},
+	"RPOP": {
+		arity: -2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "count", kind: "integer", optional: true},
+		},
+	},
+	"RPUSH": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "element", kind: "string", multiple: true},
+		},
+	},
+	"SADD": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string", multiple: true},
+		},
+	},
+	"SCAN": {
+		arity: -2,
+		arguments: []argSpec{
+			{name: "cursor", kind: "integer"},
+			{name: "pattern", kind: "pattern", token: "MATCH", optional: true},
+			{name: "count", kind: "integer", token: "COUNT", optional: true},
+			{name: "type", kind: "string", token: "TYPE", optional: true},
+		},
+	},
+	"SCARD": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"SET": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "value", kind: "string"},
+			{name: "condition", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "nx", kind: "pure-token", token: "NX"},
+				{name: "xx", kind: "pure-token", token: "XX"},
+			}},
+			{name: "get", kind: "pure-token", token: "GET", optional: true},
+			{name: "expiration", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "seconds", kind: "integer", token: "EX"},
+				{name: "milliseconds", kind: "integer", token: "PX"},
+				{name: "unix-time-seconds", kind: "unix-time", token: "EXAT"},
+				{name: "unix-time-milliseconds", kind: "unix-time", token: "PXAT"},
+				{name: "keepttl", kind: "pure-token", token: "KEEPTTL"},
+			}},
+		},
+	},
+	"SETEX": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "seconds", kind: "integer"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"SETNX": {
+		arity: 3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "value", kind: "string"},
+		},
+	},
+	"SISMEMBER": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string"},
+		},
+	},
+	"SMEMBERS": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"SPOP": {
+		arity: -2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "count", kind: "integer", optional: true},
+		},
+	},
+	"SREM": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string", multiple: true},
+		},
+	},
+	"STRLEN": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"TTL": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"TYPE": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"UNLINK": {
+		arity: -2,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: -1, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key", multiple: true},
+		},
+	},
+	"ZADD": {
+		arity: -4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "condition", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "nx", kind: "pure-token", token: "NX"},
+				{name: "xx", kind: "pure-token", token: "XX"},
+			}},
+			{name: "comparison", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "gt", kind: "pure-token", token: "GT"},
+				{name: "lt", kind: "pure-token", token: "LT"},
+			}},
+			{name: "ch", kind: "pure-token", token: "CH", optional: true},
+			{name: "incr", kind: "pure-token", token: "INCR", optional: true},
+			{name: "data", kind: "block", multiple: true, arguments: []argSpec{
+				{name: "score", kind: "double"},
+				{name: "member", kind: "string"},
+			}},
+		},
+	},
+	"ZCARD": {
+		arity: 2,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+		},
+	},
+	"ZCOUNT": {
+		arity: 4,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "min", kind: "string"},
+			{name: "max", kind: "string"},
+		},
+	},
+	"ZINCRBY": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "increment", kind: "double"},
+			{name: "member", kind: "string"},
+		},
+	},
+	"ZRANGE": {
+		arity: -4,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "start", kind: "string"},
+			{name: "stop", kind: "string"},
+			{name: "sortby", kind: "oneof", optional: true, arguments: []argSpec{
+				{name: "byscore", kind: "pure-token", token: "BYSCORE"},
+				{name: "bylex", kind: "pure-token", token: "BYLEX"},
+			}},
+			{name: "rev", kind: "pure-token", token: "REV", optional: true},
+			{name: "limit", kind: "block", token: "LIMIT", optional: true, arguments: []argSpec{
+				{name: "offset", kind: "integer"},
+				{name: "count", kind: "integer"},
+			}},
+			{name: "withscores", kind: "pure-token", token: "WITHSCORES", optional: true},
+		},
+	},
+	"ZRANGEBYSCORE": {
+		arity: -4,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "min", kind: "string"},
+			{name: "max", kind: "string"},
+			{name: "withscores", kind: "pure-token", token: "WITHSCORES", optional: true},
+			{name: "limit", kind: "block", token: "LIMIT", optional: true, arguments: []argSpec{
+				{name: "offset", kind: "integer"},
+				{name: "count", kind: "integer"},
+			}},
+		},
+	},
+	"ZRANK": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string"},
+		},
+	},
+	"ZREM": {
+		arity: -3,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string", multiple: true},
+		},
+	},
+	"ZREMRANGEBYSCORE": {
+		arity: 4,
+		write: true,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "min", kind: "string"},
+			{name: "max", kind: "string"},
+		},
+	},
+	"ZREVRANGEBYSCORE": {
+		arity: -4,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "max", kind: "string"},
+			{name: "min", kind: "string"},
+			{name: "withscores", kind: "pure-token", token: "WITHSCORES", optional: true},
+			{name: "limit", kind: "block", token: "LIMIT", optional: true, arguments: []argSpec{
+				{name: "offset", kind: "integer"},
+				{name: "count", kind: "integer"},
+			}},
+		},
+	},
+	"ZSCORE": {
+		arity: 3,
+		keySpecs: []keySpec{
+			{pos: 1, lastKey: 0, step: 1},
+		},
+		arguments: []argSpec{
+			{name: "key", kind: "key"},
+			{name: "member", kind: "string"},
+		},
+	},
+}
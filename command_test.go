@@ -5,12 +5,14 @@
 package redigomock
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -374,6 +376,75 @@ func TestExpectSliceFromStrings(t *testing.T) {
 	}
 }
 
+func TestTimes(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "hello").Expect("world").Times(2)
+	connection.Command("DEL", "hello").Times(0)
+
+	for i := 0; i < 2; i++ {
+		if value, err := redis.String(connection.Do("GET", "hello")); err != nil || value != "world" {
+			t.Errorf("Unexpected reply '%s' (%v)", value, err)
+		}
+	}
+
+	if err := connection.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expected no error and got '%s'", err)
+	}
+
+	if _, err := connection.Do("GET", "hello"); err == nil {
+		t.Error("Expected error for a command called more times than expected")
+	}
+
+	if _, err := connection.Do("DEL", "hello"); err == nil {
+		t.Error("Expected error for a command that must not be called")
+	}
+
+	if err := connection.ExpectationsWereMet(); err == nil {
+		t.Error("Expected error for commands called a different number of times")
+	}
+}
+
+func TestTimesNotReached(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "hello").Expect("world").Times(2)
+	connection.Do("GET", "hello")
+
+	expected := "Command GET with arguments []interface {}{\"hello\"} expected to be called 2 time(s) but called 1 time(s).\n"
+	if err := connection.ExpectationsWereMet(); err == nil || err.Error() != expected {
+		t.Errorf("Expected error '%s' and got '%v'", expected, err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "hello").Expect("world").Delay(50 * time.Millisecond)
+
+	start := time.Now()
+	if value, err := redis.String(connection.Do("GET", "hello")); err != nil || value != "world" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected reply after the delay and got it after %s", elapsed)
+	}
+
+	_, err := connection.DoWithTimeout(time.Millisecond, "GET", "hello")
+	if netErr, ok := err.(interface{ Timeout() bool }); !ok || !netErr.Timeout() {
+		t.Errorf("Expected timeout error and got '%v'", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := connection.DoContext(ctx, "GET", "hello"); err != context.Canceled {
+		t.Errorf("Expected context error and got '%v'", err)
+	}
+
+	connection.Send("GET", "hello")
+	connection.Flush()
+	if _, err := connection.ReceiveWithTimeout(time.Millisecond); err == nil {
+		t.Error("Expected timeout error for a delayed sent command")
+	}
+}
+
 func TestFind(t *testing.T) {
 	connection := NewConn()
 
@@ -500,7 +571,7 @@ func TestRace(t *testing.T) {
 		func(c *Cmd) { _ = match("GET", []interface{}{[]byte("hello")}, c) },
 		func(c *Cmd) { _ = c.hash() },
 		func(c *Cmd) { _ = c.Called() },
-		func(c *Cmd) { _ = c.getResponse() },
+		func(c *Cmd) { _, _, _, _ = c.call(RepeatLast) },
 		func(c *Cmd) { c.Expect([]byte("OK")) },
 		func(c *Cmd) { c.ExpectMap(map[string]string{"hello": "world"}) },
 		func(c *Cmd) { c.ExpectError(fmt.Errorf("oh no")) },
@@ -534,13 +605,12 @@ func TestRace(t *testing.T) {
 
 		wg.Wait()
 
-		// we should have 12 to 14 responses, depending on how many of the
-		// getResponse calls ran before at least two Expects, since getResponse
-		// pops a response only if there are at least two responses
+		// we should have 14 responses, as call keeps the returned
+		// responses (see ResponsePolicy)
 
 		l := len(cmd.responses)
-		if l < 12 || l > 14 {
-			t.Errorf("wanted 12-14 responses, got %v: %v", l, cmd.responses)
+		if l != 14 {
+			t.Errorf("wanted 14 responses, got %v: %v", l, cmd.responses)
 		}
 	}
 }
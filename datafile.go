@@ -0,0 +1,80 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isJSONFile checks if the file should be encoded as JSON instead of YAML,
+// based on its extension
+func isJSONFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".json"
+}
+
+// readDataFile decodes the YAML or JSON file into v, selecting the format by
+// the file extension. Fields unknown by v are rejected, so files of other
+// formats aren't silently accepted
+func readDataFile(path string, v interface{}) error {
+	return decodeDataFile(path, v, false)
+}
+
+// readExactDataFile works like readDataFile, but the JSON numbers decoded into
+// interface values are kept as json.Number, so integers above 2^53 aren't
+// rounded
+func readExactDataFile(path string, v interface{}) error {
+	return decodeDataFile(path, v, true)
+}
+
+// decodeDataFile decodes the YAML or JSON file into v (see readDataFile)
+func decodeDataFile(path string, v interface{}, useNumber bool) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if isJSONFile(path) {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if useNumber {
+			decoder.UseNumber()
+		}
+		err = decoder.Decode(v)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(v)
+	}
+
+	// empty files have no data
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// writeDataFile encodes v into a YAML or JSON file, selecting the format by
+// the file extension
+func writeDataFile(path string, v interface{}) error {
+	var content []byte
+	var err error
+
+	if isJSONFile(path) {
+		content, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		content, err = yaml.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
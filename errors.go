@@ -0,0 +1,133 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// The functions below build the error replies of the Redis server, so tests
+// can verify retry and redirect logic with realistic errors. They can be used
+// in registered commands (see the Cmd helpers like ExpectMoved) and in fault
+// injection (see Fault.Error).
+
+// WrongTypeError returns the error of a command executed against a key
+// holding the wrong kind of value
+func WrongTypeError() redis.Error {
+	return redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+// MovedError returns the cluster redirection error when the hash slot is
+// served by another node
+func MovedError(slot int, addr string) redis.Error {
+	return redis.Error(fmt.Sprintf("MOVED %d %s", slot, addr))
+}
+
+// AskError returns the cluster redirection error when the hash slot is being
+// migrated to another node
+func AskError(slot int, addr string) redis.Error {
+	return redis.Error(fmt.Sprintf("ASK %d %s", slot, addr))
+}
+
+// NoScriptError returns the error of an EVALSHA executed with an unknown
+// script
+func NoScriptError() redis.Error {
+	return redis.Error("NOSCRIPT No matching script. Please use EVAL.")
+}
+
+// BusyError returns the error of a command executed while a script is running
+func BusyError() redis.Error {
+	return redis.Error("BUSY Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.")
+}
+
+// LoadingError returns the error of a command executed while the server is
+// loading the dataset
+func LoadingError() redis.Error {
+	return redis.Error("LOADING Redis is loading the dataset in memory")
+}
+
+// ReadOnlyError returns the error of a write command executed in a replica
+func ReadOnlyError() redis.Error {
+	return redis.Error("READONLY You can't write against a read only replica.")
+}
+
+// OOMError returns the error of a command executed when the server reached
+// the memory limit
+func OOMError() redis.Error {
+	return redis.Error("OOM command not allowed when used memory > 'maxmemory'.")
+}
+
+// NoAuthError returns the error of a command executed without authentication
+func NoAuthError() redis.Error {
+	return redis.Error("NOAUTH Authentication required.")
+}
+
+// ExecAbortError returns the error of an EXEC when a queued command failed
+func ExecAbortError() redis.Error {
+	return redis.Error("EXECABORT Transaction discarded because of previous errors.")
+}
+
+// CrossSlotError returns the cluster error of a command with keys in
+// different hash slots
+func CrossSlotError() redis.Error {
+	return redis.Error("CROSSSLOT Keys in request don't hash to the same slot")
+}
+
+// ExpectWrongType works like ExpectError with a WRONGTYPE error
+func (c *Cmd) ExpectWrongType() *Cmd {
+	return c.ExpectError(WrongTypeError())
+}
+
+// ExpectMoved works like ExpectError with a MOVED redirection error
+func (c *Cmd) ExpectMoved(slot int, addr string) *Cmd {
+	return c.ExpectError(MovedError(slot, addr))
+}
+
+// ExpectAsk works like ExpectError with an ASK redirection error
+func (c *Cmd) ExpectAsk(slot int, addr string) *Cmd {
+	return c.ExpectError(AskError(slot, addr))
+}
+
+// ExpectNoScript works like ExpectError with a NOSCRIPT error
+func (c *Cmd) ExpectNoScript() *Cmd {
+	return c.ExpectError(NoScriptError())
+}
+
+// ExpectBusy works like ExpectError with a BUSY error
+func (c *Cmd) ExpectBusy() *Cmd {
+	return c.ExpectError(BusyError())
+}
+
+// ExpectLoading works like ExpectError with a LOADING error
+func (c *Cmd) ExpectLoading() *Cmd {
+	return c.ExpectError(LoadingError())
+}
+
+// ExpectReadOnly works like ExpectError with a READONLY error
+func (c *Cmd) ExpectReadOnly() *Cmd {
+	return c.ExpectError(ReadOnlyError())
+}
+
+// ExpectOOM works like ExpectError with an OOM error
+func (c *Cmd) ExpectOOM() *Cmd {
+	return c.ExpectError(OOMError())
+}
+
+// ExpectNoAuth works like ExpectError with a NOAUTH error
+func (c *Cmd) ExpectNoAuth() *Cmd {
+	return c.ExpectError(NoAuthError())
+}
+
+// ExpectExecAbort works like ExpectError with an EXECABORT error
+func (c *Cmd) ExpectExecAbort() *Cmd {
+	return c.ExpectError(ExecAbortError())
+}
+
+// ExpectCrossSlot works like ExpectError with a CROSSSLOT error
+func (c *Cmd) ExpectCrossSlot() *Cmd {
+	return c.ExpectError(CrossSlotError())
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestRedisErrors(t *testing.T) {
+	data := []struct {
+		cmd    func(*Cmd) *Cmd
+		prefix string
+	}{
+		{cmd: (*Cmd).ExpectWrongType, prefix: "WRONGTYPE "},
+		{cmd: func(c *Cmd) *Cmd { return c.ExpectMoved(3999, "127.0.0.1:6381") }, prefix: "MOVED 3999 127.0.0.1:6381"},
+		{cmd: func(c *Cmd) *Cmd { return c.ExpectAsk(3999, "127.0.0.1:6381") }, prefix: "ASK 3999 127.0.0.1:6381"},
+		{cmd: (*Cmd).ExpectNoScript, prefix: "NOSCRIPT "},
+		{cmd: (*Cmd).ExpectBusy, prefix: "BUSY "},
+		{cmd: (*Cmd).ExpectLoading, prefix: "LOADING "},
+		{cmd: (*Cmd).ExpectReadOnly, prefix: "READONLY "},
+		{cmd: (*Cmd).ExpectOOM, prefix: "OOM "},
+		{cmd: (*Cmd).ExpectNoAuth, prefix: "NOAUTH "},
+		{cmd: (*Cmd).ExpectExecAbort, prefix: "EXECABORT "},
+		{cmd: (*Cmd).ExpectCrossSlot, prefix: "CROSSSLOT "},
+	}
+
+	for i, item := range data {
+		connection := NewConn()
+		item.cmd(connection.Command("GET", "key"))
+
+		_, err := connection.Do("GET", "key")
+		redisErr, ok := err.(redis.Error)
+		if !ok {
+			t.Errorf("Item %d: expected redis.Error and got '%T'", i, err)
+			continue
+		}
+
+		if !strings.HasPrefix(string(redisErr), item.prefix) {
+			t.Errorf("Item %d: expected error starting with '%s' and got '%s'", i, item.prefix, redisErr)
+		}
+	}
+}
+
+func TestRedisErrorsAsFault(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "key").Expect("value")
+	connection.Fault().Times(1).Error(MovedError(866, "10.0.0.2:6379"))
+
+	if _, err := connection.Do("GET", "key"); err != MovedError(866, "10.0.0.2:6379") {
+		t.Errorf("Expected MOVED error and got '%v'", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+//go:generate go run gen_commands.go
+
+// Expectations registers commands with typed arguments and replies, generated
+// from the Redis command table (see commands.json). Required arguments are
+// typed parameters, while optional ones (like the SET options) are sent as the
+// trailing arguments. Arguments are matched as they are sent to the Redis
+// server, so a command registered with an int64 also matches the same number
+// sent as int or string. Repeated pairs, like the fields and values of HSET,
+// are given as a map and match the pairs sent in any order
+type Expectations struct {
+	conn *Conn
+}
+
+// Expect returns the typed command registrations of the connection, e.g.
+// c.Expect().Get("key").Return("value")
+func (c *Conn) Expect() *Expectations {
+	return &Expectations{conn: c}
+}
+
+// command registers the command, matching the arguments as they are sent to
+// the Redis server. The blocks of arguments are matched regardless of their
+// order
+func (e *Expectations) command(commandName string, args []interface{}, blocks ...argBlock) *Cmd {
+	return e.conn.addCommand(&Cmd{
+		name:   commandName,
+		args:   args,
+		wire:   true,
+		blocks: blocks,
+	})
+}
+
+// StatusCmd is a command that replies with a status, like OK
+type StatusCmd struct {
+	*Cmd
+}
+
+// Return sets the status replied by the command
+func (c *StatusCmd) Return(status string) *StatusCmd {
+	c.Expect(status)
+	return c
+}
+
+// ReturnOK sets the OK status as the reply of the command
+func (c *StatusCmd) ReturnOK() *StatusCmd {
+	return c.Return("OK")
+}
+
+// ReturnNil sets a nil reply, like the one of a SET with the NX option when
+// the key exists
+func (c *StatusCmd) ReturnNil() *StatusCmd {
+	c.Expect(nil)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *StatusCmd) ReturnError(err error) *StatusCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// StringCmd is a command that replies with a bulk string
+type StringCmd struct {
+	*Cmd
+}
+
+// Return sets the string replied by the command
+func (c *StringCmd) Return(value string) *StringCmd {
+	c.Expect([]byte(value))
+	return c
+}
+
+// ReturnBytes sets the bytes replied by the command
+func (c *StringCmd) ReturnBytes(value []byte) *StringCmd {
+	c.Expect(value)
+	return c
+}
+
+// ReturnNil sets a nil reply, like the one of a missing key
+func (c *StringCmd) ReturnNil() *StringCmd {
+	c.Expect(nil)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *StringCmd) ReturnError(err error) *StringCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// IntCmd is a command that replies with an integer
+type IntCmd struct {
+	*Cmd
+}
+
+// ReturnInt sets the integer replied by the command
+func (c *IntCmd) ReturnInt(value int64) *IntCmd {
+	c.Expect(value)
+	return c
+}
+
+// ReturnBool sets the integer replied by the command as 1 or 0, like the
+// replies of SETNX or EXPIRE
+func (c *IntCmd) ReturnBool(value bool) *IntCmd {
+	if value {
+		return c.ReturnInt(1)
+	}
+	return c.ReturnInt(0)
+}
+
+// ReturnError sets the error returned by the command
+func (c *IntCmd) ReturnError(err error) *IntCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// FloatCmd is a command that replies with a floating point number, sent as a
+// bulk string in RESP2 (see Double)
+type FloatCmd struct {
+	*Cmd
+}
+
+// ReturnFloat sets the number replied by the command
+func (c *FloatCmd) ReturnFloat(value float64) *FloatCmd {
+	c.ExpectDouble(value)
+	return c
+}
+
+// ReturnNil sets a nil reply, like the one of a missing member
+func (c *FloatCmd) ReturnNil() *FloatCmd {
+	c.Expect(nil)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *FloatCmd) ReturnError(err error) *FloatCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// SliceCmd is a command that replies with an array
+type SliceCmd struct {
+	*Cmd
+}
+
+// ReturnStrings sets the strings replied by the command, as bulk strings
+func (c *SliceCmd) ReturnStrings(values ...string) *SliceCmd {
+	c.ExpectStringSlice(values...)
+	return c
+}
+
+// ReturnValues sets the values replied by the command, like the nil values
+// of MGET
+func (c *SliceCmd) ReturnValues(values ...interface{}) *SliceCmd {
+	c.ExpectSlice(values...)
+	return c
+}
+
+// ReturnNil sets a nil reply, like the one of a BLPOP timeout
+func (c *SliceCmd) ReturnNil() *SliceCmd {
+	c.Expect(nil)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *SliceCmd) ReturnError(err error) *SliceCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// MapCmd is a command that replies with a map, sent as an array of keys and
+// values in RESP2 (see RESP3Map)
+type MapCmd struct {
+	*Cmd
+}
+
+// ReturnMap sets the map replied by the command
+func (c *MapCmd) ReturnMap(values map[string]string) *MapCmd {
+	resp := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		resp[key] = []byte(value)
+	}
+	c.ExpectRESP3Map(resp)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *MapCmd) ReturnError(err error) *MapCmd {
+	c.ExpectError(err)
+	return c
+}
+
+// ValueCmd is a command that replies with any type, like EVAL
+type ValueCmd struct {
+	*Cmd
+}
+
+// Return sets the value replied by the command
+func (c *ValueCmd) Return(value interface{}) *ValueCmd {
+	c.Expect(value)
+	return c
+}
+
+// ReturnError sets the error returned by the command
+func (c *ValueCmd) ReturnError(err error) *ValueCmd {
+	c.ExpectError(err)
+	return c
+}
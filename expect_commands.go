@@ -0,0 +1,652 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Code generated by gen_commands.go from commands.json. DO NOT EDIT.
+
+package redigomock
+
+// Append registers the APPEND command: Appends a string to the value of a key
+func (e *Expectations) Append(key string, value string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, value)
+	return &IntCmd{e.command("APPEND", cmdArgs)}
+}
+
+// BLPop registers the BLPOP command: Removes and returns the first element in a list
+func (e *Expectations) BLPop(keys []string, timeout float64) *SliceCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	cmdArgs = append(cmdArgs, timeout)
+	return &SliceCmd{e.command("BLPOP", cmdArgs)}
+}
+
+// BRPop registers the BRPOP command: Removes and returns the last element in a list
+func (e *Expectations) BRPop(keys []string, timeout float64) *SliceCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	cmdArgs = append(cmdArgs, timeout)
+	return &SliceCmd{e.command("BRPOP", cmdArgs)}
+}
+
+// DBSize registers the DBSIZE command: Returns the number of keys in the database
+func (e *Expectations) DBSize() *IntCmd {
+	var cmdArgs []interface{}
+	return &IntCmd{e.command("DBSIZE", cmdArgs)}
+}
+
+// Decr registers the DECR command: Decrements the integer value of a key by one
+func (e *Expectations) Decr(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("DECR", cmdArgs)}
+}
+
+// DecrBy registers the DECRBY command: Decrements a number from the integer value of a key
+func (e *Expectations) DecrBy(key string, decrement int64) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, decrement)
+	return &IntCmd{e.command("DECRBY", cmdArgs)}
+}
+
+// Del registers the DEL command: Deletes one or more keys
+func (e *Expectations) Del(keys ...string) *IntCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("DEL", cmdArgs)}
+}
+
+// Echo registers the ECHO command: Returns the given string
+func (e *Expectations) Echo(message string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, message)
+	return &StringCmd{e.command("ECHO", cmdArgs)}
+}
+
+// Eval registers the EVAL command: Executes a server-side Lua script
+func (e *Expectations) Eval(script string, numkeys int64, args ...interface{}) *ValueCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, script)
+	cmdArgs = append(cmdArgs, numkeys)
+	cmdArgs = append(cmdArgs, args...)
+	return &ValueCmd{e.command("EVAL", cmdArgs)}
+}
+
+// EvalSha registers the EVALSHA command: Executes a server-side Lua script by SHA1 digest
+func (e *Expectations) EvalSha(sha1 string, numkeys int64, args ...interface{}) *ValueCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, sha1)
+	cmdArgs = append(cmdArgs, numkeys)
+	cmdArgs = append(cmdArgs, args...)
+	return &ValueCmd{e.command("EVALSHA", cmdArgs)}
+}
+
+// Exists registers the EXISTS command: Determines whether one or more keys exist
+func (e *Expectations) Exists(keys ...string) *IntCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("EXISTS", cmdArgs)}
+}
+
+// Expire registers the EXPIRE command: Sets the expiration time of a key in seconds
+func (e *Expectations) Expire(key string, seconds int64, condition ...interface{}) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, seconds)
+	cmdArgs = append(cmdArgs, condition...)
+	return &IntCmd{e.command("EXPIRE", cmdArgs)}
+}
+
+// ExpireAt registers the EXPIREAT command: Sets the expiration time of a key to a Unix timestamp
+func (e *Expectations) ExpireAt(key string, unixTimeSeconds int64, condition ...interface{}) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, unixTimeSeconds)
+	cmdArgs = append(cmdArgs, condition...)
+	return &IntCmd{e.command("EXPIREAT", cmdArgs)}
+}
+
+// FlushDB registers the FLUSHDB command: Remove all keys from the current database
+func (e *Expectations) FlushDB(flushType ...interface{}) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, flushType...)
+	return &StatusCmd{e.command("FLUSHDB", cmdArgs)}
+}
+
+// Get registers the GET command: Returns the string value of a key
+func (e *Expectations) Get(key string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &StringCmd{e.command("GET", cmdArgs)}
+}
+
+// GetDel registers the GETDEL command: Returns the string value of a key after deleting the key
+func (e *Expectations) GetDel(key string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &StringCmd{e.command("GETDEL", cmdArgs)}
+}
+
+// GetSet registers the GETSET command: Returns the previous string value of a key after setting it to a new value
+func (e *Expectations) GetSet(key string, value string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, value)
+	return &StringCmd{e.command("GETSET", cmdArgs)}
+}
+
+// HDel registers the HDEL command: Deletes one or more fields and their values from a hash
+func (e *Expectations) HDel(key string, fields ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range fields {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("HDEL", cmdArgs)}
+}
+
+// HExists registers the HEXISTS command: Determines whether a field exists in a hash
+func (e *Expectations) HExists(key string, field string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, field)
+	return &IntCmd{e.command("HEXISTS", cmdArgs)}
+}
+
+// HGet registers the HGET command: Returns the value of a field in a hash
+func (e *Expectations) HGet(key string, field string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, field)
+	return &StringCmd{e.command("HGET", cmdArgs)}
+}
+
+// HGetAll registers the HGETALL command: Returns all fields and values in a hash
+func (e *Expectations) HGetAll(key string) *MapCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &MapCmd{e.command("HGETALL", cmdArgs)}
+}
+
+// HIncrBy registers the HINCRBY command: Increments the integer value of a field in a hash by a number
+func (e *Expectations) HIncrBy(key string, field string, increment int64) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, field)
+	cmdArgs = append(cmdArgs, increment)
+	return &IntCmd{e.command("HINCRBY", cmdArgs)}
+}
+
+// HKeys registers the HKEYS command: Returns all fields in a hash
+func (e *Expectations) HKeys(key string) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &SliceCmd{e.command("HKEYS", cmdArgs)}
+}
+
+// HLen registers the HLEN command: Returns the number of fields in a hash
+func (e *Expectations) HLen(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("HLEN", cmdArgs)}
+}
+
+// HMGet registers the HMGET command: Returns the values of all fields in a hash
+func (e *Expectations) HMGet(key string, fields ...string) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range fields {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &SliceCmd{e.command("HMGET", cmdArgs)}
+}
+
+// HMSet registers the HMSET command: Sets the values of multiple fields
+func (e *Expectations) HMSet(key string, data map[string]string) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	var blocks []argBlock
+	blocks = append(blocks, argBlock{start: len(cmdArgs), size: 2, count: len(data)})
+	for _, field := range sortedKeys(data) {
+		cmdArgs = append(cmdArgs, field, data[field])
+	}
+	return &StatusCmd{e.command("HMSET", cmdArgs, blocks...)}
+}
+
+// HSet registers the HSET command: Creates or modifies the value of a field in a hash
+func (e *Expectations) HSet(key string, data map[string]string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	var blocks []argBlock
+	blocks = append(blocks, argBlock{start: len(cmdArgs), size: 2, count: len(data)})
+	for _, field := range sortedKeys(data) {
+		cmdArgs = append(cmdArgs, field, data[field])
+	}
+	return &IntCmd{e.command("HSET", cmdArgs, blocks...)}
+}
+
+// HSetNX registers the HSETNX command: Sets the value of a field in a hash only when the field doesn't exist
+func (e *Expectations) HSetNX(key string, field string, value string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, field)
+	cmdArgs = append(cmdArgs, value)
+	return &IntCmd{e.command("HSETNX", cmdArgs)}
+}
+
+// HVals registers the HVALS command: Returns all values in a hash
+func (e *Expectations) HVals(key string) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &SliceCmd{e.command("HVALS", cmdArgs)}
+}
+
+// Incr registers the INCR command: Increments the integer value of a key by one
+func (e *Expectations) Incr(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("INCR", cmdArgs)}
+}
+
+// IncrBy registers the INCRBY command: Increments the integer value of a key by a number
+func (e *Expectations) IncrBy(key string, increment int64) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, increment)
+	return &IntCmd{e.command("INCRBY", cmdArgs)}
+}
+
+// IncrByFloat registers the INCRBYFLOAT command: Increment the floating point value of a key by a number
+func (e *Expectations) IncrByFloat(key string, increment float64) *FloatCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, increment)
+	return &FloatCmd{e.command("INCRBYFLOAT", cmdArgs)}
+}
+
+// Keys registers the KEYS command: Returns all key names that match a pattern
+func (e *Expectations) Keys(pattern string) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, pattern)
+	return &SliceCmd{e.command("KEYS", cmdArgs)}
+}
+
+// LIndex registers the LINDEX command: Returns an element from a list by its index
+func (e *Expectations) LIndex(key string, index int64) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, index)
+	return &StringCmd{e.command("LINDEX", cmdArgs)}
+}
+
+// LLen registers the LLEN command: Returns the length of a list
+func (e *Expectations) LLen(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("LLEN", cmdArgs)}
+}
+
+// LPop registers the LPOP command: Returns the first elements in a list after removing it
+func (e *Expectations) LPop(key string, count ...interface{}) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, count...)
+	return &StringCmd{e.command("LPOP", cmdArgs)}
+}
+
+// LPush registers the LPUSH command: Prepends one or more elements to a list
+func (e *Expectations) LPush(key string, elements ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range elements {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("LPUSH", cmdArgs)}
+}
+
+// LRange registers the LRANGE command: Returns a range of elements from a list
+func (e *Expectations) LRange(key string, start int64, stop int64) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, start)
+	cmdArgs = append(cmdArgs, stop)
+	return &SliceCmd{e.command("LRANGE", cmdArgs)}
+}
+
+// LRem registers the LREM command: Removes elements from a list
+func (e *Expectations) LRem(key string, count int64, element string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, count)
+	cmdArgs = append(cmdArgs, element)
+	return &IntCmd{e.command("LREM", cmdArgs)}
+}
+
+// LTrim registers the LTRIM command: Removes elements from both ends a list
+func (e *Expectations) LTrim(key string, start int64, stop int64) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, start)
+	cmdArgs = append(cmdArgs, stop)
+	return &StatusCmd{e.command("LTRIM", cmdArgs)}
+}
+
+// MGet registers the MGET command: Atomically returns the string values of one or more keys
+func (e *Expectations) MGet(keys ...string) *SliceCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &SliceCmd{e.command("MGET", cmdArgs)}
+}
+
+// MSet registers the MSET command: Atomically creates or modifies the string values of one or more keys
+func (e *Expectations) MSet(data map[string]string) *StatusCmd {
+	var cmdArgs []interface{}
+	var blocks []argBlock
+	blocks = append(blocks, argBlock{start: len(cmdArgs), size: 2, count: len(data)})
+	for _, key := range sortedKeys(data) {
+		cmdArgs = append(cmdArgs, key, data[key])
+	}
+	return &StatusCmd{e.command("MSET", cmdArgs, blocks...)}
+}
+
+// Persist registers the PERSIST command: Removes the expiration time of a key
+func (e *Expectations) Persist(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("PERSIST", cmdArgs)}
+}
+
+// PExpire registers the PEXPIRE command: Sets the expiration time of a key in milliseconds
+func (e *Expectations) PExpire(key string, milliseconds int64, condition ...interface{}) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, milliseconds)
+	cmdArgs = append(cmdArgs, condition...)
+	return &IntCmd{e.command("PEXPIRE", cmdArgs)}
+}
+
+// Ping registers the PING command: Returns the server's liveliness response
+func (e *Expectations) Ping(message ...interface{}) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, message...)
+	return &StatusCmd{e.command("PING", cmdArgs)}
+}
+
+// PSetEX registers the PSETEX command: Sets both string value and expiration time in milliseconds of a key
+func (e *Expectations) PSetEX(key string, milliseconds int64, value string) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, milliseconds)
+	cmdArgs = append(cmdArgs, value)
+	return &StatusCmd{e.command("PSETEX", cmdArgs)}
+}
+
+// PTTL registers the PTTL command: Returns the expiration time in milliseconds of a key
+func (e *Expectations) PTTL(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("PTTL", cmdArgs)}
+}
+
+// Publish registers the PUBLISH command: Posts a message to a channel
+func (e *Expectations) Publish(channel string, message string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, channel)
+	cmdArgs = append(cmdArgs, message)
+	return &IntCmd{e.command("PUBLISH", cmdArgs)}
+}
+
+// Rename registers the RENAME command: Renames a key and overwrites the destination
+func (e *Expectations) Rename(key string, newkey string) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, newkey)
+	return &StatusCmd{e.command("RENAME", cmdArgs)}
+}
+
+// RPop registers the RPOP command: Returns and removes the last elements of a list
+func (e *Expectations) RPop(key string, count ...interface{}) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, count...)
+	return &StringCmd{e.command("RPOP", cmdArgs)}
+}
+
+// RPush registers the RPUSH command: Appends one or more elements to a list
+func (e *Expectations) RPush(key string, elements ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range elements {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("RPUSH", cmdArgs)}
+}
+
+// SAdd registers the SADD command: Adds one or more members to a set
+func (e *Expectations) SAdd(key string, members ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range members {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("SADD", cmdArgs)}
+}
+
+// Scan registers the SCAN command: Iterates over the key names in the database
+func (e *Expectations) Scan(cursor int64, args ...interface{}) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, cursor)
+	cmdArgs = append(cmdArgs, args...)
+	return &SliceCmd{e.command("SCAN", cmdArgs)}
+}
+
+// SCard registers the SCARD command: Returns the number of members in a set
+func (e *Expectations) SCard(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("SCARD", cmdArgs)}
+}
+
+// Set registers the SET command: Sets the string value of a key, ignoring its type
+func (e *Expectations) Set(key string, value string, args ...interface{}) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, value)
+	cmdArgs = append(cmdArgs, args...)
+	return &StatusCmd{e.command("SET", cmdArgs)}
+}
+
+// SetEX registers the SETEX command: Sets the string value and expiration time of a key
+func (e *Expectations) SetEX(key string, seconds int64, value string) *StatusCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, seconds)
+	cmdArgs = append(cmdArgs, value)
+	return &StatusCmd{e.command("SETEX", cmdArgs)}
+}
+
+// SetNX registers the SETNX command: Set the string value of a key only when the key doesn't exist
+func (e *Expectations) SetNX(key string, value string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, value)
+	return &IntCmd{e.command("SETNX", cmdArgs)}
+}
+
+// SIsMember registers the SISMEMBER command: Determines whether a member belongs to a set
+func (e *Expectations) SIsMember(key string, member string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, member)
+	return &IntCmd{e.command("SISMEMBER", cmdArgs)}
+}
+
+// SMembers registers the SMEMBERS command: Returns all members of a set
+func (e *Expectations) SMembers(key string) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &SliceCmd{e.command("SMEMBERS", cmdArgs)}
+}
+
+// SPop registers the SPOP command: Returns one or more random members from a set after removing them
+func (e *Expectations) SPop(key string, count ...interface{}) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, count...)
+	return &StringCmd{e.command("SPOP", cmdArgs)}
+}
+
+// SRem registers the SREM command: Removes one or more members from a set
+func (e *Expectations) SRem(key string, members ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range members {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("SREM", cmdArgs)}
+}
+
+// StrLen registers the STRLEN command: Returns the length of a string value
+func (e *Expectations) StrLen(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("STRLEN", cmdArgs)}
+}
+
+// TTL registers the TTL command: Returns the expiration time in seconds of a key
+func (e *Expectations) TTL(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("TTL", cmdArgs)}
+}
+
+// Type registers the TYPE command: Determines the type of value stored at a key
+func (e *Expectations) Type(key string) *StringCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &StringCmd{e.command("TYPE", cmdArgs)}
+}
+
+// Unlink registers the UNLINK command: Asynchronously deletes one or more keys
+func (e *Expectations) Unlink(keys ...string) *IntCmd {
+	var cmdArgs []interface{}
+	for _, arg := range keys {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("UNLINK", cmdArgs)}
+}
+
+// ZAdd registers the ZADD command: Adds one or more members to a sorted set, or updates their scores
+func (e *Expectations) ZAdd(key string, data map[string]float64, args ...interface{}) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, args...)
+	var blocks []argBlock
+	blocks = append(blocks, argBlock{start: len(cmdArgs), size: 2, count: len(data)})
+	for _, member := range sortedKeys(data) {
+		cmdArgs = append(cmdArgs, data[member], member)
+	}
+	return &IntCmd{e.command("ZADD", cmdArgs, blocks...)}
+}
+
+// ZCard registers the ZCARD command: Returns the number of members in a sorted set
+func (e *Expectations) ZCard(key string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	return &IntCmd{e.command("ZCARD", cmdArgs)}
+}
+
+// ZCount registers the ZCOUNT command: Returns the count of members in a sorted set that have scores within a range
+func (e *Expectations) ZCount(key string, min string, max string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, min)
+	cmdArgs = append(cmdArgs, max)
+	return &IntCmd{e.command("ZCOUNT", cmdArgs)}
+}
+
+// ZIncrBy registers the ZINCRBY command: Increments the score of a member in a sorted set
+func (e *Expectations) ZIncrBy(key string, increment float64, member string) *FloatCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, increment)
+	cmdArgs = append(cmdArgs, member)
+	return &FloatCmd{e.command("ZINCRBY", cmdArgs)}
+}
+
+// ZRange registers the ZRANGE command: Returns members in a sorted set within a range of indexes
+func (e *Expectations) ZRange(key string, start string, stop string, args ...interface{}) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, start)
+	cmdArgs = append(cmdArgs, stop)
+	cmdArgs = append(cmdArgs, args...)
+	return &SliceCmd{e.command("ZRANGE", cmdArgs)}
+}
+
+// ZRangeByScore registers the ZRANGEBYSCORE command: Returns members in a sorted set within a range of scores
+func (e *Expectations) ZRangeByScore(key string, min string, max string, args ...interface{}) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, min)
+	cmdArgs = append(cmdArgs, max)
+	cmdArgs = append(cmdArgs, args...)
+	return &SliceCmd{e.command("ZRANGEBYSCORE", cmdArgs)}
+}
+
+// ZRank registers the ZRANK command: Returns the index of a member in a sorted set ordered by ascending scores
+func (e *Expectations) ZRank(key string, member string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, member)
+	return &IntCmd{e.command("ZRANK", cmdArgs)}
+}
+
+// ZRem registers the ZREM command: Removes one or more members from a sorted set
+func (e *Expectations) ZRem(key string, members ...string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	for _, arg := range members {
+		cmdArgs = append(cmdArgs, arg)
+	}
+	return &IntCmd{e.command("ZREM", cmdArgs)}
+}
+
+// ZRemRangeByScore registers the ZREMRANGEBYSCORE command: Removes members in a sorted set within a range of scores
+func (e *Expectations) ZRemRangeByScore(key string, min string, max string) *IntCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, min)
+	cmdArgs = append(cmdArgs, max)
+	return &IntCmd{e.command("ZREMRANGEBYSCORE", cmdArgs)}
+}
+
+// ZRevRangeByScore registers the ZREVRANGEBYSCORE command: Returns members in a sorted set within a range of scores in reverse order
+func (e *Expectations) ZRevRangeByScore(key string, max string, min string, args ...interface{}) *SliceCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, max)
+	cmdArgs = append(cmdArgs, min)
+	cmdArgs = append(cmdArgs, args...)
+	return &SliceCmd{e.command("ZREVRANGEBYSCORE", cmdArgs)}
+}
+
+// ZScore registers the ZSCORE command: Returns the score of a member in a sorted set
+func (e *Expectations) ZScore(key string, member string) *FloatCmd {
+	var cmdArgs []interface{}
+	cmdArgs = append(cmdArgs, key)
+	cmdArgs = append(cmdArgs, member)
+	return &FloatCmd{e.command("ZSCORE", cmdArgs)}
+}
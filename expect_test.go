@@ -0,0 +1,119 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestExpectTyped(t *testing.T) {
+	c := NewConn()
+	c.Expect().Get("k").Return("v")
+	c.Expect().HSet("h", map[string]string{"name": "alice", "age": "30"}).ReturnInt(2)
+	c.Expect().ZRangeByScore("z", "-inf", "(10", "WITHSCORES").ReturnStrings("a", "1", "b", "2")
+	c.Expect().HGetAll("h").ReturnMap(map[string]string{"name": "alice", "age": "30"})
+	c.Expect().ZScore("z", "a").ReturnFloat(1.5)
+	c.Expect().Set("k", "v", "EX", 10).ReturnOK()
+	c.Expect().SetNX("k", "v").ReturnBool(false)
+
+	if value, err := redis.String(c.Do("GET", "k")); err != nil || value != "v" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+	if value, err := redis.Int(c.Do("HSET", "h", "age", 30, "name", "alice")); err != nil || value != 2 {
+		t.Errorf("Unexpected HSET reply %d (%v)", value, err)
+	}
+
+	values, err := redis.Strings(c.Do("ZRANGEBYSCORE", "z", "-inf", "(10", "WITHSCORES"))
+	if err != nil || !reflect.DeepEqual(values, []string{"a", "1", "b", "2"}) {
+		t.Errorf("Unexpected ZRANGEBYSCORE reply %v (%v)", values, err)
+	}
+
+	fields, err := redis.StringMap(c.Do("HGETALL", "h"))
+	if err != nil || !reflect.DeepEqual(fields, map[string]string{"name": "alice", "age": "30"}) {
+		t.Errorf("Unexpected HGETALL reply %v (%v)", fields, err)
+	}
+
+	if score, err := redis.Float64(c.Do("ZSCORE", "z", "a")); err != nil || score != 1.5 {
+		t.Errorf("Unexpected ZSCORE reply %f (%v)", score, err)
+	}
+	if value, err := redis.String(c.Do("SET", "k", "v", "EX", 10)); err != nil || value != "OK" {
+		t.Errorf("Unexpected SET reply '%s' (%v)", value, err)
+	}
+	if value, err := redis.Bool(c.Do("SETNX", "k", "v")); err != nil || value {
+		t.Errorf("Unexpected SETNX reply %t (%v)", value, err)
+	}
+
+	if err := c.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExpectTypedWireArguments(t *testing.T) {
+	c := NewConn()
+	cmd := c.Expect().Expire("k", 10).ReturnInt(1)
+	c.Expect().BLPop([]string{"a", "b"}, 1.5).ReturnStrings("a", "x")
+	c.Expect().Del("a", "b").ReturnError(errors.New("failure"))
+
+	if value, err := redis.Int(c.Do("EXPIRE", "k", 10)); err != nil || value != 1 {
+		t.Errorf("Unexpected EXPIRE reply with int argument %d (%v)", value, err)
+	}
+	if value, err := redis.Int(c.Do("expire", []byte("k"), "10")); err != nil || value != 1 {
+		t.Errorf("Unexpected EXPIRE reply with string argument %d (%v)", value, err)
+	}
+	if c.Stats(cmd.Cmd) != 2 {
+		t.Errorf("Expected EXPIRE to be called 2 times and got %d", c.Stats(cmd.Cmd))
+	}
+
+	if values, err := redis.Strings(c.Do("BLPOP", "a", "b", "1.5")); err != nil || len(values) != 2 {
+		t.Errorf("Unexpected BLPOP reply %v (%v)", values, err)
+	}
+	if _, err := c.Do("DEL", "a", "b"); err == nil || err.Error() != "failure" {
+		t.Errorf("Expected DEL error and got %v", err)
+	}
+
+	c.Expect().ZAdd("z", map[string]float64{"b": 2, "a": 1.5}, "NX").ReturnInt(2)
+	if value, err := redis.Int(c.Do("ZADD", "z", "NX", 1.5, "a", 2, "b")); err != nil || value != 2 {
+		t.Errorf("Unexpected ZADD reply %d (%v)", value, err)
+	}
+
+	if _, err := c.Do("EXPIRE", "k", 11); err == nil {
+		t.Error("Expected error for EXPIRE with different arguments")
+	}
+}
+
+func TestExpectTypedPairsInAnyOrder(t *testing.T) {
+	c := NewConn()
+	hset := c.Expect().HSet("h", map[string]string{"a": "1", "b": "2"}).ReturnInt(2)
+	c.Expect().MSet(map[string]string{"x": "1", "y": "2", "z": "3"}).ReturnOK()
+	c.Expect().ZAdd("z", map[string]float64{"a": 1, "b": 2}, "NX").ReturnInt(2)
+
+	for _, args := range [][]interface{}{{"h", "a", 1, "b", 2}, {"h", "b", 2, "a", 1}} {
+		if value, err := redis.Int(c.Do("HSET", args...)); err != nil || value != 2 {
+			t.Errorf("Unexpected HSET reply for %v %d (%v)", args, value, err)
+		}
+	}
+	if c.Stats(hset.Cmd) != 2 {
+		t.Errorf("Expected HSET to be called 2 times and got %d", c.Stats(hset.Cmd))
+	}
+
+	if value, err := redis.String(c.Do("MSET", "z", 3, "x", 1, "y", 2)); err != nil || value != "OK" {
+		t.Errorf("Unexpected MSET reply '%s' (%v)", value, err)
+	}
+	if value, err := redis.Int(c.Do("ZADD", "z", "NX", 2, "b", 1, "a")); err != nil || value != 2 {
+		t.Errorf("Unexpected ZADD reply %d (%v)", value, err)
+	}
+
+	// the pairs are matched as a whole, not the fields and values separately
+	if _, err := c.Do("HSET", "h", "a", 2, "b", 1); err == nil {
+		t.Error("Expected error for HSET with swapped values")
+	}
+	if _, err := c.Do("HSET", "h", "a", 1, "a", 1); err == nil {
+		t.Error("Expected error for HSET with a repeated pair")
+	}
+}
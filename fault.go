@@ -0,0 +1,264 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault stores the rules to inject errors in the commands executed by the
+// connection, no matter if they are executed by Do or pipelined with Send,
+// Flush and Receive. By default a fault is injected in all commands, use the
+// chained methods to select when it should happen. All selectors must be
+// satisfied to inject the fault
+type Fault struct {
+	name        string        // Name of the command, empty matches all commands
+	args        []interface{} // Arguments of the command, nil matches any arguments
+	every       int           // Inject on every nth matching call
+	calls       []int         // Inject only on these matching calls
+	times       int           // Maximum number of injections, zero is unlimited
+	probability float64       // Probability of the injection, zero is always
+	window      bool          // Inject only inside the clock window
+	from        time.Duration // Start of the clock window
+	until       time.Duration // End of the clock window
+	err         error         // Error returned instead of the command reply
+	drop        bool          // Drop the reply of the command
+	matched     int           // Number of matching calls
+	injected    int           // Number of injected faults
+	mu          sync.Mutex    // Hold while accessing any field
+}
+
+// Fault registers a new fault injection rule in the connection. The returned
+// fault must be configured with Error or Drop to have any effect
+func (c *Conn) Fault() *Fault {
+	fault := &Fault{}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.faults = append(c.faults, fault)
+	return fault
+}
+
+// SeedFaults sets the seed used to decide the faults injected with a
+// probability, so the same faults are injected on every test execution
+func (c *Conn) SeedFaults(seed int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// injectFault checks the registered faults in order, returning the first one
+// that should be injected in the command. The faults of the connection that it
+// shares the registry with (see Pool) are checked after its own ones. If no
+// fault should be injected nil is returned
+//
+// Caller must hold c.mu.
+func (c *Conn) injectFault(commandName string, args []interface{}) *Fault {
+	if len(c.faults) == 0 && c.registry == nil {
+		return nil
+	}
+	elapsed := c.currentClock().Elapsed()
+
+	var injected *Fault
+	inject := func(faults []*Fault, rng func() *rand.Rand) {
+		if len(faults) == 0 {
+			return
+		}
+
+		r := rng()
+		for _, fault := range faults {
+			if fault.inject(commandName, args, elapsed, r) {
+				injected = fault
+				return
+			}
+		}
+	}
+
+	if c.registry == nil {
+		inject(c.faults, c.faultRand)
+		return injected
+	}
+
+	// the random numbers of the registry are shared by its connections, so
+	// it's kept locked while they are used
+	c.withRegistry(func(registry *Conn) {
+		faults := append(c.faults[:len(c.faults):len(c.faults)], registry.faults...)
+		inject(faults, func() *rand.Rand {
+			if c.rng != nil {
+				return c.rng
+			}
+			return registry.faultRand()
+		})
+	})
+	return injected
+}
+
+// faultRand returns the random numbers used by fault injection, creating them
+// when needed
+//
+// Caller must hold c.mu.
+func (c *Conn) faultRand() *rand.Rand {
+	if c.rng == nil {
+		c.rng = rand.New(rand.NewSource(1))
+	}
+	return c.rng
+}
+
+// Command restricts the fault to the commands with the given name. When
+// arguments are given, they are matched in the same way of a registered
+// command, including fuzzy matchers
+func (f *Fault) Command(commandName string, args ...interface{}) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.name = commandName
+	if len(args) > 0 {
+		f.args = args
+	}
+	return f
+}
+
+// Every injects the fault on every nth matching call (e.g. every 5th command)
+func (f *Fault) Every(n int) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.every = n
+	return f
+}
+
+// OnCalls injects the fault only on the given matching calls, starting from 1
+// (e.g. the 2nd pipelined command)
+func (f *Fault) OnCalls(calls ...int) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = calls
+	return f
+}
+
+// Times limits the number of injected faults
+func (f *Fault) Times(n int) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.times = n
+	return f
+}
+
+// Probability injects the fault with the given probability, between 0 and 1.
+// The random numbers are generated from the seed defined with SeedFaults
+func (f *Fault) Probability(p float64) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.probability = p
+	return f
+}
+
+// During injects the fault only while the connection clock is inside the
+// window, relative to the clock start (e.g. During(0, 3*time.Second) for the
+// first 3 seconds). The clock is moved with Clock().Advance
+func (f *Fault) During(from, until time.Duration) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.window = true
+	f.from = from
+	f.until = until
+	return f
+}
+
+// Error defines the error returned instead of the command reply when the
+// fault is injected. The command isn't executed. A redis.Error is returned as
+// the error reply of the command, while other errors, like a net.Error or
+// io.EOF, break the connection like a network failure (see Conn.Break)
+func (f *Fault) Error(err error) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+	f.drop = false
+	return f
+}
+
+// Drop makes the command reply to be lost when the fault is injected. The
+// command is executed, but Receive will never return its reply and Do returns
+// a timeout error
+func (f *Fault) Drop() *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = nil
+	f.drop = true
+	return f
+}
+
+// Injected returns the number of times that the fault was injected
+func (f *Fault) Injected() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.injected
+}
+
+// inject checks if the fault should be injected in the command, updating the
+// counters
+func (f *Fault) inject(commandName string, args []interface{}, elapsed time.Duration, rng *rand.Rand) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err == nil && !f.drop {
+		return false
+	}
+
+	if f.name != "" {
+		if !strings.EqualFold(f.name, commandName) {
+			return false
+		}
+		if f.args != nil && !match(commandName, args, &Cmd{name: commandName, args: f.args}) {
+			return false
+		}
+	}
+
+	f.matched++
+
+	if f.window && (elapsed < f.from || elapsed >= f.until) {
+		return false
+	}
+
+	if f.every > 0 && f.matched%f.every != 0 {
+		return false
+	}
+
+	if len(f.calls) > 0 {
+		found := false
+		for _, call := range f.calls {
+			if call == f.matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.times > 0 && f.injected >= f.times {
+		return false
+	}
+
+	if f.probability > 0 && rng.Float64() >= f.probability {
+		return false
+	}
+
+	f.injected++
+	return true
+}
@@ -0,0 +1,180 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestFaultEvery(t *testing.T) {
+	connection := NewConn()
+	connection.GenericCommand("GET").Expect("value")
+
+	busyErr := BusyError()
+	fault := connection.Fault().Every(3).Error(busyErr)
+
+	for i := 1; i <= 6; i++ {
+		_, err := connection.Do("GET", fmt.Sprintf("key%d", i))
+		if i%3 == 0 && err != busyErr {
+			t.Errorf("Call %d: expected injected error and got '%v'", i, err)
+		} else if i%3 != 0 && err != nil {
+			t.Errorf("Call %d: unexpected error '%v'", i, err)
+		}
+	}
+
+	if injected := fault.Injected(); injected != 2 {
+		t.Errorf("Expected 2 injected faults and got %d", injected)
+	}
+}
+
+func TestFaultCommand(t *testing.T) {
+	connection := NewConn()
+	connection.GenericCommand("GET").Expect("value")
+	connection.GenericCommand("SET").Expect("OK")
+
+	connection.Fault().Command("SET", "key", NewAnyData()).Error(redis.Error("READONLY"))
+
+	if _, err := connection.Do("GET", "key"); err != nil {
+		t.Errorf("Unexpected error '%v'", err)
+	}
+
+	if _, err := connection.Do("SET", "other", "value"); err != nil {
+		t.Errorf("Unexpected error '%v'", err)
+	}
+
+	if _, err := connection.Do("SET", "key", "value"); err != redis.Error("READONLY") {
+		t.Errorf("Expected injected error and got '%v'", err)
+	}
+}
+
+func TestFaultDuring(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "key").Expect("value")
+
+	loadingErr := redis.Error("LOADING Redis is loading the dataset in memory")
+	connection.Fault().During(0, 3*time.Second).Error(loadingErr)
+
+	if _, err := connection.Do("GET", "key"); err != loadingErr {
+		t.Errorf("Expected injected error and got '%v'", err)
+	}
+
+	connection.Clock().Advance(3 * time.Second)
+
+	if reply, err := connection.Do("GET", "key"); err != nil || reply != "value" {
+		t.Errorf("Unexpected reply '%v' (%v)", reply, err)
+	}
+}
+
+func TestFaultDropPipelinedReply(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "key1").Expect("value1")
+	connection.Command("GET", "key2").Expect("value2")
+	connection.Command("GET", "key3").Expect("value3")
+
+	connection.Fault().OnCalls(2).Drop()
+
+	connection.Send("GET", "key1")
+	connection.Send("GET", "key2")
+	connection.Send("GET", "key3")
+	connection.Flush()
+
+	for _, expected := range []string{"value1", "value3"} {
+		if reply, err := connection.Receive(); err != nil || reply != expected {
+			t.Errorf("Expected '%s' and got '%v' (%v)", expected, reply, err)
+		}
+	}
+
+	if _, err := connection.Receive(); err == nil {
+		t.Error("Should not have more replies")
+	}
+
+	connection.Fault().Drop()
+	_, err := connection.Do("GET", "key1")
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Expected timeout error and got '%v'", err)
+	}
+}
+
+func TestFaultProbability(t *testing.T) {
+	injected := func() int {
+		connection := NewConn()
+		connection.GenericCommand("GET").Expect("value")
+		connection.SeedFaults(42)
+		fault := connection.Fault().Probability(0.5).Error(redis.Error("ERR random error"))
+
+		for i := 0; i < 100; i++ {
+			connection.Do("GET", "key")
+		}
+		return fault.Injected()
+	}
+
+	first := injected()
+	if first == 0 || first == 100 {
+		t.Errorf("Unexpected number of injected faults %d", first)
+	}
+
+	if second := injected(); first != second {
+		t.Errorf("Same seed should inject the same faults, got %d and %d", first, second)
+	}
+}
+
+func TestFaultTimes(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "key").Expect("value")
+	connection.Fault().Times(1).Error(redis.Error("ERR error"))
+
+	if _, err := connection.Do("GET", "key"); err == nil {
+		t.Error("Expected injected error")
+	}
+
+	if _, err := connection.Do("GET", "key"); err != nil {
+		t.Errorf("Unexpected error '%v'", err)
+	}
+}
+
+func TestFaultBreaksConnection(t *testing.T) {
+	connection := NewConn()
+	connection.GenericCommand("GET").Expect("value")
+	connection.Fault().Command("GET", "broken").Error(io.EOF)
+
+	connection.Send("GET", "broken")
+	connection.Send("GET", "key")
+	if _, err := connection.Do(""); err != io.EOF {
+		t.Errorf("Expected injected error and got '%v'", err)
+	}
+
+	if err := connection.Err(); err != io.EOF {
+		t.Errorf("Expected broken connection and got '%v'", err)
+	}
+	if _, err := connection.Do("GET", "key"); err != io.EOF {
+		t.Errorf("Expected error of the broken connection and got '%v'", err)
+	}
+}
+
+func TestFaultBreaksPipeline(t *testing.T) {
+	connection := NewConn()
+	connection.GenericCommand("GET").Expect("value")
+	connection.Fault().Command("GET", "broken").Error(io.EOF)
+
+	// the sent commands are executed by the first Receive
+	connection.Send("GET", "key")
+	connection.Send("GET", "broken")
+	connection.Send("GET", "key")
+
+	if value, err := redis.String(connection.Receive()); err != nil || value != "value" {
+		t.Errorf("Expected reply sent before the fault and got '%s' (%v)", value, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := connection.Receive(); err != io.EOF {
+			t.Errorf("Expected error of the broken connection and got '%v'", err)
+		}
+	}
+}
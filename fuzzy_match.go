@@ -1,6 +1,9 @@
 package redigomock
 
-import "reflect"
+import (
+	"reflect"
+	"regexp"
+)
 
 // FuzzyMatcher is an interface that exports one function. It can be
 // passed to the Command as an argument. When the command is evaluated against
@@ -32,6 +35,24 @@ func NewAnyData() FuzzyMatcher {
 	return anyData{}
 }
 
+// NewRegexp returns a FuzzyMatcher instance matching arguments that, as sent
+// to the Redis server, match the regular expression. It panics if the
+// expression can't be parsed, like regexp.MustCompile
+func NewRegexp(expr string) FuzzyMatcher {
+	matcher, err := newRegexpMatcher(expr)
+	if err != nil {
+		panic(err)
+	}
+	return matcher
+}
+
+// NewGlob returns a FuzzyMatcher instance matching arguments that, as sent to
+// the Redis server, match the glob-style pattern, with the same syntax of the
+// KEYS command
+func NewGlob(pattern string) FuzzyMatcher {
+	return globMatcher{pattern: pattern}
+}
+
 type anyInt struct{}
 
 func (matcher anyInt) Match(input interface{}) bool {
@@ -67,3 +88,36 @@ func implementsFuzzy(input interface{}) bool {
 	}
 	return inputType.Implements(reflect.TypeOf((*FuzzyMatcher)(nil)).Elem())
 }
+
+type regexpMatcher struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+func newRegexpMatcher(expr string) (FuzzyMatcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return regexpMatcher{expr: expr, re: re}, nil
+}
+
+func (matcher regexpMatcher) Match(input interface{}) bool {
+	return matcher.re.Match(formatArg(input))
+}
+
+func (matcher regexpMatcher) String() string {
+	return "regexp(" + matcher.expr + ")"
+}
+
+type globMatcher struct {
+	pattern string
+}
+
+func (matcher globMatcher) Match(input interface{}) bool {
+	return globMatch(matcher.pattern, argString(input))
+}
+
+func (matcher globMatcher) String() string {
+	return "glob(" + matcher.pattern + ")"
+}
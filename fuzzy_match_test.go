@@ -129,3 +129,78 @@ func TestRemoveRelatedFuzzyCommands(t *testing.T) {
 		t.Errorf("Non fuzzy command cound invalid, expected 9, got %d", len(connection.commands))
 	}
 }
+
+func TestFuzzyCommandMatchRegexp(t *testing.T) {
+	fuzzyCommandTestInput := []struct {
+		arguments []interface{}
+		match     bool
+	}{
+		{[]interface{}{"TEST_COMMAND", "user:1"}, true},
+		{[]interface{}{"TEST_COMMAND", []byte("user:42")}, true},
+		{[]interface{}{"TEST_COMMAND", "user:abc"}, false},
+		{[]interface{}{"TEST_COMMAND", "session:1"}, false},
+		{[]interface{}{"TEST_COMMAND", 1}, false},
+	}
+
+	command := &Cmd{
+		name: "TEST_COMMAND",
+		args: []interface{}{NewRegexp(`^user:\d+$`)},
+	}
+
+	for pos, element := range fuzzyCommandTestInput {
+		if retVal := match(element.arguments[0].(string), element.arguments[1:], command); retVal != element.match {
+			t.Errorf("comparing fuzzy comand failed. Comparison between comand [%+v] and test arguments : [%v] at position %v returned %v while it should have returned %v",
+				command, element.arguments, pos, retVal, element.match)
+		}
+	}
+}
+
+func TestFuzzyCommandMatchGlob(t *testing.T) {
+	fuzzyCommandTestInput := []struct {
+		arguments []interface{}
+		match     bool
+	}{
+		{[]interface{}{"TEST_COMMAND", "user:1"}, true},
+		{[]interface{}{"TEST_COMMAND", "user:"}, true},
+		{[]interface{}{"TEST_COMMAND", "session:1"}, false},
+		{[]interface{}{"TEST_COMMAND", 10}, false},
+	}
+
+	command := &Cmd{
+		name: "TEST_COMMAND",
+		args: []interface{}{NewGlob("user:*")},
+	}
+
+	for pos, element := range fuzzyCommandTestInput {
+		if retVal := match(element.arguments[0].(string), element.arguments[1:], command); retVal != element.match {
+			t.Errorf("comparing fuzzy comand failed. Comparison between comand [%+v] and test arguments : [%v] at position %v returned %v while it should have returned %v",
+				command, element.arguments, pos, retVal, element.match)
+		}
+	}
+}
+
+func TestFuzzyCommandsWithDifferentPatterns(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", NewGlob("user:*")).Expect("user")
+	connection.Command("GET", NewGlob("session:*")).Expect("session")
+
+	if len(connection.commands) != 2 {
+		t.Fatalf("Expected 2 registered commands and got %d", len(connection.commands))
+	}
+
+	connection.Command("GET", NewGlob("user:*")).Expect("other user")
+	if len(connection.commands) != 2 {
+		t.Errorf("Expected registration with the same pattern to replace the previous one and got %d commands",
+			len(connection.commands))
+	}
+}
+
+func TestNewRegexpInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for an invalid regular expression")
+		}
+	}()
+
+	NewRegexp("[")
+}
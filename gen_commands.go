@@ -0,0 +1,388 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+// +build ignore
+
+// gen_commands generates the typed command registrations (see Expectations)
+// and the specifications used to validate the commands (see
+// Conn.ValidateCommands) from the Redis command table in commands.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+// command is a command of the Redis command table
+type command struct {
+	Summary     string                 `json:"summary"`
+	Group       string                 `json:"group"`
+	Arity       int                    `json:"arity"`
+	Flags       []string               `json:"command_flags"`
+	KeySpecs    []keySpec              `json:"key_specs"`
+	Arguments   []argument             `json:"arguments"`
+	ReplySchema map[string]interface{} `json:"reply_schema"`
+}
+
+// keySpec describes the position of the keys in the arguments of a command
+type keySpec struct {
+	BeginSearch struct {
+		Index struct {
+			Pos int `json:"pos"`
+		} `json:"index"`
+	} `json:"begin_search"`
+	FindKeys struct {
+		Range *struct {
+			LastKey int `json:"lastkey"`
+			Step    int `json:"step"`
+		} `json:"range"`
+		KeyNum *struct{} `json:"keynum"`
+	} `json:"find_keys"`
+}
+
+// argument is an argument of a command in the Redis command table
+type argument struct {
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Token        string     `json:"token"`
+	Optional     bool       `json:"optional"`
+	Multiple     bool       `json:"multiple"`
+	KeySpecIndex int        `json:"key_spec_index"`
+	Arguments    []argument `json:"arguments"`
+}
+
+// goNames are the method names of the commands composed by more than one
+// word, the other ones only have the first letter in upper case
+var goNames = map[string]string{
+	"BLPOP": "BLPop", "BRPOP": "BRPop", "DBSIZE": "DBSize",
+	"DECRBY": "DecrBy", "EVALSHA": "EvalSha", "EXPIREAT": "ExpireAt", "FLUSHDB": "FlushDB",
+	"GETDEL": "GetDel", "GETSET": "GetSet", "HDEL": "HDel", "HEXISTS": "HExists",
+	"HGET": "HGet", "HGETALL": "HGetAll", "HINCRBY": "HIncrBy", "HKEYS": "HKeys",
+	"HLEN": "HLen", "HMGET": "HMGet", "HMSET": "HMSet", "HSET": "HSet",
+	"HSETNX": "HSetNX", "HVALS": "HVals", "INCRBY": "IncrBy", "INCRBYFLOAT": "IncrByFloat",
+	"LINDEX": "LIndex", "LLEN": "LLen", "LPOP": "LPop", "LPUSH": "LPush",
+	"LRANGE": "LRange", "LREM": "LRem", "LTRIM": "LTrim", "MGET": "MGet",
+	"MSET": "MSet", "PEXPIRE": "PExpire", "PSETEX": "PSetEX", "PTTL": "PTTL",
+	"RPOP": "RPop", "RPUSH": "RPush", "SADD": "SAdd", "SCARD": "SCard",
+	"SETEX": "SetEX", "SETNX": "SetNX", "SISMEMBER": "SIsMember", "SMEMBERS": "SMembers",
+	"SPOP": "SPop", "SREM": "SRem", "STRLEN": "StrLen", "TTL": "TTL",
+	"ZADD": "ZAdd", "ZCARD": "ZCard", "ZCOUNT": "ZCount", "ZINCRBY": "ZIncrBy",
+	"ZRANGE": "ZRange", "ZRANGEBYSCORE": "ZRangeByScore", "ZRANK": "ZRank", "ZREM": "ZRem",
+	"ZREMRANGEBYSCORE": "ZRemRangeByScore", "ZREVRANGEBYSCORE": "ZRevRangeByScore", "ZSCORE": "ZScore",
+}
+
+func main() {
+	data, err := ioutil.ReadFile("commands.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var commands map[string]command
+	if err := json.Unmarshal(data, &commands); err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var methods bytes.Buffer
+	writeHeader(&methods)
+	for _, name := range names {
+		if err := writeMethod(&methods, name, commands[name]); err != nil {
+			log.Fatalf("%s: %s", name, err)
+		}
+	}
+	writeSource("expect_commands.go", &methods)
+
+	var specs bytes.Buffer
+	writeHeader(&specs)
+	specs.WriteString("\n// commandSpecs are the specifications of the commands, used to validate\n")
+	specs.WriteString("// their arguments (see Conn.ValidateCommands), to find their keys and to\n")
+	specs.WriteString("// reject the write commands on replicas\n")
+	specs.WriteString("var commandSpecs = map[string]*commandSpec{\n")
+	for _, name := range names {
+		if err := writeSpec(&specs, name, commands[name]); err != nil {
+			log.Fatalf("%s: %s", name, err)
+		}
+	}
+	specs.WriteString("}\n")
+	writeSource("command_specs.go", &specs)
+}
+
+// writeHeader writes the beginning of a generated file
+func writeHeader(buf *bytes.Buffer) {
+	buf.WriteString("// Copyright 2014 Rafael Dantas Justo. All rights reserved.\n")
+	buf.WriteString("// Use of this source code is governed by a MIT\n")
+	buf.WriteString("// license that can be found in the LICENSE file.\n\n")
+	buf.WriteString("// Code generated by gen_commands.go from commands.json. DO NOT EDIT.\n\n")
+	buf.WriteString("package redigomock\n")
+}
+
+// writeSource formats the generated code and writes it to the file
+func writeSource(path string, buf *bytes.Buffer) {
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("%s: %s", path, err)
+	}
+	if err := ioutil.WriteFile(path, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeSpec writes the specification of the command, with its arity, key
+// positions and arguments
+func writeSpec(buf *bytes.Buffer, name string, cmd command) error {
+	fmt.Fprintf(buf, "%q: {\narity: %d,\n", name, cmd.Arity)
+
+	for _, flag := range cmd.Flags {
+		if flag == "WRITE" {
+			buf.WriteString("write: true,\n")
+		}
+	}
+
+	if len(cmd.KeySpecs) > 0 {
+		buf.WriteString("keySpecs: []keySpec{\n")
+		for _, ks := range cmd.KeySpecs {
+			switch {
+			case ks.FindKeys.Range != nil:
+				r := ks.FindKeys.Range
+				fmt.Fprintf(buf, "{pos: %d, lastKey: %d, step: %d},\n", ks.BeginSearch.Index.Pos, r.LastKey, r.Step)
+			case ks.FindKeys.KeyNum != nil:
+				fmt.Fprintf(buf, "{pos: %d, keyNum: true},\n", ks.BeginSearch.Index.Pos)
+			default:
+				return fmt.Errorf("unsupported key specification")
+			}
+		}
+		buf.WriteString("},\n")
+	}
+
+	if len(cmd.Arguments) > 0 {
+		buf.WriteString("arguments: ")
+		writeArgSpecs(buf, cmd.Arguments)
+		buf.WriteString(",\n")
+	}
+
+	buf.WriteString("},\n")
+	return nil
+}
+
+// writeArgSpecs writes the specification of the arguments
+func writeArgSpecs(buf *bytes.Buffer, args []argument) {
+	buf.WriteString("[]argSpec{\n")
+	for _, arg := range args {
+		fmt.Fprintf(buf, "{name: %q, kind: %q", arg.Name, arg.Type)
+		if arg.Token != "" {
+			fmt.Fprintf(buf, ", token: %q", arg.Token)
+		}
+		if arg.Optional {
+			buf.WriteString(", optional: true")
+		}
+		if arg.Multiple {
+			buf.WriteString(", multiple: true")
+		}
+		if len(arg.Arguments) > 0 {
+			buf.WriteString(", arguments: ")
+			writeArgSpecs(buf, arg.Arguments)
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}")
+}
+
+// writeMethod writes the Expectations method registering the command
+func writeMethod(buf *bytes.Buffer, name string, cmd command) error {
+	goName, ok := goNames[name]
+	if !ok {
+		goName = name[:1] + strings.ToLower(name[1:])
+	}
+
+	reply, err := replyType(cmd.ReplySchema)
+	if err != nil {
+		return err
+	}
+
+	var params, body []string
+	variadic, hasBlocks := "", false
+	for i, arg := range cmd.Arguments {
+		last := i == len(cmd.Arguments)-1
+
+		// pairs repeated by the caller, like field and value, are sent as a
+		// map in the order of its keys, but matched in any order
+		if mapType, keyArg, ok := pairType(arg); ok {
+			param := paramName(arg)
+			params = append(params, param+" "+mapType)
+
+			values := make([]string, len(arg.Arguments))
+			for j := range arg.Arguments {
+				values[j] = param + "[" + keyArg + "]"
+				if j == keyIndex(arg) {
+					values[j] = keyArg
+				}
+			}
+			if !hasBlocks {
+				body = append(body, "var blocks []argBlock")
+				hasBlocks = true
+			}
+			body = append(body, fmt.Sprintf("blocks = append(blocks, argBlock{start: len(cmdArgs), size: %d, count: len(%s)})",
+				len(arg.Arguments), param))
+			body = append(body, fmt.Sprintf("for _, %s := range sortedKeys(%s) {\ncmdArgs = append(cmdArgs, %s)\n}",
+				keyArg, param, strings.Join(values, ", ")))
+			continue
+		}
+
+		// optional and composed arguments are sent by the caller as they are
+		// sent to the Redis server
+		if arg.Optional || arg.Type == "block" || arg.Type == "oneof" {
+			if variadic == "" {
+				variadic = "args"
+				if last {
+					variadic = paramName(arg)
+				}
+				body = append(body, fmt.Sprintf("cmdArgs = append(cmdArgs, %s...)", variadic))
+			}
+			continue
+		}
+		if variadic != "" {
+			return fmt.Errorf("argument %s after optional arguments", arg.Name)
+		}
+
+		if arg.Token != "" {
+			body = append(body, fmt.Sprintf("cmdArgs = append(cmdArgs, %q)", arg.Token))
+		}
+		if arg.Type == "pure-token" {
+			continue
+		}
+
+		goType, err := argType(arg)
+		if err != nil {
+			return err
+		}
+
+		param := paramName(arg)
+		switch {
+		case arg.Multiple && last:
+			params = append(params, param+" ..."+goType)
+			body = append(body, fmt.Sprintf("for _, arg := range %s {\ncmdArgs = append(cmdArgs, arg)\n}", param))
+		case arg.Multiple:
+			params = append(params, param+" []"+goType)
+			body = append(body, fmt.Sprintf("for _, arg := range %s {\ncmdArgs = append(cmdArgs, arg)\n}", param))
+		default:
+			params = append(params, param+" "+goType)
+			body = append(body, fmt.Sprintf("cmdArgs = append(cmdArgs, %s)", param))
+		}
+	}
+	if variadic != "" {
+		params = append(params, variadic+" ...interface{}")
+	}
+
+	// only the first sentence of the summary is used in the documentation
+	summary := strings.SplitN(cmd.Summary, ". ", 2)[0]
+	fmt.Fprintf(buf, "\n// %s registers the %s command: %s\n", goName, name, strings.TrimSuffix(summary, "."))
+	fmt.Fprintf(buf, "func (e *Expectations) %s(%s) *%s {\n", goName, strings.Join(params, ", "), reply)
+	buf.WriteString("var cmdArgs []interface{}\n")
+	for _, line := range body {
+		buf.WriteString(line + "\n")
+	}
+	if hasBlocks {
+		fmt.Fprintf(buf, "return &%s{e.command(%q, cmdArgs, blocks...)}\n}\n", reply, name)
+	} else {
+		fmt.Fprintf(buf, "return &%s{e.command(%q, cmdArgs)}\n}\n", reply, name)
+	}
+	return nil
+}
+
+// replyType returns the type used to set the replies of the command
+func replyType(schema map[string]interface{}) (string, error) {
+	if _, ok := schema["const"]; ok {
+		return "StatusCmd", nil
+	}
+
+	switch schema["type"] {
+	case nil:
+		return "ValueCmd", nil
+	case "string":
+		return "StringCmd", nil
+	case "integer":
+		return "IntCmd", nil
+	case "number":
+		return "FloatCmd", nil
+	case "array":
+		return "SliceCmd", nil
+	case "object":
+		return "MapCmd", nil
+	}
+	return "", fmt.Errorf("unsupported reply type %v", schema["type"])
+}
+
+// pairType returns the map type used for a required block of two arguments
+// repeated by the caller, keyed by the name of the string argument
+func pairType(arg argument) (mapType, keyArg string, ok bool) {
+	if arg.Type != "block" || !arg.Multiple || arg.Optional || len(arg.Arguments) != 2 {
+		return "", "", false
+	}
+
+	index := keyIndex(arg)
+	if index < 0 {
+		return "", "", false
+	}
+
+	valueType, err := argType(arg.Arguments[1-index])
+	if err != nil {
+		return "", "", false
+	}
+	return "map[string]" + valueType, paramName(arg.Arguments[index]), true
+}
+
+// keyIndex returns the position of the argument of the block used as map key,
+// the first string one, or -1 when there isn't one
+func keyIndex(arg argument) int {
+	index := -1
+	for i, pair := range arg.Arguments {
+		if pair.Token != "" || pair.Multiple || pair.Optional {
+			return -1
+		}
+		if goType, err := argType(pair); err == nil && goType == "string" && index < 0 {
+			index = i
+		}
+	}
+	return index
+}
+
+// argType returns the Go type of the argument
+func argType(arg argument) (string, error) {
+	switch arg.Type {
+	case "key", "string", "pattern":
+		return "string", nil
+	case "integer", "unix-time":
+		return "int64", nil
+	case "double":
+		return "float64", nil
+	}
+	return "", fmt.Errorf("unsupported argument type %s", arg.Type)
+}
+
+// paramName returns the name of the method parameter for the argument, in
+// camel case and plural when the argument accepts multiple values
+func paramName(arg argument) string {
+	words := strings.Split(arg.Name, "-")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+
+	name := strings.Join(words, "")
+	if arg.Multiple && !strings.HasSuffix(name, "s") && name != "data" {
+		name += "s"
+	}
+	return name
+}
@@ -0,0 +1,236 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// ExpectValue works in the same way of the Expect command, but encodes the
+// value into the types returned by redigo: strings become bulk strings,
+// integers and booleans become integers, floats become doubles (see Double),
+// slices become arrays, and maps and structs become maps (see RESP3Map), where
+// struct fields are named by their redis tag like in redis.ScanStruct
+func ExpectValue[T any](cmd *Cmd, value T) *Cmd {
+	return cmd.Expect(typedReply(value))
+}
+
+// HandleTyped works in the same way of the Handle command, but decodes the
+// arguments into A and encodes the result (see ExpectValue). When A is a
+// struct the arguments are decoded into its exported fields in order, where a
+// trailing slice field receives the remaining arguments. When A is a slice it
+// receives all the arguments, otherwise A receives the only argument
+func HandleTyped[A, R any](cmd *Cmd, fn func(A) (R, error)) *Cmd {
+	return cmd.Handle(func(args []interface{}) (interface{}, error) {
+		var input A
+		if err := decodeArgs(args, reflect.ValueOf(&input).Elem()); err != nil {
+			return nil, err
+		}
+
+		result, err := fn(input)
+		if err != nil {
+			return nil, err
+		}
+		return typedReply(result), nil
+	})
+}
+
+// typedReply converts the value to the types returned by redigo (see
+// ExpectValue)
+func typedReply(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil, []byte, error, Double, BigNumber, VerbatimString, RESP3Map, Set, Push:
+		return value
+	case string:
+		return []byte(value)
+	case *big.Int:
+		return BigNumber{value}
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return typedReply(v.Elem().Interface())
+	case reflect.String:
+		return []byte(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Bool:
+		if v.Bool() {
+			return int64(1)
+		}
+		return int64(0)
+	case reflect.Float32, reflect.Float64:
+		return Double(v.Float())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = typedReply(v.Index(i).Interface())
+		}
+		return values
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		values := make(RESP3Map, v.Len())
+		for _, key := range v.MapKeys() {
+			values[argString(key.Interface())] = typedReply(v.MapIndex(key).Interface())
+		}
+		return values
+	case reflect.Struct:
+		values := make(RESP3Map, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			values[name] = typedReply(v.Field(i).Interface())
+		}
+		return values
+	}
+	return value
+}
+
+// fieldName returns the name of the struct field in the Redis replies, from
+// the redis tag like in redis.ScanStruct. Unexported fields and the ones
+// tagged with "-" are ignored
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	switch tag := field.Tag.Get("redis"); tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return tag, true
+	}
+}
+
+// decodeArgs decodes the command arguments into the value (see HandleTyped)
+func decodeArgs(args []interface{}, v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return decodeStruct(args, v)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		return decodeSlice(args, v)
+	case len(args) != 1:
+		return fmt.Errorf("expected 1 argument and got %d", len(args))
+	}
+	return decodeArg(args[0], v)
+}
+
+// decodeStruct decodes the arguments into the exported fields in order, where
+// a trailing slice field receives the remaining arguments
+func decodeStruct(args []interface{}, v reflect.Value) error {
+	var fields []int
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := fieldName(v.Type().Field(i)); ok {
+			fields = append(fields, i)
+		}
+	}
+
+	pos := 0
+	for i, index := range fields {
+		field, name := v.Field(index), v.Type().Field(index).Name
+
+		if i == len(fields)-1 && field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			if err := decodeSlice(args[pos:], field); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			return nil
+		}
+
+		if pos >= len(args) {
+			return fmt.Errorf("missing argument for field %s", name)
+		}
+		if err := decodeArg(args[pos], field); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		pos++
+	}
+
+	if pos < len(args) {
+		return fmt.Errorf("expected %d arguments and got %d", pos, len(args))
+	}
+	return nil
+}
+
+// decodeSlice decodes all arguments into the slice
+func decodeSlice(args []interface{}, v reflect.Value) error {
+	values := reflect.MakeSlice(v.Type(), len(args), len(args))
+	for i, arg := range args {
+		if err := decodeArg(arg, values.Index(i)); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+	}
+	v.Set(values)
+	return nil
+}
+
+// decodeArg decodes the argument into the value, parsing it as it would be
+// sent to the Redis server
+func decodeArg(arg interface{}, v reflect.Value) error {
+	data := argString(arg)
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if arg != nil {
+			v.Set(reflect.ValueOf(arg))
+		}
+		return nil
+	case reflect.String:
+		v.SetString(data)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(data))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(data, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %q into %s", data, v.Type())
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(data, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %q into %s", data, v.Type())
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(data, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %q into %s", data, v.Type())
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(data)
+		if err != nil {
+			return fmt.Errorf("cannot decode %q into %s", data, v.Type())
+		}
+		v.SetBool(b)
+		return nil
+	}
+	return fmt.Errorf("cannot decode into %s", v.Type())
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestExpectValue(t *testing.T) {
+	type person struct {
+		Name    string `redis:"name"`
+		Age     int    `redis:"age"`
+		Admin   bool
+		Ignored string `redis:"-"`
+		private string
+	}
+
+	c := NewConn()
+	ExpectValue(c.Command("GET", "name"), "alice")
+	ExpectValue(c.Command("GET", "age"), 30)
+	ExpectValue(c.Command("LRANGE", "names", 0, -1), []string{"alice", "bob"})
+	ExpectValue(c.Command("HGETALL", "person"), person{Name: "alice", Age: 30, Admin: true})
+	ExpectValue(c.Command("GET", "missing"), (*string)(nil))
+
+	if value, err := redis.String(c.Do("GET", "name")); err != nil || value != "alice" {
+		t.Errorf("Expected 'alice' and got '%s' (%v)", value, err)
+	}
+	if value, err := redis.Int(c.Do("GET", "age")); err != nil || value != 30 {
+		t.Errorf("Expected 30 and got %d (%v)", value, err)
+	}
+
+	names, err := redis.Strings(c.Do("LRANGE", "names", 0, -1))
+	if err != nil || !reflect.DeepEqual(names, []string{"alice", "bob"}) {
+		t.Errorf("Expected [alice bob] and got %v (%v)", names, err)
+	}
+
+	values, err := redis.Values(c.Do("HGETALL", "person"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p person
+	if err := redis.ScanStruct(values, &p); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (person{Name: "alice", Age: 30, Admin: true}); p != expected {
+		t.Errorf("Expected %+v and got %+v", expected, p)
+	}
+
+	if value, err := c.Do("GET", "missing"); err != nil || value != nil {
+		t.Errorf("Expected nil and got %v (%v)", value, err)
+	}
+}
+
+func TestHandleTyped(t *testing.T) {
+	type setArgs struct {
+		Key     string
+		Value   int64
+		Options []string
+	}
+
+	c := NewConn()
+	HandleTyped(c.GenericCommand("SET"), func(args setArgs) (string, error) {
+		if args.Value < 0 {
+			return "", errors.New("negative value")
+		}
+		return args.Key + ":" + args.Options[0], nil
+	})
+	HandleTyped(c.GenericCommand("SUM"), func(numbers []float64) (float64, error) {
+		var sum float64
+		for _, n := range numbers {
+			sum += n
+		}
+		return sum, nil
+	})
+	HandleTyped(c.GenericCommand("EXISTS"), func(key string) (bool, error) {
+		return key == "present", nil
+	})
+
+	if value, err := redis.String(c.Do("SET", "k", []byte("10"), "NX")); err != nil || value != "k:NX" {
+		t.Errorf("Expected 'k:NX' and got '%s' (%v)", value, err)
+	}
+	if _, err := c.Do("SET", "k", -1, "NX"); err == nil || err.Error() != "negative value" {
+		t.Errorf("Expected handler error and got %v", err)
+	}
+	if _, err := c.Do("SET", "k", "ten"); err == nil {
+		t.Error("Expected error decoding the value")
+	}
+	if _, err := c.Do("SET", "k"); err == nil {
+		t.Error("Expected error for missing argument")
+	}
+
+	if value, err := redis.Float64(c.Do("SUM", 1, "2.5")); err != nil || value != 3.5 {
+		t.Errorf("Expected 3.5 and got %f (%v)", value, err)
+	}
+
+	if value, err := redis.Bool(c.Do("EXISTS", "present")); err != nil || !value {
+		t.Errorf("Expected true and got %t (%v)", value, err)
+	}
+	if _, err := c.Do("EXISTS", "a", "b"); err == nil {
+		t.Error("Expected error for extra arguments")
+	}
+}
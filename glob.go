@@ -0,0 +1,92 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+// globMatch checks if the value matches the glob-style pattern in the same way
+// Redis does for PSUBSCRIBE and KEYS. Supported special characters are "*",
+// "?", "[...]" (with "^" negation and "a-z" ranges) and "\" to escape
+func globMatch(pattern, value string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(value); i++ {
+				if globMatch(pattern[1:], value[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(value) == 0 {
+				return false
+			}
+			value = value[1:]
+
+		case '[':
+			if len(value) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			negate := len(pattern) > 0 && pattern[0] == '^'
+			if negate {
+				pattern = pattern[1:]
+			}
+
+			matched := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				switch {
+				case pattern[0] == '\\' && len(pattern) > 1:
+					pattern = pattern[1:]
+					if pattern[0] == value[0] {
+						matched = true
+					}
+				case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+					start, end := pattern[0], pattern[2]
+					if start > end {
+						start, end = end, start
+					}
+					if value[0] >= start && value[0] <= end {
+						matched = true
+					}
+					pattern = pattern[2:]
+				default:
+					if pattern[0] == value[0] {
+						matched = true
+					}
+				}
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				// unterminated class, Redis considers the end of the pattern as the
+				// end of the class
+				pattern = "]"
+			}
+			if matched == negate {
+				return false
+			}
+			value = value[1:]
+
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+
+		default:
+			if len(value) == 0 || pattern[0] != value[0] {
+				return false
+			}
+			value = value[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(value) == 0
+}
@@ -0,0 +1,38 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	data := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{pattern: "news.*", value: "news.tech", expected: true},
+		{pattern: "news.*", value: "news", expected: false},
+		{pattern: "*", value: "", expected: true},
+		{pattern: "h?llo", value: "hello", expected: true},
+		{pattern: "h?llo", value: "hllo", expected: false},
+		{pattern: "h[ae]llo", value: "hallo", expected: true},
+		{pattern: "h[ae]llo", value: "hillo", expected: false},
+		{pattern: "h[^e]llo", value: "hallo", expected: true},
+		{pattern: "h[^e]llo", value: "hello", expected: false},
+		{pattern: "h[a-b]llo", value: "hbllo", expected: true},
+		{pattern: "h[a-b]llo", value: "hcllo", expected: false},
+		{pattern: "h\\*llo", value: "h*llo", expected: true},
+		{pattern: "h\\*llo", value: "hello", expected: false},
+		{pattern: "a*b*c", value: "a/x/b/y/c", expected: true},
+		{pattern: "a*b*c", value: "a/x/b/y/d", expected: false},
+	}
+
+	for i, item := range data {
+		if result := globMatch(item.pattern, item.value); result != item.expected {
+			t.Errorf("Item %d: pattern '%s' with value '%s' expected %t and got %t",
+				i, item.pattern, item.value, item.expected, result)
+		}
+	}
+}
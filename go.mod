@@ -1,5 +1,9 @@
 module github.com/rafaeljusto/redigomock/v3
 
-go 1.15
+go 1.18
 
-require github.com/gomodule/redigo v1.8.8
+require (
+	github.com/gomodule/redigo v1.8.8
+	github.com/yuin/gopher-lua v0.0.0-20210529063254-f4c35e4016d9
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,367 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeySpace is an in-memory data store that answers the core Redis commands
+// for strings, hashes, lists, sets and sorted sets. When a connection uses a
+// key space (see Conn.UseKeySpace), commands that don't match any registered
+// command are executed against it, so tests don't need to register every
+// command of a working key-value store. Registered commands still have
+// priority, allowing error injection.
+//
+// A key space can be shared by many connections, like a Redis server.
+type KeySpace struct {
+	keys          map[string]*entry // Stored keys
+	scripts       scriptCache       // Lua scripts cached by SHA1 digest
+	scriptTimeout time.Duration     // How long a script runs before it's stopped
+	now           time.Time         // Clock time of the command being executed
+	readOnly      bool              // Rejects the writes of scripts, when the command is executed by a replica
+	mu            sync.Mutex        // Hold while accessing keys
+}
+
+// entry stores the value of a key. The value type depends on the data type:
+// []byte for strings, map[string][]byte for hashes, [][]byte for lists,
+// map[string]struct{} for sets and map[string]float64 for sorted sets
+type entry struct {
+	value    interface{}
+	expireAt time.Time // When the key expires, zero if it doesn't expire
+}
+
+// keySpaceCommand executes a command against the key space, receiving the
+// arguments as the bulk strings sent to the Redis server
+type keySpaceCommand struct {
+	min     int // Minimum number of arguments
+	max     int // Maximum number of arguments, negative is unlimited
+	handler func(ks *KeySpace, args [][]byte) (interface{}, error)
+}
+
+// keySpaceCommands stores all commands supported by the key space, by
+// lowercase name
+var keySpaceCommands = map[string]keySpaceCommand{}
+
+// NewKeySpace returns an empty key space
+func NewKeySpace() *KeySpace {
+	return &KeySpace{
+		keys:          make(map[string]*entry),
+		scripts:       make(scriptCache),
+		scriptTimeout: defaultScriptTimeout,
+	}
+}
+
+// UseKeySpace makes the connection execute the commands without a registered
+// response against the key space. A nil key space disables it
+func (c *Conn) UseKeySpace(ks *KeySpace) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keySpace = ks
+}
+
+// KeySpace returns the key space used by the connection, or nil if the
+// connection doesn't use one
+func (c *Conn) KeySpace() *KeySpace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.currentKeySpace()
+}
+
+// currentKeySpace returns the key space used by the connection, falling back
+// to the one of the connection that it shares the registry with (see Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) currentKeySpace() *KeySpace {
+	return fromRegistry(c, func(conn *Conn) *KeySpace {
+		return conn.keySpace
+	})
+}
+
+// exec executes the command against the key space at the given clock time,
+// used to expire keys. When readOnly is true the write commands called by
+// scripts are rejected, like in a replica. If the command isn't supported
+// false is returned
+func (ks *KeySpace) exec(commandName string, args []interface{}, now time.Time, readOnly bool) (replyElement, bool) {
+	if _, ok := keySpaceCommands[strings.ToLower(commandName)]; !ok {
+		return replyElement{}, false
+	}
+
+	bulkArgs := make([][]byte, len(args))
+	for i, arg := range args {
+		bulkArgs[i] = formatArg(arg)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.now, ks.readOnly = now, readOnly
+	reply, err := ks.call(commandName, bulkArgs)
+	return replyElement{reply: reply, err: err}, true
+}
+
+// call executes the command, checking the number of arguments
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) call(commandName string, args [][]byte) (interface{}, error) {
+	name := strings.ToLower(commandName)
+
+	command, ok := keySpaceCommands[name]
+	if !ok {
+		return nil, redis.Error(fmt.Sprintf("ERR unknown command '%s'", commandName))
+	}
+
+	if len(args) < command.min || (command.max >= 0 && len(args) > command.max) {
+		return nil, errWrongArgs(name)
+	}
+	return command.handler(ks, args)
+}
+
+// lookup returns the key entry, checking if it stores the expected data
+// type. The value of a missing key is nil
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) lookup(key []byte, kind string) (*entry, error) {
+	e := ks.get(key)
+	if e == nil {
+		return nil, nil
+	}
+
+	if entryType(e) != kind {
+		return nil, WrongTypeError()
+	}
+	return e, nil
+}
+
+// get returns the key entry, or nil if the key doesn't exist. Expired keys
+// are removed when accessed
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) get(key []byte) *entry {
+	e := ks.keys[string(key)]
+	if e != nil && !e.expireAt.IsZero() && ks.now.After(e.expireAt) {
+		delete(ks.keys, string(key))
+		return nil
+	}
+	return e
+}
+
+// entryType returns the Redis data type name of the key entry
+func entryType(e *entry) string {
+	switch e.value.(type) {
+	case []byte:
+		return "string"
+	case map[string][]byte:
+		return "hash"
+	case [][]byte:
+		return "list"
+	case map[string]struct{}:
+		return "set"
+	case map[string]float64:
+		return "zset"
+	}
+	return "none"
+}
+
+var (
+	errNotInteger   = redis.Error("ERR value is not an integer or out of range")
+	errNotFloat     = redis.Error("ERR value is not a valid float")
+	errSyntax       = redis.Error("ERR syntax error")
+	errNoSuchKey    = redis.Error("ERR no such key")
+	errIncrOverflow = redis.Error("ERR increment or decrement would overflow")
+	errIncrNaN      = redis.Error("ERR increment would produce NaN or Infinity")
+)
+
+// errWrongArgs returns the error of a command called with the wrong number of
+// arguments
+func errWrongArgs(name string) redis.Error {
+	return redis.Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// parseInt parses an integer argument like the Redis server
+func parseInt(arg []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	return n, nil
+}
+
+// parseFloat parses a float argument like the Redis server, accepting
+// infinity values
+func parseFloat(arg []byte) (float64, error) {
+	switch strings.ToLower(string(arg)) {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	}
+
+	f, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+// formatFloat formats a float reply like the Redis server
+func formatFloat(f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return []byte("inf")
+	case math.IsInf(f, -1):
+		return []byte("-inf")
+	}
+	return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// formatIncrFloat adds the increment to the value like INCRBYFLOAT and
+// HINCRBYFLOAT, which use a long double with 17 decimal digits and remove the
+// trailing zeros, so 0.1 plus 0.2 is 0.3. Both numbers must be finite
+func formatIncrFloat(value, increment []byte) []byte {
+	sum := new(big.Float).SetPrec(64)
+	if len(value) > 0 {
+		sum.Parse(string(value), 10)
+	}
+
+	inc, _, _ := big.ParseFloat(string(increment), 10, 64, big.ToNearestEven)
+	sum.Add(sum, inc)
+
+	result := sum.Text('f', 17)
+	result = strings.TrimRight(result, "0")
+	result = strings.TrimSuffix(result, ".")
+	if result == "-0" {
+		result = "0"
+	}
+	return []byte(result)
+}
+
+// rangeIndexes converts the start and stop indexes of a range command
+// (LRANGE, ZRANGE, ...), which can be negative, to slice indexes. If the range
+// is empty ok is false
+func rangeIndexes(start, stop int64, length int) (from, to int, ok bool) {
+	n := int64(length)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return int(start), int(stop) + 1, true
+}
+
+// sortedKeys returns the keys of the map in lexicographical order, so replies
+// and the arguments built from maps are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	keySpaceCommands["del"] = keySpaceCommand{min: 1, max: -1, handler: cmdDel}
+	keySpaceCommands["unlink"] = keySpaceCommand{min: 1, max: -1, handler: cmdDel}
+	keySpaceCommands["exists"] = keySpaceCommand{min: 1, max: -1, handler: cmdExists}
+	keySpaceCommands["type"] = keySpaceCommand{min: 1, max: 1, handler: cmdType}
+	keySpaceCommands["keys"] = keySpaceCommand{min: 1, max: 1, handler: cmdKeys}
+	keySpaceCommands["rename"] = keySpaceCommand{min: 2, max: 2, handler: cmdRename}
+	keySpaceCommands["dbsize"] = keySpaceCommand{min: 0, max: 0, handler: cmdDBSize}
+	keySpaceCommands["flushdb"] = keySpaceCommand{min: 0, max: 1, handler: cmdFlushDB}
+	keySpaceCommands["flushall"] = keySpaceCommand{min: 0, max: 1, handler: cmdFlushDB}
+}
+
+func cmdDel(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var deleted int64
+	for _, key := range args {
+		if ks.get(key) != nil {
+			delete(ks.keys, string(key))
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cmdExists(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var found int64
+	for _, key := range args {
+		if ks.get(key) != nil {
+			found++
+		}
+	}
+	return found, nil
+}
+
+func cmdType(ks *KeySpace, args [][]byte) (interface{}, error) {
+	e := ks.get(args[0])
+	if e == nil {
+		return "none", nil
+	}
+	return entryType(e), nil
+}
+
+func cmdKeys(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var keys []string
+	for key := range ks.keys {
+		if ks.get([]byte(key)) != nil && globMatch(string(args[0]), key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	reply := make([]interface{}, len(keys))
+	for i, key := range keys {
+		reply[i] = []byte(key)
+	}
+	return reply, nil
+}
+
+func cmdRename(ks *KeySpace, args [][]byte) (interface{}, error) {
+	e := ks.get(args[0])
+	if e == nil {
+		return nil, errNoSuchKey
+	}
+
+	delete(ks.keys, string(args[0]))
+	ks.keys[string(args[1])] = e
+	return "OK", nil
+}
+
+func cmdDBSize(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var size int64
+	for key := range ks.keys {
+		if ks.get([]byte(key)) != nil {
+			size++
+		}
+	}
+	return size, nil
+}
+
+func cmdFlushDB(ks *KeySpace, args [][]byte) (interface{}, error) {
+	ks.keys = make(map[string]*entry)
+	return "OK", nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func init() {
+	keySpaceCommands["expire"] = keySpaceCommand{min: 2, max: 3, handler: cmdExpire}
+	keySpaceCommands["pexpire"] = keySpaceCommand{min: 2, max: 3, handler: cmdPExpire}
+	keySpaceCommands["expireat"] = keySpaceCommand{min: 2, max: 3, handler: cmdExpireAt}
+	keySpaceCommands["pexpireat"] = keySpaceCommand{min: 2, max: 3, handler: cmdPExpireAt}
+	keySpaceCommands["ttl"] = keySpaceCommand{min: 1, max: 1, handler: cmdTTL}
+	keySpaceCommands["pttl"] = keySpaceCommand{min: 1, max: 1, handler: cmdPTTL}
+	keySpaceCommands["expiretime"] = keySpaceCommand{min: 1, max: 1, handler: cmdExpireTime}
+	keySpaceCommands["pexpiretime"] = keySpaceCommand{min: 1, max: 1, handler: cmdPExpireTime}
+	keySpaceCommands["persist"] = keySpaceCommand{min: 1, max: 1, handler: cmdPersist}
+}
+
+// errInvalidExpire returns the error of a command called with an expire time
+// out of range
+func errInvalidExpire(name string) redis.Error {
+	return redis.Error(fmt.Sprintf("ERR invalid expire time in '%s' command", name))
+}
+
+// expiryTime parses the argument of an expire option (EX, PX, EXAT or PXAT),
+// returning when the key expires. If positive is true, values that aren't
+// greater than zero are rejected, like the SET command does
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) expiryTime(name, option string, arg []byte, positive bool) (time.Time, error) {
+	n, err := parseInt(arg)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if positive && n <= 0 {
+		return time.Time{}, errInvalidExpire(name)
+	}
+
+	ms := n
+	if option == "ex" || option == "exat" {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return time.Time{}, errInvalidExpire(name)
+		}
+		ms = n * 1000
+	}
+
+	if option == "exat" || option == "pxat" {
+		return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), nil
+	}
+
+	if ms > math.MaxInt64/int64(time.Millisecond) || ms < math.MinInt64/int64(time.Millisecond) {
+		return time.Time{}, errInvalidExpire(name)
+	}
+	return ks.now.Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+// expire sets when the key expires. A time that isn't in the future removes
+// the key immediately, like the Redis server does
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) expire(key []byte, expireAt time.Time) {
+	e := ks.get(key)
+	if e == nil {
+		return
+	}
+
+	if !expireAt.After(ks.now) {
+		delete(ks.keys, string(key))
+		return
+	}
+	e.expireAt = expireAt
+}
+
+// expireCommand implements the EXPIRE family of commands, where option is
+// the SET option with the same expire unit
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) expireCommand(name, option string, args [][]byte) (interface{}, error) {
+	expireAt, err := ks.expiryTime(name, option, args[1], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var nx, xx, gt, lt bool
+	if len(args) == 3 {
+		switch strings.ToLower(string(args[2])) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		default:
+			return nil, redis.Error("ERR Unsupported option " + string(args[2]))
+		}
+	}
+
+	e := ks.get(args[0])
+	if e == nil {
+		return int64(0), nil
+	}
+
+	// keys without a time to live are handled as never expiring
+	persistent := e.expireAt.IsZero()
+	switch {
+	case nx && !persistent,
+		xx && persistent,
+		gt && (persistent || !expireAt.After(e.expireAt)),
+		lt && !persistent && !expireAt.Before(e.expireAt):
+		return int64(0), nil
+	}
+
+	ks.expire(args[0], expireAt)
+	return int64(1), nil
+}
+
+// ttl returns the remaining time to live of the key, -1 if the key doesn't
+// expire or -2 if the key doesn't exist
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) ttl(key []byte, unit time.Duration) int64 {
+	e := ks.get(key)
+	if e == nil {
+		return -2
+	}
+	if e.expireAt.IsZero() {
+		return -1
+	}
+
+	// round to the closest unit, like the Redis server does
+	return int64((e.expireAt.Sub(ks.now) + unit/2) / unit)
+}
+
+// expireTime returns the absolute Unix time when the key expires, -1 if the
+// key doesn't expire or -2 if the key doesn't exist
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) expireTime(key []byte, unit time.Duration) int64 {
+	e := ks.get(key)
+	if e == nil {
+		return -2
+	}
+	if e.expireAt.IsZero() {
+		return -1
+	}
+	return (e.expireAt.UnixNano() + int64(unit/2)) / int64(unit)
+}
+
+func cmdExpire(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireCommand("expire", "ex", args)
+}
+
+func cmdPExpire(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireCommand("pexpire", "px", args)
+}
+
+func cmdExpireAt(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireCommand("expireat", "exat", args)
+}
+
+func cmdPExpireAt(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireCommand("pexpireat", "pxat", args)
+}
+
+func cmdTTL(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.ttl(args[0], time.Second), nil
+}
+
+func cmdPTTL(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.ttl(args[0], time.Millisecond), nil
+}
+
+func cmdExpireTime(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireTime(args[0], time.Second), nil
+}
+
+func cmdPExpireTime(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.expireTime(args[0], time.Millisecond), nil
+}
+
+func cmdPersist(ks *KeySpace, args [][]byte) (interface{}, error) {
+	e := ks.get(args[0])
+	if e == nil || e.expireAt.IsZero() {
+		return int64(0), nil
+	}
+
+	e.expireAt = time.Time{}
+	return int64(1), nil
+}
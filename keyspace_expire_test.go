@@ -0,0 +1,126 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySpaceExpire(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(-2)},
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(-1)},
+		{command: "EXPIRE", args: []interface{}{"key", 10}, expected: int64(1)},
+		{command: "EXPIRE", args: []interface{}{"missing", 10}, expected: int64(0)},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(10)},
+		{advance: 2500 * time.Millisecond, command: "PTTL", args: []interface{}{"key"}, expected: int64(7500)},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(8)},
+		{command: "EXPIRE", args: []interface{}{"key", 20, "NX"}, expected: int64(0)},
+		{command: "EXPIRE", args: []interface{}{"key", 5, "GT"}, expected: int64(0)},
+		{command: "EXPIRE", args: []interface{}{"key", 5, "LT"}, expected: int64(1)},
+		{command: "EXPIRE", args: []interface{}{"key", 5, "XY"}, err: redis.Error("ERR Unsupported option XY")},
+		{command: "PERSIST", args: []interface{}{"key"}, expected: int64(1)},
+		{command: "PERSIST", args: []interface{}{"key"}, expected: int64(0)},
+		{command: "EXPIRE", args: []interface{}{"key", 5, "XX"}, expected: int64(0)},
+		{command: "PEXPIRE", args: []interface{}{"key", 1500}, expected: int64(1)},
+		{advance: 1500 * time.Millisecond, command: "EXISTS", args: []interface{}{"key"}, expected: int64(1)},
+		{advance: time.Millisecond, command: "EXISTS", args: []interface{}{"key"}, expected: int64(0)},
+		{command: "GET", args: []interface{}{"key"}, expected: nil},
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "EXPIRE", args: []interface{}{"key", -1}, expected: int64(1)},
+		{command: "EXISTS", args: []interface{}{"key"}, expected: int64(0)},
+	})
+}
+
+func TestKeySpaceExpireAt(t *testing.T) {
+	at := keySpaceStart.Unix() + 100
+
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "EXPIREAT", args: []interface{}{"key", at}, expected: int64(1)},
+		{command: "EXPIRETIME", args: []interface{}{"key"}, expected: at},
+		{command: "PEXPIRETIME", args: []interface{}{"key"}, expected: at * 1000},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(100)},
+		{command: "PEXPIREAT", args: []interface{}{"key", at*1000 + 500}, expected: int64(1)},
+		{command: "PTTL", args: []interface{}{"key"}, expected: int64(100500)},
+		{command: "EXPIRETIME", args: []interface{}{"missing"}, expected: int64(-2)},
+		{advance: 101 * time.Second, command: "KEYS", args: []interface{}{"*"}, expected: []interface{}{}},
+	})
+}
+
+func TestKeySpaceSetExpire(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SET", args: []interface{}{"key", "value", "EX", 10}, expected: "OK"},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(10)},
+		{command: "SET", args: []interface{}{"key", "other", "KEEPTTL"}, expected: "OK"},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(10)},
+		{command: "SET", args: []interface{}{"key", "other"}, expected: "OK"},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(-1)},
+		{command: "SET", args: []interface{}{"key", "value", "PX", 1500}, expected: "OK"},
+		{command: "PTTL", args: []interface{}{"key"}, expected: int64(1500)},
+		{command: "SET", args: []interface{}{"key", "value", "EXAT", keySpaceStart.Unix() + 5}, expected: "OK"},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(5)},
+		{command: "SET", args: []interface{}{"key", "value", "EX", 0}, err: errInvalidExpire("set")},
+		{command: "SET", args: []interface{}{"key", "value", "EX", "x"}, err: errNotInteger},
+		{command: "SET", args: []interface{}{"key", "value", "EX"}, err: errSyntax},
+		{command: "SET", args: []interface{}{"key", "value", "EX", 1, "KEEPTTL"}, err: errSyntax},
+		{command: "SET", args: []interface{}{"key", "value", "EX", 1, "PX", 1}, err: errSyntax},
+		{command: "SETEX", args: []interface{}{"key", 3, "value"}, expected: "OK"},
+		{command: "PSETEX", args: []interface{}{"key", 3000, "value"}, expected: "OK"},
+		{command: "INCR", args: []interface{}{"counter"}, expected: int64(1)},
+		{command: "EXPIRE", args: []interface{}{"counter", 3}, expected: int64(1)},
+		{command: "INCR", args: []interface{}{"counter"}, expected: int64(2)},
+		{command: "TTL", args: []interface{}{"counter"}, expected: int64(3)},
+		{advance: 3001 * time.Millisecond, command: "GET", args: []interface{}{"key"}, expected: nil},
+		{command: "INCR", args: []interface{}{"counter"}, expected: int64(1)},
+	})
+}
+
+func TestKeySpaceSetNXLock(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SET", args: []interface{}{"lock", "owner1", "NX", "PX", 30000}, expected: "OK"},
+		{command: "SET", args: []interface{}{"lock", "owner2", "NX", "PX", 30000}, expected: nil},
+		{advance: 30 * time.Second, command: "SET", args: []interface{}{"lock", "owner2", "NX", "PX", 30000}, expected: nil},
+		{advance: time.Millisecond, command: "SET", args: []interface{}{"lock", "owner2", "NX", "PX", 30000}, expected: "OK"},
+		{command: "GET", args: []interface{}{"lock"}, expected: []byte("owner2")},
+	})
+}
+
+func TestKeySpaceGetEx(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "GETEX", args: []interface{}{"key"}, expected: nil},
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "GETEX", args: []interface{}{"key", "EX", 10}, expected: []byte("value")},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(10)},
+		{command: "GETEX", args: []interface{}{"key", "PERSIST"}, expected: []byte("value")},
+		{command: "TTL", args: []interface{}{"key"}, expected: int64(-1)},
+		{command: "GETEX", args: []interface{}{"key", "PX", 0}, err: errInvalidExpire("getex")},
+		{command: "GETEX", args: []interface{}{"key", "EX", 1, "PERSIST"}, err: errSyntax},
+		{command: "RPUSH", args: []interface{}{"list", "a"}, expected: int64(1)},
+		{command: "GETEX", args: []interface{}{"list"}, err: WrongTypeError()},
+	})
+}
+
+func TestKeySpaceExpireUsesPoolClock(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.UseKeySpace(NewKeySpace())
+
+	conn := mockPool.RedisPool().Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", "key", "value", "EX", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	mockPool.Clock().Advance(11 * time.Second)
+
+	if exists, err := redis.Bool(conn.Do("EXISTS", "key")); err != nil || exists {
+		t.Errorf("Expected key to be expired (%v)", err)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func init() {
+	keySpaceCommands["hset"] = keySpaceCommand{min: 3, max: -1, handler: cmdHSet}
+	keySpaceCommands["hmset"] = keySpaceCommand{min: 3, max: -1, handler: cmdHMSet}
+	keySpaceCommands["hsetnx"] = keySpaceCommand{min: 3, max: 3, handler: cmdHSetNX}
+	keySpaceCommands["hget"] = keySpaceCommand{min: 2, max: 2, handler: cmdHGet}
+	keySpaceCommands["hmget"] = keySpaceCommand{min: 2, max: -1, handler: cmdHMGet}
+	keySpaceCommands["hgetall"] = keySpaceCommand{min: 1, max: 1, handler: cmdHGetAll}
+	keySpaceCommands["hdel"] = keySpaceCommand{min: 2, max: -1, handler: cmdHDel}
+	keySpaceCommands["hexists"] = keySpaceCommand{min: 2, max: 2, handler: cmdHExists}
+	keySpaceCommands["hlen"] = keySpaceCommand{min: 1, max: 1, handler: cmdHLen}
+	keySpaceCommands["hkeys"] = keySpaceCommand{min: 1, max: 1, handler: cmdHKeys}
+	keySpaceCommands["hvals"] = keySpaceCommand{min: 1, max: 1, handler: cmdHVals}
+	keySpaceCommands["hincrby"] = keySpaceCommand{min: 3, max: 3, handler: cmdHIncrBy}
+	keySpaceCommands["hincrbyfloat"] = keySpaceCommand{min: 3, max: 3, handler: cmdHIncrByFloat}
+}
+
+// getHash returns the hash stored in the key. When the key doesn't exist and
+// create is true, a new empty hash is stored, otherwise nil is returned
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getHash(key []byte, create bool) (map[string][]byte, error) {
+	e, err := ks.lookup(key, "hash")
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &entry{value: make(map[string][]byte)}
+		ks.keys[string(key)] = e
+	}
+	return e.value.(map[string][]byte), nil
+}
+
+// hashFields returns the hash fields in lexicographical order, so replies are
+// deterministic
+func hashFields(hash map[string][]byte) []string {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func cmdHSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, errWrongArgs("hset")
+	}
+
+	hash, err := ks.getHash(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := hash[string(args[i])]; !ok {
+			added++
+		}
+		hash[string(args[i])] = append([]byte{}, args[i+1]...)
+	}
+	return added, nil
+}
+
+func cmdHMSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, errWrongArgs("hmset")
+	}
+
+	if _, err := cmdHSet(ks, args); err != nil {
+		return nil, err
+	}
+	return "OK", nil
+}
+
+func cmdHSetNX(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := hash[string(args[1])]; ok {
+		return int64(0), nil
+	}
+
+	hash[string(args[1])] = append([]byte{}, args[2]...)
+	return int64(1), nil
+}
+
+func cmdHGet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := hash[string(args[1])]; ok {
+		return value, nil
+	}
+	return nil, nil
+}
+
+func cmdHMGet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, len(args)-1)
+	for i, field := range args[1:] {
+		if value, ok := hash[string(field)]; ok {
+			reply[i] = value
+		}
+	}
+	return reply, nil
+}
+
+func cmdHGetAll(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, 0, len(hash)*2)
+	for _, field := range hashFields(hash) {
+		reply = append(reply, []byte(field), hash[field])
+	}
+	return reply, nil
+}
+
+func cmdHDel(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted int64
+	for _, field := range args[1:] {
+		if _, ok := hash[string(field)]; ok {
+			delete(hash, string(field))
+			deleted++
+		}
+	}
+
+	if hash != nil && len(hash) == 0 {
+		delete(ks.keys, string(args[0]))
+	}
+	return deleted, nil
+}
+
+func cmdHExists(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := hash[string(args[1])]; ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func cmdHLen(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(hash)), nil
+}
+
+func cmdHKeys(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, 0, len(hash))
+	for _, field := range hashFields(hash) {
+		reply = append(reply, []byte(field))
+	}
+	return reply, nil
+}
+
+func cmdHVals(ks *KeySpace, args [][]byte) (interface{}, error) {
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, 0, len(hash))
+	for _, field := range hashFields(hash) {
+		reply = append(reply, hash[field])
+	}
+	return reply, nil
+}
+
+func cmdHIncrBy(ks *KeySpace, args [][]byte) (interface{}, error) {
+	increment, err := parseInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	if value, ok := hash[string(args[1])]; ok {
+		if n, err = parseInt(value); err != nil {
+			return nil, redis.Error("ERR hash value is not an integer")
+		}
+	}
+
+	if (increment > 0 && n > math.MaxInt64-increment) || (increment < 0 && n < math.MinInt64-increment) {
+		return nil, errIncrOverflow
+	}
+
+	n += increment
+	if hash == nil {
+		hash, _ = ks.getHash(args[0], true)
+	}
+	hash[string(args[1])] = formatArg(n)
+	return n, nil
+}
+
+func cmdHIncrByFloat(ks *KeySpace, args [][]byte) (interface{}, error) {
+	increment, err := parseFloat(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := ks.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var f float64
+	value, ok := hash[string(args[1])]
+	if ok {
+		if f, err = parseFloat(value); err != nil {
+			return nil, redis.Error("ERR hash value is not a float")
+		}
+	}
+
+	f += increment
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, errIncrNaN
+	}
+
+	result := formatIncrFloat(value, args[2])
+	if hash == nil {
+		hash, _ = ks.getHash(args[0], true)
+	}
+	hash[string(args[1])] = result
+	return result, nil
+}
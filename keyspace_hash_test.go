@@ -0,0 +1,39 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySpaceHashes(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "HSET", args: []interface{}{"hash", "b", 2, "a", 1}, expected: int64(2)},
+		{command: "HSET", args: []interface{}{"hash", "a", 3}, expected: int64(0)},
+		{command: "HSET", args: []interface{}{"hash", "a"}, err: errWrongArgs("hset")},
+		{command: "HMSET", args: []interface{}{"hash", "c", 4}, expected: "OK"},
+		{command: "HSETNX", args: []interface{}{"hash", "c", 5}, expected: int64(0)},
+		{command: "HGET", args: []interface{}{"hash", "a"}, expected: []byte("3")},
+		{command: "HGET", args: []interface{}{"hash", "z"}, expected: nil},
+		{command: "HMGET", args: []interface{}{"hash", "a", "z"}, expected: []interface{}{[]byte("3"), nil}},
+		{command: "HGETALL", args: []interface{}{"hash"}, expected: bulks("a", "3", "b", "2", "c", "4")},
+		{command: "HGETALL", args: []interface{}{"missing"}, expected: []interface{}{}},
+		{command: "HKEYS", args: []interface{}{"hash"}, expected: bulks("a", "b", "c")},
+		{command: "HVALS", args: []interface{}{"hash"}, expected: bulks("3", "2", "4")},
+		{command: "HLEN", args: []interface{}{"hash"}, expected: int64(3)},
+		{command: "HEXISTS", args: []interface{}{"hash", "a"}, expected: int64(1)},
+		{command: "HINCRBY", args: []interface{}{"hash", "a", 2}, expected: int64(5)},
+		{command: "HINCRBYFLOAT", args: []interface{}{"hash", "a", 0.5}, expected: []byte("5.5")},
+		{command: "HINCRBYFLOAT", args: []interface{}{"decimals", "d", 0.1}, expected: []byte("0.1")},
+		{command: "HINCRBYFLOAT", args: []interface{}{"decimals", "d", 0.2}, expected: []byte("0.3")},
+		{command: "HINCRBY", args: []interface{}{"hash", "a", 1}, err: redis.Error("ERR hash value is not an integer")},
+		{command: "HDEL", args: []interface{}{"hash", "a", "b", "c", "z"}, expected: int64(3)},
+		{command: "EXISTS", args: []interface{}{"hash"}, expected: int64(0)},
+		{command: "HINCRBY", args: []interface{}{"other", "a", "x"}, err: errNotInteger},
+		{command: "EXISTS", args: []interface{}{"other"}, expected: int64(0)},
+	})
+}
@@ -0,0 +1,345 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func init() {
+	keySpaceCommands["lpush"] = keySpaceCommand{min: 2, max: -1, handler: cmdLPush}
+	keySpaceCommands["rpush"] = keySpaceCommand{min: 2, max: -1, handler: cmdRPush}
+	keySpaceCommands["lpushx"] = keySpaceCommand{min: 2, max: -1, handler: cmdLPushX}
+	keySpaceCommands["rpushx"] = keySpaceCommand{min: 2, max: -1, handler: cmdRPushX}
+	keySpaceCommands["lpop"] = keySpaceCommand{min: 1, max: 2, handler: cmdLPop}
+	keySpaceCommands["rpop"] = keySpaceCommand{min: 1, max: 2, handler: cmdRPop}
+	keySpaceCommands["llen"] = keySpaceCommand{min: 1, max: 1, handler: cmdLLen}
+	keySpaceCommands["lrange"] = keySpaceCommand{min: 3, max: 3, handler: cmdLRange}
+	keySpaceCommands["lindex"] = keySpaceCommand{min: 2, max: 2, handler: cmdLIndex}
+	keySpaceCommands["lset"] = keySpaceCommand{min: 3, max: 3, handler: cmdLSet}
+	keySpaceCommands["lrem"] = keySpaceCommand{min: 3, max: 3, handler: cmdLRem}
+	keySpaceCommands["ltrim"] = keySpaceCommand{min: 3, max: 3, handler: cmdLTrim}
+	keySpaceCommands["linsert"] = keySpaceCommand{min: 4, max: 4, handler: cmdLInsert}
+}
+
+// getList returns the list entry stored in the key, or nil if the key doesn't
+// exist
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getList(key []byte) (*entry, error) {
+	return ks.lookup(key, "list")
+}
+
+// setList stores the list in the key entry, removing the key when the list is
+// empty like the Redis server does
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) setList(key []byte, e *entry, list [][]byte) {
+	if len(list) == 0 {
+		delete(ks.keys, string(key))
+		return
+	}
+
+	if e == nil {
+		e = &entry{}
+		ks.keys[string(key)] = e
+	}
+	e.value = list
+}
+
+// push adds the values to the head or tail of the list. If onlyExisting is
+// true, nothing happens when the key doesn't exist
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) push(args [][]byte, head, onlyExisting bool) (interface{}, error) {
+	e, err := ks.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil && onlyExisting {
+		return int64(0), nil
+	}
+
+	var list [][]byte
+	if e != nil {
+		list = e.value.([][]byte)
+	}
+
+	for _, value := range args[1:] {
+		value = append([]byte{}, value...)
+		if head {
+			list = append([][]byte{value}, list...)
+		} else {
+			list = append(list, value)
+		}
+	}
+
+	ks.setList(args[0], e, list)
+	return int64(len(list)), nil
+}
+
+// pop removes values from the head or tail of the list. Without the count
+// argument a single value is returned, otherwise an array
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) pop(args [][]byte, head bool) (interface{}, error) {
+	count := int64(1)
+	if len(args) > 1 {
+		var err error
+		if count, err = parseInt(args[1]); err != nil || count < 0 {
+			return nil, redis.Error("ERR value is out of range, must be positive")
+		}
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	list := e.value.([][]byte)
+	if count > int64(len(list)) {
+		count = int64(len(list))
+	}
+
+	var popped [][]byte
+	if head {
+		popped, list = list[:count], list[count:]
+	} else {
+		popped = make([][]byte, 0, count)
+		for i := len(list) - 1; i >= len(list)-int(count); i-- {
+			popped = append(popped, list[i])
+		}
+		list = list[:len(list)-int(count)]
+	}
+
+	ks.setList(args[0], e, list)
+
+	if len(args) == 1 {
+		return popped[0], nil
+	}
+
+	reply := make([]interface{}, len(popped))
+	for i, value := range popped {
+		reply[i] = value
+	}
+	return reply, nil
+}
+
+func cmdLPush(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.push(args, true, false)
+}
+
+func cmdRPush(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.push(args, false, false)
+}
+
+func cmdLPushX(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.push(args, true, true)
+}
+
+func cmdRPushX(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.push(args, false, true)
+}
+
+func cmdLPop(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.pop(args, true)
+}
+
+func cmdRPop(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.pop(args, false)
+}
+
+func cmdLLen(ks *KeySpace, args [][]byte) (interface{}, error) {
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+	return int64(len(e.value.([][]byte))), nil
+}
+
+func cmdLRange(ks *KeySpace, args [][]byte) (interface{}, error) {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	reply := []interface{}{}
+	if e == nil {
+		return reply, nil
+	}
+
+	list := e.value.([][]byte)
+	from, to, ok := rangeIndexes(start, stop, len(list))
+	if !ok {
+		return reply, nil
+	}
+
+	for _, value := range list[from:to] {
+		reply = append(reply, value)
+	}
+	return reply, nil
+}
+
+func cmdLIndex(ks *KeySpace, args [][]byte) (interface{}, error) {
+	index, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	list := e.value.([][]byte)
+	if index < 0 {
+		index += int64(len(list))
+	}
+	if index < 0 || index >= int64(len(list)) {
+		return nil, nil
+	}
+	return list[index], nil
+}
+
+func cmdLSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	index, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, errNoSuchKey
+	}
+
+	list := e.value.([][]byte)
+	if index < 0 {
+		index += int64(len(list))
+	}
+	if index < 0 || index >= int64(len(list)) {
+		return nil, redis.Error("ERR index out of range")
+	}
+
+	list[index] = append([]byte{}, args[2]...)
+	return "OK", nil
+}
+
+func cmdLRem(ks *KeySpace, args [][]byte) (interface{}, error) {
+	count, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	list := e.value.([][]byte)
+	remove := make(map[int]bool)
+
+	if count >= 0 {
+		for i := 0; i < len(list) && (count == 0 || int64(len(remove)) < count); i++ {
+			if bytes.Equal(list[i], args[2]) {
+				remove[i] = true
+			}
+		}
+	} else {
+		for i := len(list) - 1; i >= 0 && int64(len(remove)) < -count; i-- {
+			if bytes.Equal(list[i], args[2]) {
+				remove[i] = true
+			}
+		}
+	}
+
+	var result [][]byte
+	for i, value := range list {
+		if !remove[i] {
+			result = append(result, value)
+		}
+	}
+
+	ks.setList(args[0], e, result)
+	return int64(len(remove)), nil
+}
+
+func cmdLTrim(ks *KeySpace, args [][]byte) (interface{}, error) {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return "OK", err
+	}
+
+	list := e.value.([][]byte)
+	from, to, ok := rangeIndexes(start, stop, len(list))
+	if !ok {
+		ks.setList(args[0], e, nil)
+		return "OK", nil
+	}
+
+	ks.setList(args[0], e, list[from:to])
+	return "OK", nil
+}
+
+func cmdLInsert(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var before bool
+	switch strings.ToLower(string(args[1])) {
+	case "before":
+		before = true
+	case "after":
+	default:
+		return nil, errSyntax
+	}
+
+	e, err := ks.getList(args[0])
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	list := e.value.([][]byte)
+	for i, value := range list {
+		if !bytes.Equal(value, args[2]) {
+			continue
+		}
+
+		if !before {
+			i++
+		}
+
+		result := make([][]byte, 0, len(list)+1)
+		result = append(result, list[:i]...)
+		result = append(result, append([]byte{}, args[3]...))
+		result = append(result, list[i:]...)
+		ks.setList(args[0], e, result)
+		return int64(len(result)), nil
+	}
+	return int64(-1), nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySpaceLists(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "LPUSHX", args: []interface{}{"list", "a"}, expected: int64(0)},
+		{command: "RPUSH", args: []interface{}{"list", "b", "c"}, expected: int64(2)},
+		{command: "LPUSH", args: []interface{}{"list", "a"}, expected: int64(3)},
+		{command: "RPUSHX", args: []interface{}{"list", "d"}, expected: int64(4)},
+		{command: "LRANGE", args: []interface{}{"list", 0, -1}, expected: bulks("a", "b", "c", "d")},
+		{command: "LRANGE", args: []interface{}{"list", 5, 10}, expected: []interface{}{}},
+		{command: "LLEN", args: []interface{}{"list"}, expected: int64(4)},
+		{command: "LINDEX", args: []interface{}{"list", -1}, expected: []byte("d")},
+		{command: "LINDEX", args: []interface{}{"list", 10}, expected: nil},
+		{command: "LSET", args: []interface{}{"list", 1, "B"}, expected: "OK"},
+		{command: "LSET", args: []interface{}{"list", 10, "B"}, err: redis.Error("ERR index out of range")},
+		{command: "LSET", args: []interface{}{"missing", 0, "B"}, err: errNoSuchKey},
+		{command: "LINSERT", args: []interface{}{"list", "BEFORE", "c", "x"}, expected: int64(5)},
+		{command: "LINSERT", args: []interface{}{"list", "AFTER", "z", "x"}, expected: int64(-1)},
+		{command: "RPUSH", args: []interface{}{"list", "x"}, expected: int64(6)},
+		{command: "LREM", args: []interface{}{"list", -1, "x"}, expected: int64(1)},
+		{command: "LRANGE", args: []interface{}{"list", 0, -1}, expected: bulks("a", "B", "x", "c", "d")},
+		{command: "LTRIM", args: []interface{}{"list", 1, -2}, expected: "OK"},
+		{command: "LRANGE", args: []interface{}{"list", 0, -1}, expected: bulks("B", "x", "c")},
+		{command: "LPOP", args: []interface{}{"list"}, expected: []byte("B")},
+		{command: "RPOP", args: []interface{}{"list", 5}, expected: bulks("c", "x")},
+		{command: "RPOP", args: []interface{}{"list"}, expected: nil},
+		{command: "EXISTS", args: []interface{}{"list"}, expected: int64(0)},
+	})
+}
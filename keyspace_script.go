@@ -0,0 +1,288 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func init() {
+	keySpaceCommands["eval"] = keySpaceCommand{min: 2, max: -1, handler: cmdEval}
+	keySpaceCommands["evalsha"] = keySpaceCommand{min: 2, max: -1, handler: cmdEvalSHA}
+}
+
+// defaultScriptTimeout is how long a script runs before it's stopped, like the
+// busy-reply-threshold of the Redis server
+const defaultScriptTimeout = 5 * time.Second
+
+// scriptCommands are the commands that can't be called from scripts
+var scriptCommands = map[string]bool{
+	"eval":    true,
+	"evalsha": true,
+	"script":  true,
+}
+
+// SetScriptTimeout changes how long a script runs before it's stopped with a
+// BUSY error, 5 seconds by default. The Redis server would keep the script
+// running and refuse the other commands, but as the key space is locked while
+// the script runs it's stopped instead
+func (ks *KeySpace) SetScriptTimeout(d time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.scriptTimeout = d
+}
+
+func cmdEval(ks *KeySpace, args [][]byte) (interface{}, error) {
+	ks.scripts.load(args[0])
+	return ks.eval(string(args[0]), args[1:])
+}
+
+func cmdEvalSHA(ks *KeySpace, args [][]byte) (interface{}, error) {
+	script, ok := ks.scripts.get(string(args[0]))
+	if !ok {
+		return nil, NoScriptError()
+	}
+	return ks.eval(script, args[1:])
+}
+
+// eval runs the Lua script, where args are the number of keys followed by the
+// keys and the other arguments. Commands called by the script are executed
+// against the key space, so the script is atomic like in the Redis server
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) eval(script string, args [][]byte) (interface{}, error) {
+	numKeys, err := parseInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if numKeys < 0 {
+		return nil, redis.Error("ERR Number of keys can't be negative")
+	}
+	if numKeys > int64(len(args)-1) {
+		return nil, redis.Error("ERR Number of keys can't be greater than number of args")
+	}
+
+	L := ks.newLuaState()
+	defer L.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ks.scriptTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	L.SetGlobal("KEYS", luaStrings(L, args[1:numKeys+1]))
+	L.SetGlobal("ARGV", luaStrings(L, args[numKeys+1:]))
+
+	fn, err := L.LoadString(script)
+	if err != nil {
+		return nil, redis.Error("ERR Error compiling script: " + err.Error())
+	}
+
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		if ctx.Err() != nil {
+			return nil, BusyError()
+		}
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			// errors raised by redis.call keep the reply of the command
+			if reply, ok := luaToRedis(apiErr.Object).(redis.Error); ok {
+				return nil, reply
+			}
+			return nil, redis.Error("ERR Error running script: " + apiErr.Object.String())
+		}
+		return nil, redis.Error("ERR Error running script: " + err.Error())
+	}
+
+	reply := luaToRedis(L.Get(-1))
+	if err, ok := reply.(redis.Error); ok {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// newLuaState returns a Lua interpreter with the libraries and the redis
+// module available to scripts in the Redis server
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) newLuaState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	libs := []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	}
+
+	for _, lib := range libs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// scripts can't access the file system
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	module := L.NewTable()
+	L.SetFuncs(module, map[string]lua.LGFunction{
+		"call": func(L *lua.LState) int {
+			return ks.luaCall(L, false)
+		},
+		"pcall": func(L *lua.LState) int {
+			return ks.luaCall(L, true)
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(luaReplyTable(L, "ok", L.CheckString(1)))
+			return 1
+		},
+		"error_reply": func(L *lua.LState) int {
+			L.Push(luaReplyTable(L, "err", L.CheckString(1)))
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(scriptSHA([]byte(L.CheckString(1)))))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			return 0
+		},
+	})
+
+	for i, level := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		module.RawSetString(level, lua.LNumber(i))
+	}
+
+	L.SetGlobal("redis", module)
+	return L
+}
+
+// luaCall implements redis.call and redis.pcall. When protected is true,
+// errors are returned to the script instead of raised
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) luaCall(L *lua.LState, protected bool) int {
+	var err error
+
+	args := make([][]byte, L.GetTop())
+	for i := range args {
+		switch value := L.Get(i + 1).(type) {
+		case lua.LString:
+			args[i] = []byte(value)
+		case lua.LNumber:
+			args[i] = []byte(value.String())
+		default:
+			err = redis.Error("ERR Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	var reply interface{}
+	switch {
+	case err != nil:
+	case len(args) == 0:
+		err = redis.Error("ERR Please specify at least one argument for this redis lib call")
+	case scriptCommands[strings.ToLower(string(args[0]))]:
+		err = redis.Error("ERR This Redis command is not allowed from script")
+	case ks.readOnly && IsWriteCommand(string(args[0])):
+		err = ReadOnlyError()
+	default:
+		reply, err = ks.call(string(args[0]), args[1:])
+	}
+
+	if err != nil {
+		errTable := luaReplyTable(L, "err", err.Error())
+		if !protected {
+			L.Error(errTable, 1)
+		}
+		L.Push(errTable)
+		return 1
+	}
+
+	L.Push(redisToLua(L, reply))
+	return 1
+}
+
+// luaStrings converts the arguments to a Lua array of strings
+func luaStrings(L *lua.LState, args [][]byte) *lua.LTable {
+	table := L.CreateTable(len(args), 0)
+	for _, arg := range args {
+		table.Append(lua.LString(arg))
+	}
+	return table
+}
+
+// luaReplyTable returns a table with a single field, the way Lua scripts
+// represent status (ok) and error (err) replies
+func luaReplyTable(L *lua.LState, field, value string) *lua.LTable {
+	table := L.NewTable()
+	table.RawSetString(field, lua.LString(value))
+	return table
+}
+
+// redisToLua converts a command reply to a Lua value, following the Redis
+// server conversion rules
+func redisToLua(L *lua.LState, reply interface{}) lua.LValue {
+	switch value := reply.(type) {
+	case int64:
+		return lua.LNumber(value)
+	case []byte:
+		return lua.LString(value)
+	case string:
+		return luaReplyTable(L, "ok", value)
+	case redis.Error:
+		return luaReplyTable(L, "err", value.Error())
+	case []interface{}:
+		table := L.CreateTable(len(value), 0)
+		for _, item := range value {
+			table.Append(redisToLua(L, item))
+		}
+		return table
+	}
+	return lua.LFalse
+}
+
+// luaToRedis converts a Lua value to a command reply, following the Redis
+// server conversion rules. Error replies are returned as redis.Error values
+func luaToRedis(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case lua.LString:
+		return []byte(v)
+	case lua.LNumber:
+		return int64(v)
+	case lua.LBool:
+		if v {
+			return int64(1)
+		}
+		return nil
+	case *lua.LTable:
+		if err, ok := v.RawGetString("err").(lua.LString); ok {
+			return redis.Error(err)
+		}
+		if status, ok := v.RawGetString("ok").(lua.LString); ok {
+			return string(status)
+		}
+
+		// arrays end at the first nil value
+		reply := []interface{}{}
+		for i := 1; ; i++ {
+			item := v.RawGetInt(i)
+			if item == lua.LNil {
+				break
+			}
+			reply = append(reply, luaToRedis(item))
+		}
+		return reply
+	}
+	return nil
+}
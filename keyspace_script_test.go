@@ -0,0 +1,142 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySpaceEval(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "EVAL", args: []interface{}{"return 1", 0}, expected: int64(1)},
+		{command: "EVAL", args: []interface{}{"return 3.9", 0}, expected: int64(3)},
+		{command: "EVAL", args: []interface{}{"return 'text'", 0}, expected: []byte("text")},
+		{command: "EVAL", args: []interface{}{"return true", 0}, expected: int64(1)},
+		{command: "EVAL", args: []interface{}{"return false", 0}, expected: nil},
+		{command: "EVAL", args: []interface{}{"return nil", 0}, expected: nil},
+		{command: "EVAL", args: []interface{}{"return {1, 'a', {2}, nil, 3}", 0}, expected: []interface{}{int64(1), []byte("a"), []interface{}{int64(2)}}},
+		{command: "EVAL", args: []interface{}{"return redis.status_reply('DONE')", 0}, expected: "DONE"},
+		{command: "EVAL", args: []interface{}{"return redis.error_reply('ERR failed')", 0}, err: redis.Error("ERR failed")},
+		{command: "EVAL", args: []interface{}{"return {KEYS[1], ARGV[1], #KEYS, #ARGV}", 1, "key", "arg"}, expected: []interface{}{[]byte("key"), []byte("arg"), int64(1), int64(1)}},
+		{command: "EVAL", args: []interface{}{"return redis.sha1hex('')", 0}, expected: []byte("da39a3ee5e6b4b0d3255bfef95601890afd80709")},
+		{command: "EVAL", args: []interface{}{"return 1", 2, "key"}, err: redis.Error("ERR Number of keys can't be greater than number of args")},
+		{command: "EVAL", args: []interface{}{"return 1", -1}, err: redis.Error("ERR Number of keys can't be negative")},
+		{command: "EVAL", args: []interface{}{"return 1", "x"}, err: errNotInteger},
+	})
+}
+
+func TestKeySpaceEvalCompileError(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	_, err := conn.Do("EVAL", "return (", 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "ERR Error compiling script: ") {
+		t.Errorf("Expected compile error and got '%v'", err)
+	}
+}
+
+func TestKeySpaceEvalTimeout(t *testing.T) {
+	ks := NewKeySpace()
+	ks.SetScriptTimeout(10 * time.Millisecond)
+
+	conn := NewConn()
+	conn.UseKeySpace(ks)
+
+	_, err := conn.Do("EVAL", "redis.call('SET', 'k', 'v') while true do end", 0)
+	if err != BusyError() {
+		t.Errorf("Expected busy error and got '%v'", err)
+	}
+
+	// the key space isn't kept locked by the stopped script
+	if value, err := redis.String(conn.Do("GET", "k")); err != nil || value != "v" {
+		t.Errorf("Expected 'v' and got '%s' (%v)", value, err)
+	}
+}
+
+func TestKeySpaceEvalCommands(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "EVAL", args: []interface{}{"return redis.call('SET', KEYS[1], ARGV[1])", 1, "key", 10}, expected: "OK"},
+		{command: "EVAL", args: []interface{}{"return redis.call('INCRBY', KEYS[1], 5)", 1, "key"}, expected: int64(15)},
+		{command: "EVAL", args: []interface{}{"return redis.call('GET', 'missing') == false", 0}, expected: int64(1)},
+		{command: "EVAL", args: []interface{}{"redis.call('RPUSH', 'list', 'a', 'b'); return redis.call('LRANGE', 'list', 0, -1)", 0}, expected: bulks("a", "b")},
+		{command: "EVAL", args: []interface{}{"return redis.call('HGET', 'key', 'field')", 0}, err: WrongTypeError()},
+		{command: "EVAL", args: []interface{}{"local r = redis.pcall('HGET', 'key', 'field'); return r.err", 0}, expected: []byte(WrongTypeError().Error())},
+		{command: "EVAL", args: []interface{}{"local ok, r = pcall(redis.call, 'HGET', 'key', 'field'); return r", 0}, err: WrongTypeError()},
+		{command: "EVAL", args: []interface{}{"return redis.call('EVAL', 'return 1', 0)", 0}, err: redis.Error("ERR This Redis command is not allowed from script")},
+		{command: "EVAL", args: []interface{}{"return redis.call('GET', {})", 0}, err: redis.Error("ERR Lua redis lib command arguments must be strings or integers")},
+		{command: "EVAL", args: []interface{}{"return redis.call()", 0}, err: redis.Error("ERR Please specify at least one argument for this redis lib call")},
+		{command: "EVAL", args: []interface{}{"return redis.call('UNKNOWN')", 0}, err: redis.Error("ERR unknown command 'UNKNOWN'")},
+		{command: "EVAL", args: []interface{}{"error('boom')", 0}, err: redis.Error("ERR Error running script: <string>:1: boom")},
+	})
+}
+
+func TestKeySpaceEvalSHA(t *testing.T) {
+	script := "return ARGV[1]"
+	sha := scriptSHA([]byte(script))
+
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "EVALSHA", args: []interface{}{sha, 0, "a"}, err: NoScriptError()},
+		{command: "EVAL", args: []interface{}{script, 0, "a"}, expected: []byte("a")},
+		{command: "EVALSHA", args: []interface{}{sha, 0, "b"}, expected: []byte("b")},
+	})
+}
+
+func TestKeySpaceEvalRateLimiter(t *testing.T) {
+	// fixed window rate limiter, allowing 2 requests per second
+	script := redis.NewScript(1, `
+		local current = redis.call('INCR', KEYS[1])
+		if current == 1 then
+			redis.call('PEXPIRE', KEYS[1], ARGV[1])
+		end
+		if current > tonumber(ARGV[2]) then
+			return 0
+		end
+		return 1
+	`)
+
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	expected := []int{1, 1, 0}
+	for i, allowed := range expected {
+		if value, err := redis.Int(script.Do(conn, "limiter", 1000, 2)); err != nil || value != allowed {
+			t.Errorf("Request %d: expected %d and got %d (%v)", i, allowed, value, err)
+		}
+	}
+
+	conn.Clock().Advance(1001 * time.Millisecond)
+
+	if value, err := redis.Int(script.Do(conn, "limiter", 1000, 2)); err != nil || value != 1 {
+		t.Errorf("Expected request to be allowed and got %d (%v)", value, err)
+	}
+}
+
+func TestKeySpaceEvalLockRelease(t *testing.T) {
+	script := redis.NewScript(1, `
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			return redis.call('DEL', KEYS[1])
+		end
+		return 0
+	`)
+
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	if _, err := conn.Do("SET", "lock", "owner1", "NX", "PX", 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if released, err := redis.Int(script.Do(conn, "lock", "owner2")); err != nil || released != 0 {
+		t.Errorf("Expected lock not to be released and got %d (%v)", released, err)
+	}
+
+	if released, err := redis.Int(script.Do(conn, "lock", "owner1")); err != nil || released != 1 {
+		t.Errorf("Expected lock to be released and got %d (%v)", released, err)
+	}
+}
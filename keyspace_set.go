@@ -0,0 +1,267 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+func init() {
+	keySpaceCommands["sadd"] = keySpaceCommand{min: 2, max: -1, handler: cmdSAdd}
+	keySpaceCommands["srem"] = keySpaceCommand{min: 2, max: -1, handler: cmdSRem}
+	keySpaceCommands["smembers"] = keySpaceCommand{min: 1, max: 1, handler: cmdSMembers}
+	keySpaceCommands["sismember"] = keySpaceCommand{min: 2, max: 2, handler: cmdSIsMember}
+	keySpaceCommands["smismember"] = keySpaceCommand{min: 2, max: -1, handler: cmdSMIsMember}
+	keySpaceCommands["scard"] = keySpaceCommand{min: 1, max: 1, handler: cmdSCard}
+	keySpaceCommands["spop"] = keySpaceCommand{min: 1, max: 2, handler: cmdSPop}
+	keySpaceCommands["smove"] = keySpaceCommand{min: 3, max: 3, handler: cmdSMove}
+	keySpaceCommands["sinter"] = keySpaceCommand{min: 1, max: -1, handler: cmdSInter}
+	keySpaceCommands["sunion"] = keySpaceCommand{min: 1, max: -1, handler: cmdSUnion}
+	keySpaceCommands["sdiff"] = keySpaceCommand{min: 1, max: -1, handler: cmdSDiff}
+}
+
+// getSet returns the set stored in the key. When the key doesn't exist and
+// create is true, a new empty set is stored, otherwise nil is returned
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getSet(key []byte, create bool) (map[string]struct{}, error) {
+	e, err := ks.lookup(key, "set")
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &entry{value: make(map[string]struct{})}
+		ks.keys[string(key)] = e
+	}
+	return e.value.(map[string]struct{}), nil
+}
+
+// getSets returns the sets stored in the keys, missing keys are returned as
+// empty sets
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getSets(keys [][]byte) ([]map[string]struct{}, error) {
+	sets := make([]map[string]struct{}, len(keys))
+	for i, key := range keys {
+		set, err := ks.getSet(key, false)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+// membersReply converts the set members to an array reply, in
+// lexicographical order so replies are deterministic
+func membersReply(set map[string]struct{}) []interface{} {
+	reply := make([]interface{}, 0, len(set))
+	for _, member := range sortedKeys(set) {
+		reply = append(reply, []byte(member))
+	}
+	return reply
+}
+
+func cmdSAdd(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	var added int64
+	for _, member := range args[1:] {
+		if _, ok := set[string(member)]; !ok {
+			set[string(member)] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+func cmdSRem(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed int64
+	for _, member := range args[1:] {
+		if _, ok := set[string(member)]; ok {
+			delete(set, string(member))
+			removed++
+		}
+	}
+
+	if set != nil && len(set) == 0 {
+		delete(ks.keys, string(args[0]))
+	}
+	return removed, nil
+}
+
+func cmdSMembers(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return membersReply(set), nil
+}
+
+func cmdSIsMember(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := set[string(args[1])]; ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func cmdSMIsMember(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]interface{}, len(args)-1)
+	for i, member := range args[1:] {
+		reply[i] = int64(0)
+		if _, ok := set[string(member)]; ok {
+			reply[i] = int64(1)
+		}
+	}
+	return reply, nil
+}
+
+func cmdSCard(ks *KeySpace, args [][]byte) (interface{}, error) {
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(set)), nil
+}
+
+// cmdSPop removes members from the set. To keep tests deterministic, members
+// are removed in lexicographical order instead of randomly
+func cmdSPop(ks *KeySpace, args [][]byte) (interface{}, error) {
+	count := int64(1)
+	if len(args) > 1 {
+		var err error
+		if count, err = parseInt(args[1]); err != nil || count < 0 {
+			return nil, errNotInteger
+		}
+	}
+
+	set, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		if len(args) > 1 {
+			return []interface{}{}, nil
+		}
+		return nil, nil
+	}
+
+	members := sortedKeys(set)
+	if count > int64(len(members)) {
+		count = int64(len(members))
+	}
+
+	reply := make([]interface{}, 0, count)
+	for _, member := range members[:count] {
+		delete(set, member)
+		reply = append(reply, []byte(member))
+	}
+
+	if len(set) == 0 {
+		delete(ks.keys, string(args[0]))
+	}
+
+	if len(args) == 1 {
+		return reply[0], nil
+	}
+	return reply, nil
+}
+
+func cmdSMove(ks *KeySpace, args [][]byte) (interface{}, error) {
+	source, err := ks.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ks.getSet(args[1], false); err != nil {
+		return nil, err
+	}
+
+	if _, ok := source[string(args[2])]; !ok {
+		return int64(0), nil
+	}
+
+	delete(source, string(args[2]))
+	if len(source) == 0 {
+		delete(ks.keys, string(args[0]))
+	}
+
+	destination, _ := ks.getSet(args[1], true)
+	destination[string(args[2])] = struct{}{}
+	return int64(1), nil
+}
+
+func cmdSInter(ks *KeySpace, args [][]byte) (interface{}, error) {
+	sets, err := ks.getSets(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]struct{})
+	for member := range sets[0] {
+		found := true
+		for _, set := range sets[1:] {
+			if _, ok := set[member]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			result[member] = struct{}{}
+		}
+	}
+	return membersReply(result), nil
+}
+
+func cmdSUnion(ks *KeySpace, args [][]byte) (interface{}, error) {
+	sets, err := ks.getSets(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]struct{})
+	for _, set := range sets {
+		for member := range set {
+			result[member] = struct{}{}
+		}
+	}
+	return membersReply(result), nil
+}
+
+func cmdSDiff(ks *KeySpace, args [][]byte) (interface{}, error) {
+	sets, err := ks.getSets(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]struct{})
+	for member := range sets[0] {
+		result[member] = struct{}{}
+	}
+	for _, set := range sets[1:] {
+		for member := range set {
+			delete(result, member)
+		}
+	}
+	return membersReply(result), nil
+}
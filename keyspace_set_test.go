@@ -0,0 +1,32 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+)
+
+func TestKeySpaceSets(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SADD", args: []interface{}{"s1", "c", "a", "b", "a"}, expected: int64(3)},
+		{command: "SADD", args: []interface{}{"s2", "b", "d"}, expected: int64(2)},
+		{command: "SMEMBERS", args: []interface{}{"s1"}, expected: bulks("a", "b", "c")},
+		{command: "SMEMBERS", args: []interface{}{"missing"}, expected: []interface{}{}},
+		{command: "SISMEMBER", args: []interface{}{"s1", "a"}, expected: int64(1)},
+		{command: "SMISMEMBER", args: []interface{}{"s1", "a", "z"}, expected: []interface{}{int64(1), int64(0)}},
+		{command: "SCARD", args: []interface{}{"s1"}, expected: int64(3)},
+		{command: "SINTER", args: []interface{}{"s1", "s2"}, expected: bulks("b")},
+		{command: "SUNION", args: []interface{}{"s1", "s2"}, expected: bulks("a", "b", "c", "d")},
+		{command: "SDIFF", args: []interface{}{"s1", "s2"}, expected: bulks("a", "c")},
+		{command: "SMOVE", args: []interface{}{"s1", "s2", "a"}, expected: int64(1)},
+		{command: "SMOVE", args: []interface{}{"s1", "s2", "z"}, expected: int64(0)},
+		{command: "SREM", args: []interface{}{"s2", "a", "z"}, expected: int64(1)},
+		{command: "SPOP", args: []interface{}{"s1"}, expected: []byte("b")},
+		{command: "SPOP", args: []interface{}{"s1", 5}, expected: bulks("c")},
+		{command: "SPOP", args: []interface{}{"s1"}, expected: nil},
+		{command: "SPOP", args: []interface{}{"s1", 1}, expected: []interface{}{}},
+		{command: "EXISTS", args: []interface{}{"s1"}, expected: int64(0)},
+	})
+}
@@ -0,0 +1,374 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Key is the state of a key in the key space, used to seed it and to take
+// snapshots of it. In snapshots the value is a string for strings,
+// map[string]string for hashes, []string for lists, sorted []string for sets
+// and map[string]float64 for sorted sets. When seeding, the type can be
+// omitted for strings, hashes and lists, as it is inferred from the value
+type Key struct {
+	Type  string        // Data type: string, hash, list, set or zset
+	Value interface{}   // Value of the key, depending on the type
+	TTL   time.Duration // Remaining time to live, zero if the key doesn't expire
+}
+
+// Snapshot is the state of a key space, by key name
+type Snapshot map[string]Key
+
+// Seed stores the keys in the connection key space, replacing existing ones.
+// If the connection doesn't use a key space, a new one is created. Times to
+// live are relative to the connection clock
+func (c *Conn) Seed(keys map[string]Key) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ks := c.currentKeySpace()
+	if ks == nil {
+		ks = NewKeySpace()
+		c.keySpace = ks
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.now = c.currentClock().Now()
+	return ks.seed(keys)
+}
+
+// LoadFixture seeds the connection key space with the keys stored in a YAML or
+// JSON file, selected by the file extension. Each key maps to its value, or to
+// an object with the type, value and ttl fields:
+//
+//	greeting: hello
+//	session:
+//	  type: set
+//	  value: [a, b]
+//	  ttl: 30s
+func (c *Conn) LoadFixture(path string) error {
+	var data map[string]interface{}
+	if err := readDataFile(path, &data); err != nil {
+		return fmt.Errorf("invalid fixture %s: %s", path, err)
+	}
+
+	keys := make(map[string]Key, len(data))
+	for name, value := range data {
+		key, err := fixtureKey(value)
+		if err != nil {
+			return fmt.Errorf("invalid fixture %s: key '%s': %s", path, name, err)
+		}
+		keys[name] = key
+	}
+	return c.Seed(keys)
+}
+
+// Snapshot returns the current state of the connection key space, or nil if
+// the connection doesn't use one. Times to live are relative to the connection
+// clock
+func (c *Conn) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ks := c.currentKeySpace()
+	if ks == nil {
+		return nil
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.now = c.currentClock().Now()
+	return ks.snapshot()
+}
+
+// Diff compares the expected snapshot with the actual one, returning a report
+// with one line per different key, or an empty string if they are equal
+func (s Snapshot) Diff(actual Snapshot) string {
+	names := make(map[string]struct{})
+	for name := range s {
+		names[name] = struct{}{}
+	}
+	for name := range actual {
+		names[name] = struct{}{}
+	}
+
+	var report []string
+	for _, name := range sortedKeys(names) {
+		expectedKey, expectedOK := s[name]
+		actualKey, actualOK := actual[name]
+
+		switch {
+		case !actualOK:
+			report = append(report, fmt.Sprintf("- %s: expected %s and got no key", name, expectedKey))
+		case !expectedOK:
+			report = append(report, fmt.Sprintf("+ %s: expected no key and got %s", name, actualKey))
+		case !reflect.DeepEqual(expectedKey, actualKey):
+			report = append(report, fmt.Sprintf("~ %s: expected %s and got %s", name, expectedKey, actualKey))
+		}
+	}
+	return strings.Join(report, "\n")
+}
+
+// String describes the key with its type, value and time to live
+func (k Key) String() string {
+	format := "%s %q"
+	if k.Type == "zset" {
+		format = "%s %v"
+	}
+
+	description := fmt.Sprintf(format, k.Type, k.Value)
+	if k.TTL > 0 {
+		description += fmt.Sprintf(" (ttl %s)", k.TTL)
+	}
+	return description
+}
+
+// seed stores the keys, replacing existing ones. Keys with empty values are
+// removed, as the Redis server doesn't store empty hashes, lists or sets
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) seed(keys map[string]Key) error {
+	entries := make(map[string]*entry, len(keys))
+	for name, key := range keys {
+		value, err := entryValue(key)
+		if err != nil {
+			return fmt.Errorf("key '%s': %s", name, err)
+		}
+
+		e := &entry{value: value}
+		if key.TTL > 0 {
+			e.expireAt = ks.now.Add(key.TTL)
+		}
+		entries[name] = e
+	}
+
+	for name, e := range entries {
+		if reflect.ValueOf(e.value).Len() == 0 && entryType(e) != "string" {
+			delete(ks.keys, name)
+			continue
+		}
+		ks.keys[name] = e
+	}
+	return nil
+}
+
+// snapshot returns the state of all keys that aren't expired
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) snapshot() Snapshot {
+	s := make(Snapshot)
+	for name := range ks.keys {
+		e := ks.get([]byte(name))
+		if e == nil {
+			continue
+		}
+
+		key := Key{Type: entryType(e)}
+		if !e.expireAt.IsZero() {
+			key.TTL = e.expireAt.Sub(ks.now)
+		}
+
+		switch value := e.value.(type) {
+		case []byte:
+			key.Value = string(value)
+		case map[string][]byte:
+			hash := make(map[string]string, len(value))
+			for field, v := range value {
+				hash[field] = string(v)
+			}
+			key.Value = hash
+		case [][]byte:
+			list := make([]string, len(value))
+			for i, v := range value {
+				list[i] = string(v)
+			}
+			key.Value = list
+		case map[string]struct{}:
+			key.Value = sortedKeys(value)
+		case map[string]float64:
+			zset := make(map[string]float64, len(value))
+			for member, score := range value {
+				zset[member] = score
+			}
+			key.Value = zset
+		}
+		s[name] = key
+	}
+	return s
+}
+
+// entryValue converts the value of a seeded key to the value stored in the
+// key space
+func entryValue(key Key) (interface{}, error) {
+	kind := key.Type
+	if kind == "" {
+		kind = inferType(key.Value)
+	}
+
+	switch kind {
+	case "string":
+		switch key.Value.(type) {
+		case []interface{}, []string, map[string]interface{}, map[string]string:
+			return nil, fmt.Errorf("invalid string value %v", key.Value)
+		}
+		return append([]byte{}, formatArg(key.Value)...), nil
+
+	case "hash":
+		fields, err := seedMap(key.Value)
+		if err != nil {
+			return nil, err
+		}
+		hash := make(map[string][]byte, len(fields))
+		for field, value := range fields {
+			hash[field] = append([]byte{}, formatArg(value)...)
+		}
+		return hash, nil
+
+	case "list", "set":
+		values, err := seedSlice(key.Value)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "set" {
+			set := make(map[string]struct{}, len(values))
+			for _, value := range values {
+				set[value] = struct{}{}
+			}
+			return set, nil
+		}
+		list := make([][]byte, len(values))
+		for i, value := range values {
+			list[i] = []byte(value)
+		}
+		return list, nil
+
+	case "zset":
+		members, err := seedMap(key.Value)
+		if err != nil {
+			return nil, err
+		}
+		zset := make(map[string]float64, len(members))
+		for member, score := range members {
+			f, err := parseFloat(formatArg(score))
+			if err != nil {
+				return nil, fmt.Errorf("invalid score %v for member '%s'", score, member)
+			}
+			zset[member] = f
+		}
+		return zset, nil
+	}
+	return nil, fmt.Errorf("unknown type '%s'", kind)
+}
+
+// inferType returns the data type of a seeded value without an explicit type
+func inferType(value interface{}) string {
+	switch value.(type) {
+	case map[string]float64:
+		return "zset"
+	case []byte:
+		return "string"
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map:
+		return "hash"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	}
+	return "string"
+}
+
+// seedMap converts a map with string keys, like the ones decoded from
+// fixtures, to a map of interface values
+func seedMap(value interface{}) (map[string]interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expected a map and got %T", value)
+	}
+
+	m := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, nil
+}
+
+// seedSlice converts a slice, like the ones decoded from fixtures, to a slice
+// of strings
+func seedSlice(value interface{}) ([]string, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a slice and got %T", value)
+	}
+
+	values := make([]string, v.Len())
+	for i := range values {
+		values[i] = string(formatArg(v.Index(i).Interface()))
+	}
+	return values, nil
+}
+
+// fixtureKey converts a key decoded from a fixture file. Maps with only the
+// type, value and ttl fields describe the key explicitly, any other value is
+// stored with the inferred type
+func fixtureKey(value interface{}) (Key, error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok || fields["type"] == nil || !onlyFields(fields, "type", "value", "ttl") {
+		return Key{Value: value}, nil
+	}
+
+	key := Key{Value: fields["value"]}
+	if key.Type, ok = fields["type"].(string); !ok {
+		return key, fmt.Errorf("invalid type %v", fields["type"])
+	}
+
+	switch ttl := fields["ttl"].(type) {
+	case nil:
+	case string:
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return key, fmt.Errorf("invalid ttl '%s'", ttl)
+		}
+		key.TTL = d
+	case int:
+		key.TTL = time.Duration(ttl) * time.Second
+	case float64:
+		key.TTL = time.Duration(ttl * float64(time.Second))
+	default:
+		return key, fmt.Errorf("invalid ttl %v", ttl)
+	}
+	return key, nil
+}
+
+// onlyFields checks if the map doesn't have fields other than the given ones
+func onlyFields(m map[string]interface{}, fields ...string) bool {
+	allowed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		allowed[field] = true
+	}
+
+	for field := range m {
+		if !allowed[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// flush removes all keys
+func (ks *KeySpace) flush() {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys = make(map[string]*entry)
+}
@@ -0,0 +1,140 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// fixtureSnapshot is the state of the key space after loading the test
+// fixtures
+var fixtureSnapshot = Snapshot{
+	"greeting": {Type: "string", Value: "hello"},
+	"counter":  {Type: "string", Value: "10"},
+	"user:1":   {Type: "hash", Value: map[string]string{"name": "alice", "age": "30"}},
+	"queue":    {Type: "list", Value: []string{"a", "b", "c"}},
+	"tags":     {Type: "set", Value: []string{"a", "b"}},
+	"scores":   {Type: "zset", Value: map[string]float64{"alice": 1.5, "bob": 2}},
+	"session":  {Type: "string", Value: "token", TTL: 30 * time.Second},
+}
+
+func TestSeed(t *testing.T) {
+	conn := NewConn()
+	conn.SetClock(NewClock(keySpaceStart))
+
+	err := conn.Seed(map[string]Key{
+		"greeting": {Value: "hello"},
+		"counter":  {Value: 10},
+		"user:1":   {Value: map[string]interface{}{"name": "alice", "age": 30}},
+		"queue":    {Value: []string{"a", "b", "c"}},
+		"tags":     {Type: "set", Value: []string{"b", "a", "a"}},
+		"scores":   {Value: map[string]float64{"alice": 1.5, "bob": 2}},
+		"session":  {Value: "token", TTL: 30 * time.Second},
+		"empty":    {Value: []string{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := fixtureSnapshot.Diff(conn.Snapshot()); diff != "" {
+		t.Errorf("Unexpected key space state:\n%s", diff)
+	}
+
+	if value, err := redis.Int(conn.Do("INCR", "counter")); err != nil || value != 11 {
+		t.Errorf("Unexpected reply %d (%v)", value, err)
+	}
+
+	conn.Clock().Advance(31 * time.Second)
+	if _, ok := conn.Snapshot()["session"]; ok {
+		t.Error("Expected session key to be expired")
+	}
+}
+
+func TestSeedErrors(t *testing.T) {
+	data := []map[string]Key{
+		{"key": {Type: "unknown", Value: "value"}},
+		{"key": {Type: "hash", Value: []string{"a"}}},
+		{"key": {Type: "list", Value: "value"}},
+		{"key": {Type: "zset", Value: map[string]string{"a": "x"}}},
+		{"key": {Type: "string", Value: []string{"a"}}},
+	}
+
+	for i, keys := range data {
+		conn := NewConn()
+		if err := conn.Seed(keys); err == nil {
+			t.Errorf("Item %d: expected error", i)
+		}
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	for _, path := range []string{"testdata/fixture.yaml", "testdata/fixture.json"} {
+		conn := NewConn()
+		if err := conn.LoadFixture(path); err != nil {
+			t.Errorf("Fixture %s: %s", path, err)
+			continue
+		}
+
+		if diff := fixtureSnapshot.Diff(conn.Snapshot()); diff != "" {
+			t.Errorf("Fixture %s: unexpected key space state:\n%s", path, diff)
+		}
+	}
+
+	if err := NewConn().LoadFixture("testdata/missing.yaml"); err == nil {
+		t.Error("Expected error for a missing fixture")
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	expected := Snapshot{
+		"a": {Type: "string", Value: "1"},
+		"b": {Type: "list", Value: []string{"x"}},
+		"c": {Type: "string", Value: "3", TTL: time.Second},
+	}
+
+	actual := Snapshot{
+		"b": {Type: "list", Value: []string{"y"}},
+		"c": {Type: "string", Value: "3", TTL: time.Second},
+		"d": {Type: "zset", Value: map[string]float64{"m": 1}},
+	}
+
+	report := strings.Join([]string{
+		`- a: expected string "1" and got no key`,
+		`~ b: expected list ["x"] and got list ["y"]`,
+		`+ d: expected no key and got zset map[m:1]`,
+	}, "\n")
+
+	if diff := expected.Diff(actual); diff != report {
+		t.Errorf("Expected report:\n%s\nand got:\n%s", report, diff)
+	}
+
+	if diff := expected.Diff(expected); diff != "" {
+		t.Errorf("Expected no differences and got:\n%s", diff)
+	}
+}
+
+func TestSnapshotWithoutKeySpace(t *testing.T) {
+	if snapshot := NewConn().Snapshot(); snapshot != nil {
+		t.Errorf("Expected no snapshot and got %v", snapshot)
+	}
+}
+
+func TestClearFlushesKeySpace(t *testing.T) {
+	conn := NewConn()
+	if err := conn.Seed(map[string]Key{"key": {Value: "value"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Clear()
+
+	if snapshot := conn.Snapshot(); !reflect.DeepEqual(snapshot, Snapshot{}) {
+		t.Errorf("Expected empty key space and got %v", snapshot)
+	}
+}
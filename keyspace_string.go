@@ -0,0 +1,371 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+func init() {
+	keySpaceCommands["get"] = keySpaceCommand{min: 1, max: 1, handler: cmdGet}
+	keySpaceCommands["set"] = keySpaceCommand{min: 2, max: -1, handler: cmdSet}
+	keySpaceCommands["setnx"] = keySpaceCommand{min: 2, max: 2, handler: cmdSetNX}
+	keySpaceCommands["getset"] = keySpaceCommand{min: 2, max: 2, handler: cmdGetSet}
+	keySpaceCommands["getdel"] = keySpaceCommand{min: 1, max: 1, handler: cmdGetDel}
+	keySpaceCommands["getex"] = keySpaceCommand{min: 1, max: -1, handler: cmdGetEx}
+	keySpaceCommands["setex"] = keySpaceCommand{min: 3, max: 3, handler: cmdSetEx}
+	keySpaceCommands["psetex"] = keySpaceCommand{min: 3, max: 3, handler: cmdPSetEx}
+	keySpaceCommands["mget"] = keySpaceCommand{min: 1, max: -1, handler: cmdMGet}
+	keySpaceCommands["mset"] = keySpaceCommand{min: 2, max: -1, handler: cmdMSet}
+	keySpaceCommands["msetnx"] = keySpaceCommand{min: 2, max: -1, handler: cmdMSetNX}
+	keySpaceCommands["incr"] = keySpaceCommand{min: 1, max: 1, handler: cmdIncr}
+	keySpaceCommands["incrby"] = keySpaceCommand{min: 2, max: 2, handler: cmdIncrBy}
+	keySpaceCommands["decr"] = keySpaceCommand{min: 1, max: 1, handler: cmdDecr}
+	keySpaceCommands["decrby"] = keySpaceCommand{min: 2, max: 2, handler: cmdDecrBy}
+	keySpaceCommands["incrbyfloat"] = keySpaceCommand{min: 2, max: 2, handler: cmdIncrByFloat}
+	keySpaceCommands["append"] = keySpaceCommand{min: 2, max: 2, handler: cmdAppend}
+	keySpaceCommands["strlen"] = keySpaceCommand{min: 1, max: 1, handler: cmdStrLen}
+}
+
+// getString returns the string stored in the key, or nil if the key doesn't
+// exist
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getString(key []byte) ([]byte, error) {
+	e, err := ks.lookup(key, "string")
+	if err != nil || e == nil {
+		return nil, err
+	}
+	return e.value.([]byte), nil
+}
+
+// setString stores the string in the key, replacing any existing value and
+// its time to live
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) setString(key, value []byte) {
+	ks.keys[string(key)] = &entry{value: append([]byte{}, value...)}
+}
+
+// updateString changes the string stored in the key, keeping its time to
+// live like the commands that modify a value in place (INCR, APPEND, ...)
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) updateString(key, value []byte) {
+	if e := ks.get(key); e != nil {
+		e.value = append([]byte{}, value...)
+		return
+	}
+	ks.setString(key, value)
+}
+
+// incrBy increments the integer stored in the key
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) incrBy(key []byte, increment int64) (interface{}, error) {
+	value, err := ks.getString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	if value != nil {
+		if n, err = parseInt(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if (increment > 0 && n > math.MaxInt64-increment) || (increment < 0 && n < math.MinInt64-increment) {
+		return nil, errIncrOverflow
+	}
+
+	n += increment
+	ks.updateString(key, formatArg(n))
+	return n, nil
+}
+
+func cmdGet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	value, err := ks.getString(args[0])
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func cmdSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var nx, xx, get, keepTTL bool
+	var expireAt time.Time
+
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToLower(string(args[i])); option {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "get":
+			get = true
+		case "keepttl":
+			keepTTL = true
+		case "ex", "px", "exat", "pxat":
+			if !expireAt.IsZero() || i+1 >= len(args) {
+				return nil, errSyntax
+			}
+
+			var err error
+			if expireAt, err = ks.expiryTime("set", option, args[i+1], true); err != nil {
+				return nil, err
+			}
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	if (nx && xx) || (keepTTL && !expireAt.IsZero()) {
+		return nil, errSyntax
+	}
+
+	var old []byte
+	if get {
+		var err error
+		if old, err = ks.getString(args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	current := ks.get(args[0])
+	if (nx && current != nil) || (xx && current == nil) {
+		if get && old != nil {
+			return old, nil
+		}
+		return nil, nil
+	}
+
+	ks.setString(args[0], args[1])
+
+	if keepTTL && current != nil {
+		ks.keys[string(args[0])].expireAt = current.expireAt
+	} else if !expireAt.IsZero() {
+		ks.expire(args[0], expireAt)
+	}
+
+	if get {
+		if old == nil {
+			return nil, nil
+		}
+		return old, nil
+	}
+	return "OK", nil
+}
+
+// setEx implements the SETEX and PSETEX commands, where option is the SET
+// option with the same expire unit
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) setEx(name, option string, args [][]byte) (interface{}, error) {
+	expireAt, err := ks.expiryTime(name, option, args[1], true)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.setString(args[0], args[2])
+	ks.expire(args[0], expireAt)
+	return "OK", nil
+}
+
+func cmdSetEx(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.setEx("setex", "ex", args)
+}
+
+func cmdPSetEx(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.setEx("psetex", "px", args)
+}
+
+func cmdSetNX(ks *KeySpace, args [][]byte) (interface{}, error) {
+	if ks.get(args[0]) != nil {
+		return int64(0), nil
+	}
+
+	ks.setString(args[0], args[1])
+	return int64(1), nil
+}
+
+func cmdGetSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	old, err := ks.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ks.setString(args[0], args[1])
+	if old == nil {
+		return nil, nil
+	}
+	return old, nil
+}
+
+func cmdGetDel(ks *KeySpace, args [][]byte) (interface{}, error) {
+	value, err := ks.getString(args[0])
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	delete(ks.keys, string(args[0]))
+	return value, nil
+}
+
+func cmdGetEx(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var persist bool
+	var expireAt time.Time
+
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToLower(string(args[i])); option {
+		case "persist":
+			persist = true
+		case "ex", "px", "exat", "pxat":
+			if !expireAt.IsZero() || i+1 >= len(args) {
+				return nil, errSyntax
+			}
+
+			var err error
+			if expireAt, err = ks.expiryTime("getex", option, args[i+1], true); err != nil {
+				return nil, err
+			}
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	if persist && !expireAt.IsZero() {
+		return nil, errSyntax
+	}
+
+	value, err := ks.getString(args[0])
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	if persist {
+		ks.get(args[0]).expireAt = time.Time{}
+	} else if !expireAt.IsZero() {
+		ks.expire(args[0], expireAt)
+	}
+	return value, nil
+}
+
+func cmdMGet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	reply := make([]interface{}, len(args))
+	for i, key := range args {
+		// keys holding other data types are returned as nil
+		if value, err := ks.getString(key); err == nil && value != nil {
+			reply[i] = value
+		}
+	}
+	return reply, nil
+}
+
+func cmdMSet(ks *KeySpace, args [][]byte) (interface{}, error) {
+	if len(args)%2 != 0 {
+		return nil, errWrongArgs("mset")
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		ks.setString(args[i], args[i+1])
+	}
+	return "OK", nil
+}
+
+func cmdMSetNX(ks *KeySpace, args [][]byte) (interface{}, error) {
+	if len(args)%2 != 0 {
+		return nil, errWrongArgs("msetnx")
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		if ks.get(args[i]) != nil {
+			return int64(0), nil
+		}
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		ks.setString(args[i], args[i+1])
+	}
+	return int64(1), nil
+}
+
+func cmdIncr(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.incrBy(args[0], 1)
+}
+
+func cmdIncrBy(ks *KeySpace, args [][]byte) (interface{}, error) {
+	increment, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return ks.incrBy(args[0], increment)
+}
+
+func cmdDecr(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.incrBy(args[0], -1)
+}
+
+func cmdDecrBy(ks *KeySpace, args [][]byte) (interface{}, error) {
+	decrement, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if decrement == math.MinInt64 {
+		return nil, errIncrOverflow
+	}
+	return ks.incrBy(args[0], -decrement)
+}
+
+func cmdIncrByFloat(ks *KeySpace, args [][]byte) (interface{}, error) {
+	increment, err := parseFloat(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ks.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var f float64
+	if value != nil {
+		if f, err = parseFloat(value); err != nil {
+			return nil, err
+		}
+	}
+
+	f += increment
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, errIncrNaN
+	}
+
+	result := formatIncrFloat(value, args[1])
+	ks.updateString(args[0], result)
+	return result, nil
+}
+
+func cmdAppend(ks *KeySpace, args [][]byte) (interface{}, error) {
+	value, err := ks.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	value = append(append([]byte{}, value...), args[1]...)
+	ks.updateString(args[0], value)
+	return int64(len(value)), nil
+}
+
+func cmdStrLen(ks *KeySpace, args [][]byte) (interface{}, error) {
+	value, err := ks.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(value)), nil
+}
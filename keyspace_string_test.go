@@ -0,0 +1,57 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+)
+
+func TestKeySpaceStrings(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "GET", args: []interface{}{"key"}, expected: nil},
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "GET", args: []interface{}{"key"}, expected: []byte("value")},
+		{command: "SET", args: []interface{}{"key", "other", "NX"}, expected: nil},
+		{command: "SET", args: []interface{}{"missing", "other", "XX"}, expected: nil},
+		{command: "SET", args: []interface{}{"key", "other", "XX", "GET"}, expected: []byte("value")},
+		{command: "SET", args: []interface{}{"key", "v", "NX", "XX"}, err: errSyntax},
+		{command: "SET", args: []interface{}{"empty", ""}, expected: "OK"},
+		{command: "GET", args: []interface{}{"empty"}, expected: []byte{}},
+		{command: "SETNX", args: []interface{}{"key", "value"}, expected: int64(0)},
+		{command: "GETSET", args: []interface{}{"key", "new"}, expected: []byte("other")},
+		{command: "GETDEL", args: []interface{}{"key"}, expected: []byte("new")},
+		{command: "GETDEL", args: []interface{}{"key"}, expected: nil},
+		{command: "MSET", args: []interface{}{"a", 1, "b", 2}, expected: "OK"},
+		{command: "MSET", args: []interface{}{"a", 1, "b"}, err: errWrongArgs("mset")},
+		{command: "MGET", args: []interface{}{"a", "z", "b"}, expected: []interface{}{[]byte("1"), nil, []byte("2")}},
+		{command: "MSETNX", args: []interface{}{"a", 3, "c", 4}, expected: int64(0)},
+		{command: "MSETNX", args: []interface{}{"c", 3, "d", 4}, expected: int64(1)},
+		{command: "APPEND", args: []interface{}{"c", "x"}, expected: int64(2)},
+		{command: "STRLEN", args: []interface{}{"c"}, expected: int64(2)},
+		{command: "STRLEN", args: []interface{}{"z"}, expected: int64(0)},
+	})
+}
+
+func TestKeySpaceCounters(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "INCR", args: []interface{}{"counter"}, expected: int64(1)},
+		{command: "INCRBY", args: []interface{}{"counter", 9}, expected: int64(10)},
+		{command: "DECR", args: []interface{}{"counter"}, expected: int64(9)},
+		{command: "DECRBY", args: []interface{}{"counter", 4}, expected: int64(5)},
+		{command: "INCRBY", args: []interface{}{"counter", "x"}, err: errNotInteger},
+		{command: "SET", args: []interface{}{"counter", "9223372036854775807"}, expected: "OK"},
+		{command: "INCR", args: []interface{}{"counter"}, err: errIncrOverflow},
+		{command: "SET", args: []interface{}{"text", "abc"}, expected: "OK"},
+		{command: "INCR", args: []interface{}{"text"}, err: errNotInteger},
+		{command: "INCRBYFLOAT", args: []interface{}{"float", 1.5}, expected: []byte("1.5")},
+		{command: "INCRBYFLOAT", args: []interface{}{"float", "0.25"}, expected: []byte("1.75")},
+		{command: "INCRBYFLOAT", args: []interface{}{"float", "inf"}, err: errIncrNaN},
+		{command: "INCRBYFLOAT", args: []interface{}{"decimal", 0.1}, expected: []byte("0.1")},
+		{command: "INCRBYFLOAT", args: []interface{}{"decimal", 0.2}, expected: []byte("0.3")},
+		{command: "INCRBYFLOAT", args: []interface{}{"decimal", "1.05e1"}, expected: []byte("10.8")},
+		{command: "INCRBYFLOAT", args: []interface{}{"decimal", -10.8}, expected: []byte("0")},
+		{command: "GET", args: []interface{}{"float"}, expected: []byte("1.75")},
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// keySpaceStart is the clock time of the connections used in key space
+// tests
+var keySpaceStart = time.Unix(1700000000, 0)
+
+// keySpaceStep is a command executed against the key space with its expected
+// reply. The connection clock is advanced before executing the command, and
+// steps without a command only advance the clock
+type keySpaceStep struct {
+	advance  time.Duration
+	command  string
+	args     []interface{}
+	expected interface{}
+	err      error
+}
+
+// runKeySpaceSteps executes the commands in a connection using a new key
+// space, checking the replies
+func runKeySpaceSteps(t *testing.T, steps []keySpaceStep) {
+	t.Helper()
+
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+	conn.SetClock(NewClock(keySpaceStart))
+
+	for i, step := range steps {
+		conn.Clock().Advance(step.advance)
+		if step.command == "" {
+			continue
+		}
+
+		reply, err := conn.Do(step.command, step.args...)
+		if !reflect.DeepEqual(err, step.err) {
+			t.Errorf("Step %d (%s %v): expected error '%v' and got '%v'", i, step.command, step.args, step.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(reply, step.expected) {
+			t.Errorf("Step %d (%s %v): expected reply %#v and got %#v", i, step.command, step.args, step.expected, reply)
+		}
+	}
+}
+
+func bulks(values ...string) []interface{} {
+	reply := make([]interface{}, len(values))
+	for i, value := range values {
+		reply[i] = []byte(value)
+	}
+	return reply
+}
+
+func TestKeySpaceGeneric(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SET", args: []interface{}{"a", 1}, expected: "OK"},
+		{command: "RPUSH", args: []interface{}{"b", "x"}, expected: int64(1)},
+		{command: "SADD", args: []interface{}{"c", "x"}, expected: int64(1)},
+		{command: "EXISTS", args: []interface{}{"a", "b", "z"}, expected: int64(2)},
+		{command: "TYPE", args: []interface{}{"a"}, expected: "string"},
+		{command: "TYPE", args: []interface{}{"b"}, expected: "list"},
+		{command: "TYPE", args: []interface{}{"z"}, expected: "none"},
+		{command: "KEYS", args: []interface{}{"*"}, expected: bulks("a", "b", "c")},
+		{command: "KEYS", args: []interface{}{"[ab]"}, expected: bulks("a", "b")},
+		{command: "DBSIZE", expected: int64(3)},
+		{command: "RENAME", args: []interface{}{"a", "d"}, expected: "OK"},
+		{command: "RENAME", args: []interface{}{"a", "d"}, err: redis.Error("ERR no such key")},
+		{command: "GET", args: []interface{}{"d"}, expected: []byte("1")},
+		{command: "DEL", args: []interface{}{"d", "z"}, expected: int64(1)},
+		{command: "FLUSHDB", expected: "OK"},
+		{command: "DBSIZE", expected: int64(0)},
+		{command: "DBSIZE", args: []interface{}{"x"}, err: redis.Error("ERR wrong number of arguments for 'dbsize' command")},
+	})
+}
+
+func TestKeySpaceWrongType(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "SET", args: []interface{}{"key", "value"}, expected: "OK"},
+		{command: "HGET", args: []interface{}{"key", "field"}, err: WrongTypeError()},
+		{command: "LPUSH", args: []interface{}{"key", "value"}, err: WrongTypeError()},
+		{command: "SADD", args: []interface{}{"key", "value"}, err: WrongTypeError()},
+		{command: "ZADD", args: []interface{}{"key", 1, "value"}, err: WrongTypeError()},
+	})
+}
+
+func TestKeySpaceRegisteredCommandsHavePriority(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+	cmd := conn.Command("GET", "other").ExpectLoading()
+
+	if _, err := conn.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+
+	if _, err := conn.Do("GET", "other"); !reflect.DeepEqual(err, LoadingError()) {
+		t.Errorf("Expected loading error and got '%v'", err)
+	}
+
+	if counter := conn.Stats(cmd); counter != 1 {
+		t.Errorf("Expected command to be called once and got %d", counter)
+	}
+
+	if _, err := conn.Do("UNKNOWN"); err == nil {
+		t.Error("Expected error for a command unsupported by the key space")
+	}
+}
+
+func TestKeySpacePipeline(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	conn.Send("INCR", "counter")
+	conn.Send("INCRBY", "counter", 10)
+	conn.Send("GET", "counter")
+
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{int64(1), int64(11), []byte("11")}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Errorf("Expected %#v and got %#v", expected, replies)
+	}
+}
+
+func TestKeySpaceSharedByPool(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.UseKeySpace(NewKeySpace())
+
+	pool := mockPool.RedisPool()
+	conn1 := pool.Get()
+	conn2 := pool.Get()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	if _, err := conn1.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := redis.String(conn2.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+}
+
+func TestKeySpaceDisabled(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+	conn.UseKeySpace(nil)
+
+	if _, err := conn.Do("SET", "key", "value"); err == nil {
+		t.Error("Expected error for a command not registered")
+	}
+
+	if conn.KeySpace() != nil {
+		t.Error("Expected no key space")
+	}
+}
+
+func TestRangeIndexes(t *testing.T) {
+	data := []struct {
+		start, stop int64
+		length      int
+		from, to    int
+		ok          bool
+	}{
+		{start: 0, stop: -1, length: 3, from: 0, to: 3, ok: true},
+		{start: 1, stop: 1, length: 3, from: 1, to: 2, ok: true},
+		{start: -2, stop: 10, length: 3, from: 1, to: 3, ok: true},
+		{start: -10, stop: 0, length: 3, from: 0, to: 1, ok: true},
+		{start: 2, stop: 1, length: 3},
+		{start: 5, stop: 10, length: 3},
+		{start: 0, stop: -1, length: 0},
+	}
+
+	for i, item := range data {
+		from, to, ok := rangeIndexes(item.start, item.stop, item.length)
+		if from != item.from || to != item.to || ok != item.ok {
+			t.Errorf("Item %d: expected (%d, %d, %t) and got (%d, %d, %t)",
+				i, item.from, item.to, item.ok, from, to, ok)
+		}
+	}
+}
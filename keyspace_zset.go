@@ -0,0 +1,712 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func init() {
+	keySpaceCommands["zadd"] = keySpaceCommand{min: 3, max: -1, handler: cmdZAdd}
+	keySpaceCommands["zrem"] = keySpaceCommand{min: 2, max: -1, handler: cmdZRem}
+	keySpaceCommands["zscore"] = keySpaceCommand{min: 2, max: 2, handler: cmdZScore}
+	keySpaceCommands["zcard"] = keySpaceCommand{min: 1, max: 1, handler: cmdZCard}
+	keySpaceCommands["zincrby"] = keySpaceCommand{min: 3, max: 3, handler: cmdZIncrBy}
+	keySpaceCommands["zrange"] = keySpaceCommand{min: 3, max: -1, handler: cmdZRange}
+	keySpaceCommands["zrevrange"] = keySpaceCommand{min: 3, max: 4, handler: cmdZRevRange}
+	keySpaceCommands["zrangebyscore"] = keySpaceCommand{min: 3, max: 7, handler: cmdZRangeByScore}
+	keySpaceCommands["zrevrangebyscore"] = keySpaceCommand{min: 3, max: 7, handler: cmdZRevRangeByScore}
+	keySpaceCommands["zrank"] = keySpaceCommand{min: 2, max: 2, handler: cmdZRank}
+	keySpaceCommands["zrevrank"] = keySpaceCommand{min: 2, max: 2, handler: cmdZRevRank}
+	keySpaceCommands["zcount"] = keySpaceCommand{min: 3, max: 3, handler: cmdZCount}
+	keySpaceCommands["zremrangebyscore"] = keySpaceCommand{min: 3, max: 3, handler: cmdZRemRangeByScore}
+	keySpaceCommands["zremrangebyrank"] = keySpaceCommand{min: 3, max: 3, handler: cmdZRemRangeByRank}
+	keySpaceCommands["zpopmin"] = keySpaceCommand{min: 1, max: 2, handler: cmdZPopMin}
+	keySpaceCommands["zpopmax"] = keySpaceCommand{min: 1, max: 2, handler: cmdZPopMax}
+}
+
+// zsetMember is a member of a sorted set with its score
+type zsetMember struct {
+	member string
+	score  float64
+}
+
+// scoreBound is a limit of a score range, which can be exclusive
+type scoreBound struct {
+	value     float64
+	exclusive bool
+}
+
+// getZSet returns the sorted set stored in the key. When the key doesn't
+// exist and create is true, a new empty sorted set is stored, otherwise nil is
+// returned
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) getZSet(key []byte, create bool) (map[string]float64, error) {
+	e, err := ks.lookup(key, "zset")
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &entry{value: make(map[string]float64)}
+		ks.keys[string(key)] = e
+	}
+	return e.value.(map[string]float64), nil
+}
+
+// removeEmptyZSet removes the key when the sorted set is empty, like the Redis
+// server does
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) removeEmptyZSet(key []byte, zset map[string]float64) {
+	if zset != nil && len(zset) == 0 {
+		delete(ks.keys, string(key))
+	}
+}
+
+// sortedMembers returns the sorted set members ordered by score, and then
+// lexicographically for members with the same score
+func sortedMembers(zset map[string]float64) []zsetMember {
+	members := make([]zsetMember, 0, len(zset))
+	for member, score := range zset {
+		members = append(members, zsetMember{member: member, score: score})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+// reverseMembers reverses the order of the members
+func reverseMembers(members []zsetMember) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+// zsetReply converts the members to an array reply, with the scores after
+// each member when withScores is true
+func zsetReply(members []zsetMember, withScores bool) []interface{} {
+	reply := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		reply = append(reply, []byte(m.member))
+		if withScores {
+			reply = append(reply, formatFloat(m.score))
+		}
+	}
+	return reply
+}
+
+// parseScoreBound parses a limit of a score range, like "(1.5" or "-inf"
+func parseScoreBound(arg []byte) (scoreBound, error) {
+	var bound scoreBound
+	if len(arg) > 0 && arg[0] == '(' {
+		bound.exclusive = true
+		arg = arg[1:]
+	}
+
+	value, err := parseFloat(arg)
+	if err != nil {
+		return bound, redis.Error("ERR min or max is not a float")
+	}
+	bound.value = value
+	return bound, nil
+}
+
+// inScoreRange checks if the score is inside the range
+func inScoreRange(score float64, min, max scoreBound) bool {
+	if score < min.value || (min.exclusive && score == min.value) {
+		return false
+	}
+	if score > max.value || (max.exclusive && score == max.value) {
+		return false
+	}
+	return true
+}
+
+func cmdZAdd(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var nx, xx, gt, lt, ch, incr bool
+
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return nil, errSyntax
+	}
+
+	if (nx && xx) || (gt && lt) || (nx && (gt || lt)) {
+		return nil, redis.Error("ERR XX and NX options at the same time are not compatible")
+	}
+
+	if incr && len(pairs) != 2 {
+		return nil, redis.Error("ERR INCR option supports a single increment-element pair")
+	}
+
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, err := parseFloat(pairs[j*2])
+		if err != nil {
+			return nil, err
+		}
+		scores[j] = score
+	}
+
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var added, changed int64
+	var incrResult interface{}
+
+	for j, score := range scores {
+		member := string(pairs[j*2+1])
+		current, exists := zset[member]
+
+		if (nx && exists) || (xx && !exists) {
+			continue
+		}
+
+		if incr && exists {
+			score += current
+		}
+
+		if exists && ((gt && score <= current) || (lt && score >= current)) {
+			continue
+		}
+
+		if zset == nil {
+			zset, _ = ks.getZSet(args[0], true)
+		}
+
+		zset[member] = score
+		incrResult = formatFloat(score)
+
+		if !exists {
+			added++
+		} else if current != score {
+			changed++
+		}
+	}
+
+	if incr {
+		return incrResult, nil
+	}
+	if ch {
+		return added + changed, nil
+	}
+	return added, nil
+}
+
+func cmdZRem(ks *KeySpace, args [][]byte) (interface{}, error) {
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed int64
+	for _, member := range args[1:] {
+		if _, ok := zset[string(member)]; ok {
+			delete(zset, string(member))
+			removed++
+		}
+	}
+
+	ks.removeEmptyZSet(args[0], zset)
+	return removed, nil
+}
+
+func cmdZScore(ks *KeySpace, args [][]byte) (interface{}, error) {
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	if score, ok := zset[string(args[1])]; ok {
+		return formatFloat(score), nil
+	}
+	return nil, nil
+}
+
+func cmdZCard(ks *KeySpace, args [][]byte) (interface{}, error) {
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(zset)), nil
+}
+
+func cmdZIncrBy(ks *KeySpace, args [][]byte) (interface{}, error) {
+	increment, err := parseFloat(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	score := zset[string(args[2])] + increment
+	zset[string(args[2])] = score
+	return formatFloat(score), nil
+}
+
+// zrange implements the ZREVRANGE command and ZRANGE without options, where
+// the range is given by indexes
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) zrange(args [][]byte, reverse bool) (interface{}, error) {
+	withScores := false
+	if len(args) == 4 {
+		if strings.ToLower(string(args[3])) != "withscores" {
+			return nil, errSyntax
+		}
+		withScores = true
+	}
+	return ks.indexRange(args[0], args[1], args[2], reverse, withScores)
+}
+
+// indexRange returns the members of the sorted set between the indexes
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) indexRange(key, startArg, stopArg []byte, reverse, withScores bool) (interface{}, error) {
+	start, err := parseInt(startArg)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := parseInt(stopArg)
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	if reverse {
+		reverseMembers(members)
+	}
+
+	from, to, ok := rangeIndexes(start, stop, len(members))
+	if !ok {
+		return []interface{}{}, nil
+	}
+	return zsetReply(members[from:to], withScores), nil
+}
+
+// zrangeByScore implements the ZRANGEBYSCORE and ZREVRANGEBYSCORE commands
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) zrangeByScore(args [][]byte, reverse bool) (interface{}, error) {
+	withScores := false
+	offset, count := int64(0), int64(-1)
+
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "withscores":
+			withScores = true
+		case "limit":
+			if i+2 >= len(args) {
+				return nil, errSyntax
+			}
+			var err error
+			if offset, err = parseInt(args[i+1]); err != nil {
+				return nil, err
+			}
+			if count, err = parseInt(args[i+2]); err != nil {
+				return nil, err
+			}
+			i += 2
+		default:
+			return nil, errSyntax
+		}
+	}
+	return ks.scoreRange(args[0], args[1], args[2], reverse, offset, count, withScores)
+}
+
+// scoreRange returns the members of the sorted set between the scores, where
+// the first bound is the maximum one in reverse order
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) scoreRange(key, minArg, maxArg []byte, reverse bool, offset, count int64, withScores bool) (interface{}, error) {
+	if reverse {
+		minArg, maxArg = maxArg, minArg
+	}
+
+	min, err := parseScoreBound(minArg)
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := parseScoreBound(maxArg)
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	if reverse {
+		reverseMembers(members)
+	}
+
+	var result []zsetMember
+	for _, m := range members {
+		if inScoreRange(m.score, min, max) {
+			result = append(result, m)
+		}
+	}
+	return zsetReply(limitMembers(result, offset, count), withScores), nil
+}
+
+// lexRange returns the members of the sorted set between the lexicographical
+// bounds, like "[a", "(b", "-" or "+", where the first bound is the maximum
+// one in reverse order
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) lexRange(key, minArg, maxArg []byte, reverse bool, offset, count int64) (interface{}, error) {
+	if reverse {
+		minArg, maxArg = maxArg, minArg
+	}
+
+	min, err := parseLexBound(minArg)
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := parseLexBound(maxArg)
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	if reverse {
+		reverseMembers(members)
+	}
+
+	var result []zsetMember
+	for _, m := range members {
+		if min.below(m.member) && max.above(m.member) {
+			result = append(result, m)
+		}
+	}
+	return zsetReply(limitMembers(result, offset, count), false), nil
+}
+
+// limitMembers returns count members starting at offset, or all members
+// after the offset when count is negative, like the LIMIT option
+func limitMembers(members []zsetMember, offset, count int64) []zsetMember {
+	if offset < 0 || offset >= int64(len(members)) {
+		return nil
+	}
+	members = members[offset:]
+
+	if count >= 0 && count < int64(len(members)) {
+		members = members[:count]
+	}
+	return members
+}
+
+// lexBound is a limit of a lexicographical range, which can be exclusive or
+// unlimited ("-" and "+")
+type lexBound struct {
+	value     string
+	exclusive bool
+	infinite  int // -1 for "-" and 1 for "+"
+}
+
+// parseLexBound parses a limit of a lexicographical range, like "[a", "(b",
+// "-" or "+"
+func parseLexBound(arg []byte) (lexBound, error) {
+	switch {
+	case string(arg) == "-":
+		return lexBound{infinite: -1}, nil
+	case string(arg) == "+":
+		return lexBound{infinite: 1}, nil
+	case len(arg) > 0 && arg[0] == '[':
+		return lexBound{value: string(arg[1:])}, nil
+	case len(arg) > 0 && arg[0] == '(':
+		return lexBound{value: string(arg[1:]), exclusive: true}, nil
+	}
+	return lexBound{}, redis.Error("ERR min or max not valid string range item")
+}
+
+// below checks if the bound, used as minimum, is below the member
+func (b lexBound) below(member string) bool {
+	switch {
+	case b.infinite != 0:
+		return b.infinite < 0
+	case b.exclusive:
+		return b.value < member
+	}
+	return b.value <= member
+}
+
+// above checks if the bound, used as maximum, is above the member
+func (b lexBound) above(member string) bool {
+	switch {
+	case b.infinite != 0:
+		return b.infinite > 0
+	case b.exclusive:
+		return b.value > member
+	}
+	return b.value >= member
+}
+
+// zrank implements the ZRANK and ZREVRANK commands
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) zrank(args [][]byte, reverse bool) (interface{}, error) {
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	if reverse {
+		reverseMembers(members)
+	}
+
+	for i, m := range members {
+		if m.member == string(args[1]) {
+			return int64(i), nil
+		}
+	}
+	return nil, nil
+}
+
+// zpop implements the ZPOPMIN and ZPOPMAX commands
+//
+// Caller must hold ks.mu.
+func (ks *KeySpace) zpop(args [][]byte, max bool) (interface{}, error) {
+	count := int64(1)
+	if len(args) > 1 {
+		var err error
+		if count, err = parseInt(args[1]); err != nil || count < 0 {
+			return nil, redis.Error("ERR value is out of range, must be positive")
+		}
+	}
+
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	if max {
+		reverseMembers(members)
+	}
+
+	if count > int64(len(members)) {
+		count = int64(len(members))
+	}
+
+	for _, m := range members[:count] {
+		delete(zset, m.member)
+	}
+
+	ks.removeEmptyZSet(args[0], zset)
+	return zsetReply(members[:count], true), nil
+}
+
+func cmdZRange(ks *KeySpace, args [][]byte) (interface{}, error) {
+	var byScore, byLex, reverse, withScores, limit bool
+	offset, count := int64(0), int64(-1)
+
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "byscore":
+			byScore = true
+		case "bylex":
+			byLex = true
+		case "rev":
+			reverse = true
+		case "withscores":
+			withScores = true
+		case "limit":
+			if i+2 >= len(args) {
+				return nil, errSyntax
+			}
+			var err error
+			if offset, err = parseInt(args[i+1]); err != nil {
+				return nil, err
+			}
+			if count, err = parseInt(args[i+2]); err != nil {
+				return nil, err
+			}
+			limit = true
+			i += 2
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	switch {
+	case byScore && byLex:
+		return nil, errSyntax
+	case limit && !byScore && !byLex:
+		return nil, redis.Error("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	case withScores && byLex:
+		return nil, redis.Error("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	case byScore:
+		return ks.scoreRange(args[0], args[1], args[2], reverse, offset, count, withScores)
+	case byLex:
+		return ks.lexRange(args[0], args[1], args[2], reverse, offset, count)
+	}
+	return ks.indexRange(args[0], args[1], args[2], reverse, withScores)
+}
+
+func cmdZRevRange(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zrange(args, true)
+}
+
+func cmdZRangeByScore(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zrangeByScore(args, false)
+}
+
+func cmdZRevRangeByScore(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zrangeByScore(args, true)
+}
+
+func cmdZRank(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zrank(args, false)
+}
+
+func cmdZRevRank(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zrank(args, true)
+}
+
+func cmdZCount(ks *KeySpace, args [][]byte) (interface{}, error) {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	for _, score := range zset {
+		if inScoreRange(score, min, max) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func cmdZRemRangeByScore(ks *KeySpace, args [][]byte) (interface{}, error) {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed int64
+	for member, score := range zset {
+		if inScoreRange(score, min, max) {
+			delete(zset, member)
+			removed++
+		}
+	}
+
+	ks.removeEmptyZSet(args[0], zset)
+	return removed, nil
+}
+
+func cmdZRemRangeByRank(ks *KeySpace, args [][]byte) (interface{}, error) {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	zset, err := ks.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(zset)
+	from, to, ok := rangeIndexes(start, stop, len(members))
+	if !ok {
+		return int64(0), nil
+	}
+
+	for _, m := range members[from:to] {
+		delete(zset, m.member)
+	}
+
+	ks.removeEmptyZSet(args[0], zset)
+	return int64(to - from), nil
+}
+
+func cmdZPopMin(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zpop(args, false)
+}
+
+func cmdZPopMax(ks *KeySpace, args [][]byte) (interface{}, error) {
+	return ks.zpop(args, true)
+}
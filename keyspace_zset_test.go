@@ -0,0 +1,60 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySpaceSortedSets(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "ZADD", args: []interface{}{"z", 2, "b", 1, "a", 2, "c"}, expected: int64(3)},
+		{command: "ZADD", args: []interface{}{"z", "NX", 5, "a"}, expected: int64(0)},
+		{command: "ZADD", args: []interface{}{"z", "XX", "CH", 3, "c"}, expected: int64(1)},
+		{command: "ZADD", args: []interface{}{"z", "INCR", 1, "a"}, expected: []byte("2")},
+		{command: "ZADD", args: []interface{}{"z", "NX", 1}, err: errSyntax},
+		{command: "ZADD", args: []interface{}{"z", "x", "a"}, err: errNotFloat},
+		{command: "ZCARD", args: []interface{}{"z"}, expected: int64(3)},
+		{command: "ZSCORE", args: []interface{}{"z", "c"}, expected: []byte("3")},
+		{command: "ZSCORE", args: []interface{}{"z", "x"}, expected: nil},
+		{command: "ZRANGE", args: []interface{}{"z", 0, -1}, expected: bulks("a", "b", "c")},
+		{command: "ZRANGE", args: []interface{}{"z", 0, 0, "WITHSCORES"}, expected: bulks("a", "2")},
+		{command: "ZREVRANGE", args: []interface{}{"z", 0, 1}, expected: bulks("c", "b")},
+		{command: "ZRANGEBYSCORE", args: []interface{}{"z", "(2", "+inf"}, expected: bulks("c")},
+		{command: "ZRANGEBYSCORE", args: []interface{}{"z", "-inf", "+inf", "LIMIT", 1, 1}, expected: bulks("b")},
+		{command: "ZRANGEBYSCORE", args: []interface{}{"z", "x", 1}, err: redis.Error("ERR min or max is not a float")},
+		{command: "ZREVRANGEBYSCORE", args: []interface{}{"z", 3, 2, "WITHSCORES"}, expected: bulks("c", "3", "b", "2", "a", "2")},
+		{command: "ZRANK", args: []interface{}{"z", "c"}, expected: int64(2)},
+		{command: "ZREVRANK", args: []interface{}{"z", "c"}, expected: int64(0)},
+		{command: "ZRANK", args: []interface{}{"z", "x"}, expected: nil},
+		{command: "ZCOUNT", args: []interface{}{"z", 2, "(3"}, expected: int64(2)},
+		{command: "ZINCRBY", args: []interface{}{"z", 1.5, "d"}, expected: []byte("1.5")},
+		{command: "ZPOPMIN", args: []interface{}{"z"}, expected: bulks("d", "1.5")},
+		{command: "ZPOPMAX", args: []interface{}{"z", 2}, expected: bulks("c", "3", "b", "2")},
+		{command: "ZREM", args: []interface{}{"z", "a", "x"}, expected: int64(1)},
+		{command: "EXISTS", args: []interface{}{"z"}, expected: int64(0)},
+		{command: "ZADD", args: []interface{}{"z", 1, "a", 2, "b", 3, "c"}, expected: int64(3)},
+		{command: "ZREMRANGEBYSCORE", args: []interface{}{"z", 2, 2}, expected: int64(1)},
+		{command: "ZREMRANGEBYRANK", args: []interface{}{"z", 0, -1}, expected: int64(2)},
+		{command: "ZCARD", args: []interface{}{"z"}, expected: int64(0)},
+	})
+}
+
+func TestKeySpaceZRangeOptions(t *testing.T) {
+	runKeySpaceSteps(t, []keySpaceStep{
+		{command: "ZADD", args: []interface{}{"z", 1, "a", 2, "b", 3, "c", 4, "d"}, expected: int64(4)},
+		{command: "ZRANGE", args: []interface{}{"z", 0, 1, "REV"}, expected: bulks("d", "c")},
+		{command: "ZRANGE", args: []interface{}{"z", "(1", 3, "BYSCORE", "WITHSCORES"}, expected: bulks("b", "2", "c", "3")},
+		{command: "ZRANGE", args: []interface{}{"z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", 1, 2}, expected: bulks("c", "b")},
+		{command: "ZRANGE", args: []interface{}{"z", "[b", "(d", "BYLEX"}, expected: bulks("b", "c")},
+		{command: "ZRANGE", args: []interface{}{"z", "+", "-", "BYLEX", "REV", "LIMIT", 0, 1}, expected: bulks("d")},
+		{command: "ZRANGE", args: []interface{}{"z", "b", "d", "BYLEX"}, err: redis.Error("ERR min or max not valid string range item")},
+		{command: "ZRANGE", args: []interface{}{"z", 0, 1, "LIMIT", 0, 1}, err: redis.Error("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")},
+		{command: "ZRANGE", args: []interface{}{"z", "-", "+", "BYLEX", "WITHSCORES"}, err: redis.Error("ERR syntax error, WITHSCORES not supported in combination with BYLEX")},
+		{command: "ZRANGE", args: []interface{}{"z", 0, 1, "BYSCORE", "BYLEX"}, err: errSyntax},
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import "fmt"
+
+// ResponsePolicy defines what a command returns once all its responses were
+// returned (see Cmd.Policy and Conn.ResponsePolicy). Commands without
+// responses aren't affected
+type ResponsePolicy int
+
+const (
+	// RepeatLast returns the last response forever, the default policy
+	RepeatLast ResponsePolicy = iota
+
+	// Cycle returns the responses again from the first one
+	Cycle
+
+	// FailWhenExhausted returns an error, also reported by
+	// ExpectationsWereMet, useful to detect unexpected calls
+	FailWhenExhausted
+
+	// FallThrough ignores the command, so the next matching one handles the
+	// call, like a generic command or the key space
+	FallThrough
+)
+
+// responsePolicy returns the policy of the commands without their own,
+// falling back to the connection that it shares the registry with (see Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) responsePolicy() ResponsePolicy {
+	return fromRegistry(c, func(conn *Conn) ResponsePolicy {
+		return conn.ResponsePolicy
+	})
+}
+
+// exhaustedError is the error returned by commands with the FailWhenExhausted
+// policy once all their responses were returned
+func exhaustedError(commandName string, args []interface{}) error {
+	return fmt.Errorf("command %s with arguments %#v called after all its responses were returned",
+		commandName, args)
+}
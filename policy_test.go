@@ -0,0 +1,102 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestResponsePolicy(t *testing.T) {
+	data := []struct {
+		policy   ResponsePolicy
+		expected []string
+	}{
+		{policy: RepeatLast, expected: []string{"a", "b", "b", "b"}},
+		{policy: Cycle, expected: []string{"a", "b", "a", "b"}},
+		{policy: FailWhenExhausted, expected: []string{"a", "b", "error", "error"}},
+		{policy: FallThrough, expected: []string{"a", "b", "generic", "generic"}},
+	}
+
+	for _, item := range data {
+		c := NewConn()
+		c.GenericCommand("GET").Expect("generic")
+		c.Command("GET", "k").Expect("a").Expect("b").Policy(item.policy)
+
+		var values []string
+		for i := 0; i < 4; i++ {
+			value, err := redis.String(c.Do("GET", "k"))
+			if err != nil {
+				value = "error"
+			}
+			values = append(values, value)
+		}
+
+		if strings.Join(values, ",") != strings.Join(item.expected, ",") {
+			t.Errorf("Expected %v and got %v for policy %d", item.expected, values, item.policy)
+		}
+	}
+}
+
+func TestResponsePolicyConn(t *testing.T) {
+	c := NewConn()
+	c.ResponsePolicy = FailWhenExhausted
+	cmd := c.Command("GET", "k").Expect("a")
+	c.Command("GET", "other").Expect("b").Policy(RepeatLast)
+	c.Command("PING")
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Do("GET", "other"); err != nil {
+			t.Errorf("Unexpected error for command with its own policy: %v", err)
+		}
+		if _, err := c.Do("PING"); err != nil {
+			t.Errorf("Unexpected error for command without responses: %v", err)
+		}
+	}
+
+	if _, err := c.Do("GET", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("GET", "k"); err == nil || !strings.Contains(err.Error(), "after all its responses were returned") {
+		t.Errorf("Expected exhausted error and got %v", err)
+	}
+	if c.Stats(cmd) != 2 {
+		t.Errorf("Expected 2 calls and got %d", c.Stats(cmd))
+	}
+	if err := c.ExpectationsWereMet(); err == nil {
+		t.Error("Expected the exhausted command to be reported")
+	}
+}
+
+func TestResponsePolicyFallThrough(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.ResponsePolicy = FallThrough
+	mockPool.UseKeySpace(NewKeySpace())
+	cmd := mockPool.Command("GET", "k").Expect("mocked")
+
+	conn, err := mockPool.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("SET", "k", "stored"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"mocked", "stored"} {
+		if value, err := redis.String(conn.Do("GET", "k")); err != nil || value != expected {
+			t.Errorf("Expected '%s' and got '%s' (%v)", expected, value, err)
+		}
+	}
+	if mockPool.Stats(cmd) != 1 {
+		t.Errorf("Expected 1 call and got %d", mockPool.Stats(cmd))
+	}
+
+	mockPool.UseKeySpace(nil)
+	if _, err := conn.Do("GET", "k"); err == nil || !strings.Contains(err.Error(), "after all its responses were returned") {
+		t.Errorf("Expected exhausted error and got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Pool creates mocked connections for a redis.Pool. Commands are registered in
+// the embedded Conn and are shared by all dialed connections. By default each
+// dialed connection has its own state (pipeline, subscriptions and errors),
+// set SharedConn to make all of them use the embedded Conn directly.
+//
+// The fields of Pool should not be modified after first use.
+type Pool struct {
+	*Conn                                                  // Connection where the commands are registered
+	SharedConn       bool                                  // When set to true, all dialed connections share the state of Conn
+	DialMock         func() error                          // Mock the dial, a non-nil error is returned instead of the connection
+	TestOnBorrowMock func(c redis.Conn, t time.Time) error // Mock the redis.Pool TestOnBorrow function
+	conns            []*Conn                               // Connections dialed by the pool
+	dialed           int                                   // Number of dialed connections
+	borrowed         int                                   // Number of connections retrieved from the pool
+	closed           int                                   // Number of closed connections
+	mu               sync.Mutex                            // Hold while accessing any mutable fields
+}
+
+// NewPool returns a new pool mock, where commands can be registered in the
+// same way of a Conn
+func NewPool() *Pool {
+	return &Pool{
+		Conn: NewConn(),
+	}
+}
+
+// RedisPool returns a redis.Pool that dials mocked connections. The returned
+// pool can be customized (MaxIdle, MaxActive, etc.), but the Dial,
+// DialContext and TestOnBorrow functions must not be replaced to keep the
+// counters working. To customize the TestOnBorrow behaviour use the
+// TestOnBorrowMock field
+func (p *Pool) RedisPool() *redis.Pool {
+	return &redis.Pool{
+		Dial:         p.Dial,
+		DialContext:  p.DialContext,
+		TestOnBorrow: p.testOnBorrow,
+	}
+}
+
+// Dial returns a new mocked connection. It can be used as the redis.Pool Dial
+// function
+func (p *Pool) Dial() (redis.Conn, error) {
+	return p.DialContext(context.Background())
+}
+
+// DialContext returns a new mocked connection. It can be used as the
+// redis.Pool DialContext function
+func (p *Pool) DialContext(ctx context.Context) (redis.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.DialMock != nil {
+		if err := p.DialMock(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dialed++
+	p.borrowed++
+
+	if p.SharedConn {
+		return &poolConn{Conn: p.Conn, pool: p}, nil
+	}
+
+	conn := NewConn()
+	conn.registry = p.Conn
+	p.conns = append(p.conns, conn)
+	return &poolConn{Conn: conn, pool: p}, nil
+}
+
+// testOnBorrow is called by redis.Pool when an idle connection is reused
+func (p *Pool) testOnBorrow(c redis.Conn, t time.Time) error {
+	if p.TestOnBorrowMock != nil {
+		if err := p.TestOnBorrowMock(c, t); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.borrowed++
+	return nil
+}
+
+// Conns returns the connections dialed by the pool. When SharedConn is set no
+// connection is returned, as all of them are the embedded Conn
+func (p *Pool) Conns() []*Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := make([]*Conn, len(p.conns))
+	copy(ret, p.conns)
+	return ret
+}
+
+// Dialed returns the number of connections successfully dialed
+func (p *Pool) Dialed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.dialed
+}
+
+// Borrowed returns the number of connections retrieved from the pool, dialed
+// or reused from the idle connections
+func (p *Pool) Borrowed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.borrowed
+}
+
+// Closed returns the number of dialed connections that were closed. A
+// connection returned to the pool as idle isn't closed
+func (p *Pool) Closed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.closed
+}
+
+// Stats returns the number of times that a command was called in all
+// connections of the pool
+func (p *Pool) Stats(cmd *Cmd) int {
+	total := p.Conn.Stats(cmd)
+	for _, conn := range p.Conns() {
+		total += conn.Stats(cmd)
+	}
+	return total
+}
+
+// Publish works like the Conn method, but delivers the message to all
+// connections of the pool
+func (p *Pool) Publish(channel string, payload interface{}) int {
+	delivered := p.Conn.Publish(channel, payload)
+	for _, conn := range p.Conns() {
+		delivered += conn.Publish(channel, payload)
+	}
+	return delivered
+}
+
+// ExpectationsWereMet works like the Conn method, but checks the
+// expectations in all connections of the pool
+func (p *Pool) ExpectationsWereMet() error {
+	errMsg := ""
+	if err := p.Conn.ExpectationsWereMet(); err != nil {
+		errMsg = err.Error()
+	}
+
+	for i, conn := range p.Conns() {
+		if err := conn.ExpectationsWereMet(); err != nil {
+			errMsg = fmt.Sprintf("%sConnection %d: %s", errMsg, i, err.Error())
+		}
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf("%s", errMsg)
+	}
+
+	return nil
+}
+
+// poolConn is a connection dialed by the pool, which counts the number of
+// times that it was closed
+type poolConn struct {
+	*Conn
+	pool *Pool
+}
+
+// Close closes the mocked connection. The shared connection is never closed,
+// as other dialed connections are still using it
+func (c *poolConn) Close() error {
+	c.pool.mu.Lock()
+	c.pool.closed++
+	c.pool.mu.Unlock()
+
+	if c.pool.SharedConn {
+		return nil
+	}
+	return c.Conn.Close()
+}
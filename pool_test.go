@@ -0,0 +1,214 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPool(t *testing.T) {
+	mockPool := NewPool()
+	cmd := mockPool.Command("GET", "key").Expect("value")
+
+	pool := mockPool.RedisPool()
+	pool.MaxIdle = 1
+
+	conn1 := pool.Get()
+	conn2 := pool.Get()
+
+	for _, conn := range []redis.Conn{conn1, conn2} {
+		if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+			t.Errorf("Unexpected reply '%s' (%v)", value, err)
+		}
+	}
+
+	conn1.Close()
+	conn2.Close()
+
+	conn3 := pool.Get()
+	conn3.Close()
+
+	if dialed := mockPool.Dialed(); dialed != 2 {
+		t.Errorf("Expected 2 dialed connections and got %d", dialed)
+	}
+
+	if borrowed := mockPool.Borrowed(); borrowed != 3 {
+		t.Errorf("Expected 3 borrowed connections and got %d", borrowed)
+	}
+
+	if closed := mockPool.Closed(); closed != 1 {
+		t.Errorf("Expected 1 closed connection and got %d", closed)
+	}
+
+	if counter := mockPool.Stats(cmd); counter != 2 {
+		t.Errorf("Expected command to be called 2 times and got %d", counter)
+	}
+
+	if conns := mockPool.Conns(); len(conns) != 2 {
+		t.Errorf("Expected 2 connections and got %d", len(conns))
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	pool.Close()
+
+	if closed := mockPool.Closed(); closed != 2 {
+		t.Errorf("Expected 2 closed connections and got %d", closed)
+	}
+}
+
+func TestPoolConnectionState(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.Command("GET", "key").Expect("value")
+
+	pool := mockPool.RedisPool()
+
+	conn1 := pool.Get()
+	defer conn1.Close()
+
+	conn2 := pool.Get()
+	defer conn2.Close()
+
+	conn1.Send("GET", "key")
+	conn2.Do("GET", "unknown")
+
+	if reply, err := redis.String(conn1.Receive()); err != nil || reply != "value" {
+		t.Errorf("Unexpected reply '%s' (%v)", reply, err)
+	}
+
+	conns := mockPool.Conns()
+	if errs := conns[0].Errors(); len(errs) != 0 {
+		t.Errorf("Unexpected errors in the first connection: %v", errs)
+	}
+	if errs := conns[1].Errors(); len(errs) != 1 {
+		t.Errorf("Expected 1 error in the second connection and got %d", len(errs))
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err == nil {
+		t.Error("Should detect the unregistered command")
+	}
+}
+
+func TestPoolSharedConn(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.SharedConn = true
+	cmd := mockPool.Command("GET", "key").Expect("value")
+
+	pool := mockPool.RedisPool()
+
+	conn := pool.Get()
+	conn.Do("GET", "key")
+	conn.Close()
+	pool.Close()
+
+	if counter := mockPool.Conn.Stats(cmd); counter != 1 {
+		t.Errorf("Expected command to be called once in the shared connection and got %d", counter)
+	}
+
+	if conns := mockPool.Conns(); len(conns) != 0 {
+		t.Errorf("Expected no connections and got %d", len(conns))
+	}
+
+	if closed := mockPool.Closed(); closed != 1 {
+		t.Errorf("Expected 1 closed connection and got %d", closed)
+	}
+
+	if _, err := mockPool.Do("GET", "key"); err != nil {
+		t.Errorf("Shared connection should not be closed: %v", err)
+	}
+}
+
+func TestPoolDialFailure(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.DialMock = func() error {
+		return fmt.Errorf("dial error")
+	}
+
+	conn := mockPool.RedisPool().Get()
+	if err := conn.Err(); err == nil || err.Error() != "dial error" {
+		t.Errorf("Expected dial error and got '%v'", err)
+	}
+
+	if dialed := mockPool.Dialed(); dialed != 0 {
+		t.Errorf("Expected no dialed connections and got %d", dialed)
+	}
+}
+
+func TestPoolTestOnBorrow(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.TestOnBorrowMock = func(c redis.Conn, t time.Time) error {
+		return fmt.Errorf("unhealthy")
+	}
+
+	pool := mockPool.RedisPool()
+	pool.MaxIdle = 1
+
+	pool.Get().Close()
+	pool.Get().Close()
+
+	if dialed := mockPool.Dialed(); dialed != 2 {
+		t.Errorf("Expected 2 dialed connections and got %d", dialed)
+	}
+
+	if borrowed := mockPool.Borrowed(); borrowed != 2 {
+		t.Errorf("Expected 2 borrowed connections and got %d", borrowed)
+	}
+
+	if closed := mockPool.Closed(); closed != 1 {
+		t.Errorf("Expected 1 closed connection and got %d", closed)
+	}
+}
+
+func TestPoolDiscardsBrokenConn(t *testing.T) {
+	mockPool := NewPool()
+	pool := mockPool.RedisPool()
+	pool.MaxIdle = 1
+
+	conn := pool.Get()
+	mockPool.Conns()[0].Break(fmt.Errorf("connection reset by peer"))
+	conn.Close()
+
+	pool.Get().Close()
+
+	if dialed := mockPool.Dialed(); dialed != 2 {
+		t.Errorf("Expected 2 dialed connections and got %d", dialed)
+	}
+
+	if closed := mockPool.Closed(); closed != 1 {
+		t.Errorf("Expected 1 closed connection and got %d", closed)
+	}
+}
+
+func TestPoolFault(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.Command("GET", "key").Expect("value")
+
+	loadingErr := redis.Error("LOADING Redis is loading the dataset in memory")
+	fault := mockPool.Fault().During(0, 3*time.Second).Error(loadingErr)
+
+	conn, err := mockPool.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Do("GET", "key"); err != loadingErr {
+		t.Errorf("Expected injected error and got '%v'", err)
+	}
+
+	mockPool.Clock().Advance(3 * time.Second)
+
+	if reply, err := conn.Do("GET", "key"); err != nil || reply != "value" {
+		t.Errorf("Unexpected reply '%v' (%v)", reply, err)
+	}
+	if injected := fault.Injected(); injected != 1 {
+		t.Errorf("Expected 1 injected fault and got %d", injected)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// pubSub handles the subscription commands (SUBSCRIBE, PSUBSCRIBE,
+// UNSUBSCRIBE and PUNSUBSCRIBE) that weren't registered, updating the
+// subscription state of the connection. It returns one confirmation reply for
+// each affected channel or pattern, like the Redis server does. PING commands
+// are also handled while the connection is subscribed, returning no reply when
+// the pong is dropped (see DropPongs). If the command isn't a subscription
+// command ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) pubSub(commandName string, args []interface{}) (replies []replyElement, ok bool) {
+	kind := strings.ToLower(commandName)
+
+	var subscriptions map[string]struct{}
+	switch kind {
+	case "subscribe", "unsubscribe":
+		if c.channels == nil {
+			c.channels = make(map[string]struct{})
+		}
+		subscriptions = c.channels
+	case "psubscribe", "punsubscribe":
+		if c.patterns == nil {
+			c.patterns = make(map[string]struct{})
+		}
+		subscriptions = c.patterns
+	case "ping":
+		return c.ping(args)
+	default:
+		return nil, false
+	}
+
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = argString(arg)
+	}
+
+	subscribing := !strings.Contains(kind, "unsubscribe")
+	if len(names) == 0 {
+		if subscribing {
+			err := redis.Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", kind))
+			return []replyElement{{err: err}}, true
+		}
+
+		for name := range subscriptions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(names) == 0 {
+			return []replyElement{{reply: []interface{}{[]byte(kind), nil, int64(c.subscriptionsCount())}}}, true
+		}
+	}
+
+	for _, name := range names {
+		if subscribing {
+			subscriptions[name] = struct{}{}
+		} else {
+			delete(subscriptions, name)
+		}
+
+		replies = append(replies, replyElement{
+			reply: []interface{}{[]byte(kind), []byte(name), int64(c.subscriptionsCount())},
+		})
+	}
+	return replies, true
+}
+
+// ping answers a PING command in subscription mode, where the Redis server
+// replies with a pong message instead of a status reply
+//
+// Caller must hold c.mu.
+func (c *Conn) ping(args []interface{}) (replies []replyElement, ok bool) {
+	if c.subscriptionsCount() == 0 {
+		return nil, false
+	}
+
+	if len(args) > 1 {
+		err := redis.Error("ERR wrong number of arguments for 'ping' command")
+		return []replyElement{{err: err}}, true
+	}
+
+	if c.droppedPongs != 0 {
+		if c.droppedPongs > 0 {
+			c.droppedPongs--
+		}
+		return nil, true
+	}
+
+	var data []byte
+	if len(args) == 1 {
+		data = formatArg(args[0])
+	}
+	return []replyElement{{reply: []interface{}{[]byte("pong"), data}}}, true
+}
+
+// DropPongs makes the next PING commands sent while the connection is
+// subscribed to not receive a pong, simulating a health check failure. The
+// subscriber will be blocked in Receive until it times out (see
+// SubscriptionFeed). A negative count drops all pongs, and zero stops dropping
+// them
+func (c *Conn) DropPongs(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.droppedPongs = count
+}
+
+// subscriptionsCount returns the number of channels and patterns that the
+// connection is subscribed to
+//
+// Caller must hold c.mu.
+func (c *Conn) subscriptionsCount() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+// Publish delivers a message to the connection if it is subscribed to the
+// channel, directly or via a pattern, returning the number of delivered
+// messages like the PUBLISH command. Messages are returned by the Receive
+// method in the same format of the Redis server, so redis.PubSubConn
+// generates redis.Message values (with the Pattern field filled when the
+// message matched a pattern). Subscriptions are tracked only for the
+// subscription commands without a registered response
+func (c *Conn) Publish(channel string, payload interface{}) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := formatArg(payload)
+	delivered := 0
+
+	if _, ok := c.channels[channel]; ok {
+		c.subResponses = append(c.subResponses, response{
+			response: []interface{}{[]byte("message"), []byte(channel), data},
+		})
+		delivered++
+	}
+
+	patterns := make([]string, 0, len(c.patterns))
+	for pattern := range c.patterns {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if !globMatch(pattern, channel) {
+			continue
+		}
+
+		c.subResponses = append(c.subResponses, response{
+			response: []interface{}{[]byte("pmessage"), []byte(pattern), []byte(channel), data},
+		})
+		delivered++
+	}
+
+	if delivered > 0 {
+		c.notify()
+	}
+	return delivered
+}
+
+// subscriptionFeedSize is the number of messages that can be sent to the
+// subscription feed without a Receive call consuming them
+const subscriptionFeedSize = 64
+
+// SubscriptionFeed returns a channel to deliver messages to the connection.
+// Once the feed is in use, Receive calls without a pending reply block until a
+// message is sent to the channel, a message is published (see Publish) or the
+// connection is closed. Messages can be sent in the Redis server format
+// (e.g. []interface{}{[]byte("message"), []byte("channel"), []byte("data")})
+// or as redis.Message, redis.Subscription and redis.Pong values, which are
+// converted to the server format. Errors sent to the channel are returned by
+// Receive as errors. The channel is buffered, so the test doesn't need a
+// goroutine to feed a few messages
+func (c *Conn) SubscriptionFeed() chan<- interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.feed == nil {
+		c.feed = make(chan interface{}, subscriptionFeedSize)
+		c.wakeup = make(chan struct{}, 1)
+	}
+	return c.feed
+}
+
+// feedReply converts a message sent to the subscription feed to the reply that
+// Receive returns
+func feedReply(msg interface{}) (interface{}, error) {
+	switch msg := msg.(type) {
+	case error:
+		return nil, msg
+	case redis.Message:
+		if msg.Pattern != "" {
+			return []interface{}{[]byte("pmessage"), []byte(msg.Pattern), []byte(msg.Channel), msg.Data}, nil
+		}
+		return []interface{}{[]byte("message"), []byte(msg.Channel), msg.Data}, nil
+	case redis.Subscription:
+		return []interface{}{[]byte(msg.Kind), []byte(msg.Channel), int64(msg.Count)}, nil
+	case redis.Pong:
+		return []interface{}{[]byte("pong"), []byte(msg.Data)}, nil
+	}
+	return msg, nil
+}
@@ -0,0 +1,288 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPubSubSubscriptions(t *testing.T) {
+	conn := NewConn()
+	psc := redis.PubSubConn{Conn: conn}
+
+	if err := psc.Subscribe("channel1", "channel2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := psc.PSubscribe("news.*"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []redis.Subscription{
+		{Kind: "subscribe", Channel: "channel1", Count: 1},
+		{Kind: "subscribe", Channel: "channel2", Count: 2},
+		{Kind: "psubscribe", Channel: "news.*", Count: 3},
+	}
+
+	for _, subscription := range expected {
+		switch msg := psc.Receive().(type) {
+		case redis.Subscription:
+			if msg != subscription {
+				t.Errorf("Expected subscription '%#v' and got '%#v'", subscription, msg)
+			}
+		default:
+			t.Errorf("Expected subscription message type but received '%T'", msg)
+		}
+	}
+
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if err := psc.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []redis.Subscription{
+		{Kind: "unsubscribe", Channel: "channel1", Count: 2},
+		{Kind: "unsubscribe", Channel: "channel2", Count: 1},
+	}
+
+	for _, subscription := range expected {
+		if msg := psc.Receive(); msg != subscription {
+			t.Errorf("Expected subscription '%#v' and got '%#v'", subscription, msg)
+		}
+	}
+}
+
+func TestPubSubPublish(t *testing.T) {
+	conn := NewConn()
+	psc := redis.PubSubConn{Conn: conn}
+
+	psc.Subscribe("news.tech")
+	psc.PSubscribe("news.*")
+	psc.Receive()
+	psc.Receive()
+
+	if delivered := conn.Publish("news.tech", "hello"); delivered != 2 {
+		t.Errorf("Expected message delivered 2 times and got %d", delivered)
+	}
+	if delivered := conn.Publish("news.sports", []byte("goal")); delivered != 1 {
+		t.Errorf("Expected message delivered once and got %d", delivered)
+	}
+	if delivered := conn.Publish("weather", "sunny"); delivered != 0 {
+		t.Errorf("Expected message not delivered and got %d", delivered)
+	}
+
+	expected := []redis.Message{
+		{Channel: "news.tech", Data: []byte("hello")},
+		{Channel: "news.tech", Pattern: "news.*", Data: []byte("hello")},
+		{Channel: "news.sports", Pattern: "news.*", Data: []byte("goal")},
+	}
+
+	for _, message := range expected {
+		msg := psc.Receive()
+		if !reflect.DeepEqual(msg, message) {
+			t.Errorf("Expected message '%#v' and got '%#v'", message, msg)
+		}
+	}
+
+	if err, ok := psc.Receive().(error); !ok || err == nil {
+		t.Error("Should not receive messages from unsubscribed channels")
+	}
+}
+
+func TestPubSubRegisteredCommand(t *testing.T) {
+	conn := NewConn()
+	conn.Command("SUBSCRIBE", "channel").ExpectError(redis.Error("NOPERM"))
+
+	psc := redis.PubSubConn{Conn: conn}
+	psc.Subscribe("channel")
+
+	if err, ok := psc.Receive().(error); !ok || err.Error() != "NOPERM" {
+		t.Errorf("Expected registered error and got '%v'", err)
+	}
+
+	if delivered := conn.Publish("channel", "data"); delivered != 0 {
+		t.Errorf("Expected message not delivered and got %d", delivered)
+	}
+}
+
+func TestPubSubWrongArguments(t *testing.T) {
+	conn := NewConn()
+
+	if _, err := conn.Do("SUBSCRIBE"); err == nil {
+		t.Error("Should detect a subscription without channels")
+	}
+
+	reply, err := conn.Do("UNSUBSCRIBE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{[]byte("unsubscribe"), nil, int64(0)}
+	if !reflect.DeepEqual(reply, expected) {
+		t.Errorf("Expected '%#v' and got '%#v'", expected, reply)
+	}
+}
+
+func TestSubscriptionFeed(t *testing.T) {
+	conn := NewConn()
+	feed := conn.SubscriptionFeed()
+	psc := redis.PubSubConn{Conn: conn}
+
+	psc.Subscribe("channel")
+
+	go func() {
+		feed <- redis.Message{Channel: "channel", Data: []byte("value1")}
+		feed <- []interface{}{[]byte("message"), []byte("channel"), []byte("value2")}
+		feed <- fmt.Errorf("simulated error")
+	}()
+
+	if msg, ok := psc.Receive().(redis.Subscription); !ok || msg.Channel != "channel" {
+		t.Errorf("Expected subscription message and got '%#v'", msg)
+	}
+
+	for _, expected := range []string{"value1", "value2"} {
+		msg, ok := psc.Receive().(redis.Message)
+		if !ok || string(msg.Data) != expected {
+			t.Errorf("Expected message '%s' and got '%#v'", expected, msg)
+		}
+	}
+
+	if err, ok := psc.Receive().(error); !ok || err.Error() != "simulated error" {
+		t.Errorf("Expected feed error and got '%v'", err)
+	}
+}
+
+func TestSubscriptionFeedPublish(t *testing.T) {
+	conn := NewConn()
+	conn.SubscriptionFeed()
+	psc := redis.PubSubConn{Conn: conn}
+
+	psc.Subscribe("channel")
+	psc.Receive()
+
+	received := make(chan interface{})
+	go func() {
+		received <- psc.Receive()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	conn.Publish("channel", "data")
+
+	select {
+	case msg := <-received:
+		if m, ok := msg.(redis.Message); !ok || string(m.Data) != "data" {
+			t.Errorf("Expected published message and got '%#v'", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive was not unblocked by the published message")
+	}
+}
+
+func TestSubscriptionFeedClose(t *testing.T) {
+	conn := NewConn()
+	conn.SubscriptionFeed()
+
+	errs := make(chan error)
+	go func() {
+		_, err := conn.Receive()
+		errs <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	conn.Close()
+
+	select {
+	case err := <-errs:
+		if err != ErrConnClosed {
+			t.Errorf("Expected closed connection error and got '%v'", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive was not unblocked by Close")
+	}
+
+	if _, err := conn.Receive(); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got '%v'", err)
+	}
+}
+
+func TestSubscriptionFeedTimeout(t *testing.T) {
+	conn := NewConn()
+	conn.SubscriptionFeed()
+
+	_, err := conn.ReceiveWithTimeout(10 * time.Millisecond)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Expected timeout error and got '%v'", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := conn.ReceiveContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context error and got '%v'", err)
+	}
+
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPubSubPing(t *testing.T) {
+	conn := NewConn()
+	psc := redis.PubSubConn{Conn: conn}
+
+	psc.Subscribe("channel")
+	psc.Receive()
+
+	if err := psc.Ping("health"); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := psc.Receive(); msg != (redis.Pong{Data: "health"}) {
+		t.Errorf("Expected pong message and got '%#v'", msg)
+	}
+
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPubSubPingNotSubscribed(t *testing.T) {
+	conn := NewConn()
+
+	if _, err := conn.Do("PING"); err == nil {
+		t.Error("Should not handle PING commands when not subscribed")
+	}
+}
+
+func TestPubSubDropPongs(t *testing.T) {
+	conn := NewConn()
+	conn.SubscriptionFeed()
+	psc := redis.PubSubConn{Conn: conn}
+
+	psc.Subscribe("channel")
+	psc.Receive()
+
+	conn.DropPongs(1)
+	psc.Ping("first")
+
+	err, ok := psc.ReceiveWithTimeout(10 * time.Millisecond).(net.Error)
+	if !ok || !err.Timeout() {
+		t.Errorf("Expected timeout error and got '%v'", err)
+	}
+
+	psc.Ping("second")
+	if msg := psc.Receive(); msg != (redis.Pong{Data: "second"}) {
+		t.Errorf("Expected pong message and got '%#v'", msg)
+	}
+}
@@ -0,0 +1,460 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Recording is the traffic of a connection captured by a Recorder, which can
+// be replayed by a mocked connection (see Conn.Replay)
+type Recording struct {
+	Commands []RecordedCommand // Commands in the order they were sent
+	Messages []interface{}     // Messages received while subscribed, in the order they were received
+}
+
+// RecordedCommand is a command captured by a Recorder with its reply. The
+// arguments are stored as they are sent to the Redis server, and the reply
+// uses the redigo types (int64, []byte, string, []interface{} or nil)
+type RecordedCommand struct {
+	Command string   // Name of the command
+	Args    []string // Arguments of the command
+	Reply   interface{}
+	Err     error // Error returned by the command, redis.Error for error replies
+}
+
+// Recorder wraps a redis.Conn, capturing every command and reply that goes
+// through it. It can be used against a real Redis server to create the
+// fixtures replayed in tests
+type Recorder struct {
+	conn      redis.Conn // Wrapped connection
+	recording Recording  // Captured traffic
+	pending   []int      // Sent commands waiting for a reply, by index
+	mu        sync.Mutex // Hold while accessing the recording
+}
+
+// recordingFile is the format of the files storing recordings
+type recordingFile struct {
+	Commands []recordedCommandFile `json:"commands" yaml:"commands"`
+	Messages []interface{}         `json:"messages,omitempty" yaml:"messages,omitempty"`
+}
+
+// recordedCommandFile is the format of a recorded command in the files.
+// Arguments with binary data are stored base64 encoded in args64. Replies are
+// stored as an object with a single field that identifies its type: nil,
+// integer, bulk, bulk64 (base64 encoded binary data), status, error or array
+type recordedCommandFile struct {
+	Command string      `json:"command" yaml:"command"`
+	Args    []string    `json:"args,omitempty" yaml:"args,omitempty"`
+	Args64  []string    `json:"args64,omitempty" yaml:"args64,omitempty"`
+	Reply   interface{} `json:"reply,omitempty" yaml:"reply,omitempty"`
+	Failure string      `json:"failure,omitempty" yaml:"failure,omitempty"`
+}
+
+// NewRecorder returns a recorder wrapping the connection
+func NewRecorder(conn redis.Conn) *Recorder {
+	return &Recorder{conn: conn}
+}
+
+// Do executes the command in the wrapped connection, recording it with its
+// reply
+func (r *Recorder) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return r.do(r.conn.Do, commandName, args)
+}
+
+// DoWithTimeout executes the command in the wrapped connection with the
+// timeout, recording it with its reply. The wrapped connection must support
+// timeouts (see redis.ConnWithTimeout)
+func (r *Recorder) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	return r.do(func(commandName string, args ...interface{}) (interface{}, error) {
+		return redis.DoWithTimeout(r.conn, timeout, commandName, args...)
+	}, commandName, args)
+}
+
+// DoContext executes the command in the wrapped connection with the context,
+// recording it with its reply. The wrapped connection must support contexts
+// (see redis.ConnWithContext)
+func (r *Recorder) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	return r.do(func(commandName string, args ...interface{}) (interface{}, error) {
+		return redis.DoContext(r.conn, ctx, commandName, args...)
+	}, commandName, args)
+}
+
+// do executes the command with the given Do method of the wrapped connection.
+// redigo discards the replies of the sent commands when executing a command,
+// so they are received first to be recorded, returning the first error reply
+// among them like redigo does
+func (r *Recorder) do(do func(string, ...interface{}) (interface{}, error), commandName string, args []interface{}) (interface{}, error) {
+	r.mu.Lock()
+	pending := len(r.pending)
+	r.mu.Unlock()
+
+	var pendingErr error
+	if commandName != "" && pending > 0 {
+		replies, err := do("")
+		if replies, err = r.recordDo("", nil, replies, err); err != nil {
+			return nil, err
+		}
+
+		values, _ := replies.([]interface{})
+		for _, value := range values {
+			if replyErr, ok := value.(redis.Error); ok {
+				pendingErr = replyErr
+				break
+			}
+		}
+	}
+
+	reply, err := do(commandName, args...)
+	reply, err = r.recordDo(commandName, args, reply, err)
+	if err == nil {
+		err = pendingErr
+	}
+	return reply, err
+}
+
+// recordDo records the command executed by Do with its reply, or the replies
+// of the sent commands when the command name is empty
+func (r *Recorder) recordDo(commandName string, args []interface{}, reply interface{}, err error) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if commandName == "" {
+		// the replies of the sent commands are returned together
+		replies, _ := reply.([]interface{})
+		for i, index := range r.pending {
+			command := &r.recording.Commands[index]
+			switch {
+			case err != nil:
+				command.Err = err
+			case i < len(replies):
+				command.Reply, command.Err = recordedReply(replies[i])
+			}
+		}
+		r.pending = nil
+		return reply, err
+	}
+
+	command := newRecordedCommand(commandName, args)
+	command.Reply, command.Err = reply, err
+	r.recording.Commands = append(r.recording.Commands, command)
+	return reply, err
+}
+
+// Send writes the command in the wrapped connection, recording it. The reply
+// is recorded when received
+func (r *Recorder) Send(commandName string, args ...interface{}) error {
+	if err := r.conn.Send(commandName, args...); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recording.Commands = append(r.recording.Commands, newRecordedCommand(commandName, args))
+
+	// subscription commands are confirmed by messages, handled by the mocked
+	// connection when replayed
+	if !isSubscriptionCommand(commandName) {
+		r.pending = append(r.pending, len(r.recording.Commands)-1)
+	}
+	return nil
+}
+
+// Flush flushes the wrapped connection
+func (r *Recorder) Flush() error {
+	return r.conn.Flush()
+}
+
+// Receive reads a reply from the wrapped connection, recording it as the
+// reply of the oldest sent command. When there's no sent command waiting for
+// a reply, it is recorded as a subscription message
+func (r *Recorder) Receive() (interface{}, error) {
+	reply, err := r.conn.Receive()
+	return r.recordReceive(reply, err)
+}
+
+// ReceiveWithTimeout reads a reply from the wrapped connection with the
+// timeout, recording it like Receive. The wrapped connection must support
+// timeouts (see redis.ConnWithTimeout)
+func (r *Recorder) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	reply, err := redis.ReceiveWithTimeout(r.conn, timeout)
+	return r.recordReceive(reply, err)
+}
+
+// ReceiveContext reads a reply from the wrapped connection with the context,
+// recording it like Receive. The wrapped connection must support contexts
+// (see redis.ConnWithContext)
+func (r *Recorder) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := redis.ReceiveContext(r.conn, ctx)
+	return r.recordReceive(reply, err)
+}
+
+// recordReceive records the received reply as the reply of the oldest sent
+// command or as a subscription message
+func (r *Recorder) recordReceive(reply interface{}, err error) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) > 0 {
+		command := &r.recording.Commands[r.pending[0]]
+		r.pending = r.pending[1:]
+
+		command.Reply, command.Err = reply, err
+		return reply, err
+	}
+
+	if err == nil && !isSubscriptionConfirmation(reply) {
+		r.recording.Messages = append(r.recording.Messages, reply)
+	}
+	return reply, err
+}
+
+// Close closes the wrapped connection
+func (r *Recorder) Close() error {
+	return r.conn.Close()
+}
+
+// Err returns the error of the wrapped connection
+func (r *Recorder) Err() error {
+	return r.conn.Err()
+}
+
+// Recording returns the traffic captured until now
+func (r *Recorder) Recording() *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recording := &Recording{
+		Commands: make([]RecordedCommand, len(r.recording.Commands)),
+		Messages: make([]interface{}, len(r.recording.Messages)),
+	}
+	copy(recording.Commands, r.recording.Commands)
+	copy(recording.Messages, r.recording.Messages)
+	return recording
+}
+
+// Save stores the traffic captured until now in a YAML or JSON file,
+// selected by the file extension
+func (r *Recorder) Save(path string) error {
+	return r.Recording().Save(path)
+}
+
+// Save stores the recording in a YAML or JSON file, selected by the file
+// extension
+func (rec *Recording) Save(path string) error {
+	var file recordingFile
+	for _, command := range rec.Commands {
+		entry := recordedCommandFile{
+			Command: command.Command,
+			Args:    command.Args,
+			Reply:   encodeReply(command.Reply),
+		}
+
+		for _, arg := range command.Args {
+			if !utf8.ValidString(arg) {
+				entry.Args, entry.Args64 = nil, make([]string, len(command.Args))
+				for i, arg := range command.Args {
+					entry.Args64[i] = base64.StdEncoding.EncodeToString([]byte(arg))
+				}
+				break
+			}
+		}
+
+		var redisErr redis.Error
+		if errors.As(command.Err, &redisErr) {
+			entry.Reply = encodeReply(redisErr)
+		} else if command.Err != nil {
+			entry.Reply = nil
+			entry.Failure = command.Err.Error()
+		}
+		file.Commands = append(file.Commands, entry)
+	}
+
+	for _, message := range rec.Messages {
+		file.Messages = append(file.Messages, encodeReply(message))
+	}
+	return writeDataFile(path, file)
+}
+
+// LoadRecording reads a recording stored by Recorder.Save
+func LoadRecording(path string) (*Recording, error) {
+	var file recordingFile
+	if err := readExactDataFile(path, &file); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %s", path, err)
+	}
+
+	rec := &Recording{}
+	for i, entry := range file.Commands {
+		command := RecordedCommand{
+			Command: entry.Command,
+			Args:    entry.Args,
+		}
+
+		for _, arg := range entry.Args64 {
+			data, err := base64.StdEncoding.DecodeString(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recording %s: command %d: invalid argument '%s'", path, i, arg)
+			}
+			command.Args = append(command.Args, string(data))
+		}
+
+		if entry.Reply != nil {
+			reply, err := decodeReply(entry.Reply)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recording %s: command %d: %s", path, i, err)
+			}
+			command.Reply, command.Err = recordedReply(reply)
+		}
+		if entry.Failure != "" {
+			command.Err = errors.New(entry.Failure)
+		}
+		rec.Commands = append(rec.Commands, command)
+	}
+
+	for i, message := range file.Messages {
+		reply, err := decodeReply(message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording %s: message %d: %s", path, i, err)
+		}
+		rec.Messages = append(rec.Messages, reply)
+	}
+	return rec, nil
+}
+
+// newRecordedCommand returns the command with the arguments as they are sent
+// to the Redis server
+func newRecordedCommand(commandName string, args []interface{}) RecordedCommand {
+	command := RecordedCommand{Command: commandName}
+	for _, arg := range args {
+		command.Args = append(command.Args, string(formatArg(arg)))
+	}
+	return command
+}
+
+// recordedReply splits the error replies from the other replies
+func recordedReply(reply interface{}) (interface{}, error) {
+	if err, ok := reply.(redis.Error); ok {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// isSubscriptionCommand checks if the command changes the subscriptions of
+// the connection
+func isSubscriptionCommand(commandName string) bool {
+	switch strings.ToLower(commandName) {
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe":
+		return true
+	}
+	return false
+}
+
+// isSubscriptionConfirmation checks if the reply confirms a subscription
+// change
+func isSubscriptionConfirmation(reply interface{}) bool {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) == 0 {
+		return false
+	}
+
+	kind, ok := values[0].([]byte)
+	return ok && isSubscriptionCommand(string(kind))
+}
+
+// encodeReply converts the reply to the format stored in recording files
+func encodeReply(reply interface{}) interface{} {
+	switch value := reply.(type) {
+	case nil:
+		return map[string]interface{}{"nil": true}
+	case int64:
+		return map[string]interface{}{"integer": value}
+	case []byte:
+		if !utf8.Valid(value) {
+			return map[string]interface{}{"bulk64": base64.StdEncoding.EncodeToString(value)}
+		}
+		return map[string]interface{}{"bulk": string(value)}
+	case string:
+		return map[string]interface{}{"status": value}
+	case redis.Error:
+		return map[string]interface{}{"error": value.Error()}
+	case []interface{}:
+		values := make([]interface{}, len(value))
+		for i, v := range value {
+			values[i] = encodeReply(v)
+		}
+		return map[string]interface{}{"array": values}
+	}
+	return map[string]interface{}{"status": fmt.Sprint(reply)}
+}
+
+// decodeReply converts a reply stored in recording files to the redigo types
+func decodeReply(data interface{}) (interface{}, error) {
+	fields, ok := data.(map[string]interface{})
+	if !ok || len(fields) != 1 {
+		return nil, fmt.Errorf("invalid reply %v", data)
+	}
+
+	for kind, value := range fields {
+		switch kind {
+		case "nil":
+			return nil, nil
+		case "integer":
+			switch n := value.(type) {
+			case int:
+				return int64(n), nil
+			case int64:
+				return n, nil
+			case json.Number:
+				if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+					return i, nil
+				}
+			}
+		case "bulk":
+			if s, ok := value.(string); ok {
+				return []byte(s), nil
+			}
+		case "bulk64":
+			if s, ok := value.(string); ok {
+				return base64.StdEncoding.DecodeString(s)
+			}
+		case "status":
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+		case "error":
+			if s, ok := value.(string); ok {
+				return redis.Error(s), nil
+			}
+		case "array":
+			items, ok := value.([]interface{})
+			if !ok {
+				break
+			}
+
+			values := make([]interface{}, len(items))
+			for i, item := range items {
+				v, err := decodeReply(item)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return values, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid reply %v", data)
+}
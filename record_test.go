@@ -0,0 +1,189 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	_ redis.ConnWithTimeout = &Recorder{}
+	_ redis.ConnWithContext = &Recorder{}
+)
+
+// recordTraffic records commands executed against a connection using a key
+// space, including pipelines, error replies and subscription messages
+func recordTraffic(t *testing.T) *Recording {
+	t.Helper()
+
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+	conn.Command("PING").ExpectError(errors.New("connection reset"))
+
+	recorder := NewRecorder(conn)
+	recorder.Do("SET", "counter", 10)
+	recorder.Do("INCR", "counter")
+	recorder.Do("INCR", "counter")
+	recorder.Do("HGET", "counter", "field")
+	recorder.Do("GET", "missing")
+	recorder.Do("SET", "binary", []byte{0xff, 0xfe})
+	recorder.Do("SET", "big", int64(1)<<62)
+	recorder.Do("INCR", "big")
+	recorder.Do("PING")
+
+	recorder.Send("RPUSH", "queue", "a", "b")
+	recorder.Send("LRANGE", "queue", 0, -1)
+	recorder.Do("")
+
+	recorder.Send("GET", "counter")
+	recorder.Flush()
+	recorder.Receive()
+
+	recorder.Send("SUBSCRIBE", "news")
+	recorder.Flush()
+	recorder.Receive()
+	conn.Publish("news", "hello")
+	recorder.Receive()
+
+	return recorder.Recording()
+}
+
+func TestRecorder(t *testing.T) {
+	recording := recordTraffic(t)
+
+	expected := []RecordedCommand{
+		{Command: "SET", Args: []string{"counter", "10"}, Reply: "OK"},
+		{Command: "INCR", Args: []string{"counter"}, Reply: int64(11)},
+		{Command: "INCR", Args: []string{"counter"}, Reply: int64(12)},
+		{Command: "HGET", Args: []string{"counter", "field"}, Err: WrongTypeError()},
+		{Command: "GET", Args: []string{"missing"}},
+		{Command: "SET", Args: []string{"binary", "\xff\xfe"}, Reply: "OK"},
+		{Command: "SET", Args: []string{"big", "4611686018427387904"}, Reply: "OK"},
+		{Command: "INCR", Args: []string{"big"}, Reply: int64(4611686018427387905)},
+		{Command: "PING", Err: errors.New("connection reset")},
+		{Command: "RPUSH", Args: []string{"queue", "a", "b"}, Reply: int64(2)},
+		{Command: "LRANGE", Args: []string{"queue", "0", "-1"}, Reply: bulks("a", "b")},
+		{Command: "GET", Args: []string{"counter"}, Reply: []byte("12")},
+		{Command: "SUBSCRIBE", Args: []string{"news"}},
+	}
+
+	if !reflect.DeepEqual(recording.Commands, expected) {
+		t.Errorf("Expected commands %#v and got %#v", expected, recording.Commands)
+	}
+
+	messages := []interface{}{bulks("message", "news", "hello")}
+	if !reflect.DeepEqual(recording.Messages, messages) {
+		t.Errorf("Expected messages %#v and got %#v", messages, recording.Messages)
+	}
+}
+
+func TestRecorderTimeoutAndContext(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	recorder := NewRecorder(conn)
+	if _, err := redis.DoWithTimeout(recorder, time.Second, "SET", "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := redis.DoContext(recorder, context.Background(), "GET", "k"); err != nil {
+		t.Fatal(err)
+	}
+
+	psc := redis.PubSubConn{Conn: recorder}
+	psc.Subscribe("news")
+	if _, err := recorder.ReceiveContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	conn.Publish("news", "hello")
+	if _, ok := psc.ReceiveWithTimeout(time.Second).(redis.Message); !ok {
+		t.Fatal("Expected message")
+	}
+	if _, ok := psc.ReceiveWithTimeout(time.Millisecond).(error); !ok {
+		t.Error("Expected timeout error without messages")
+	}
+
+	recording := recorder.Recording()
+	expected := []RecordedCommand{
+		{Command: "SET", Args: []string{"k", "v"}, Reply: "OK"},
+		{Command: "GET", Args: []string{"k"}, Reply: []byte("v")},
+		{Command: "SUBSCRIBE", Args: []string{"news"}},
+	}
+	if !reflect.DeepEqual(recording.Commands, expected) {
+		t.Errorf("Expected commands %#v and got %#v", expected, recording.Commands)
+	}
+
+	messages := []interface{}{bulks("message", "news", "hello")}
+	if !reflect.DeepEqual(recording.Messages, messages) {
+		t.Errorf("Expected messages %#v and got %#v", messages, recording.Messages)
+	}
+}
+
+func TestRecorderSendBeforeDo(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	recorder := NewRecorder(conn)
+	recorder.Send("SET", "k", "v")
+	recorder.Send("HGET", "k", "field")
+	if reply, err := recorder.Do("GET", "k"); err != WrongTypeError() || !reflect.DeepEqual(reply, []byte("v")) {
+		t.Errorf("Expected 'v' with the error of the sent command and got %#v (%v)", reply, err)
+	}
+
+	expected := []RecordedCommand{
+		{Command: "SET", Args: []string{"k", "v"}, Reply: "OK"},
+		{Command: "HGET", Args: []string{"k", "field"}, Err: WrongTypeError()},
+		{Command: "GET", Args: []string{"k"}, Reply: []byte("v")},
+	}
+	if commands := recorder.Recording().Commands; !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected commands %#v and got %#v", expected, commands)
+	}
+}
+
+func TestRecordingSaveAndLoad(t *testing.T) {
+	recording := recordTraffic(t)
+
+	for _, name := range []string{"recording.yaml", "recording.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := recording.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadRecording(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(loaded, recording) {
+			t.Errorf("%s: expected %#v and got %#v", name, recording, loaded)
+		}
+	}
+}
+
+func TestLoadRecordingInvalid(t *testing.T) {
+	if _, err := LoadRecording(filepath.Join("testdata", "fixture.yaml")); err == nil {
+		t.Error("Expected error for a file that isn't a recording")
+	}
+
+	if _, err := LoadRecording(filepath.Join("testdata", "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestRecordedReply(t *testing.T) {
+	if reply, err := recordedReply(redis.Error("ERR failure")); reply != nil || err != redis.Error("ERR failure") {
+		t.Errorf("Expected error reply and got %#v (%v)", reply, err)
+	}
+
+	if reply, err := recordedReply(int64(1)); reply != int64(1) || err != nil {
+		t.Errorf("Expected integer reply and got %#v (%v)", reply, err)
+	}
+}
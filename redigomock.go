@@ -6,13 +6,24 @@ package redigomock
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/gomodule/redigo/redis"
 )
 
+// ErrConnClosed is returned when using a connection that was closed, with the
+// same message of the connections dialed by redigo (the connections of
+// redis.Pool use "redigo: connection closed" instead)
+var ErrConnClosed = errors.New("redigo: closed")
+
+// errNoReply is used internally when an executed command doesn't produce a
+// reply, like a dropped pong
+var errNoReply = errors.New("no reply")
+
 type queueElement struct {
 	commandName string
 	args        []interface{}
@@ -29,19 +40,48 @@ type replyElement struct {
 // The fields of Conn should not be modified after first use.  (Sending to
 // ReceiveNow is safe.)
 type Conn struct {
-	ReceiveWait        bool            // When set to true, Receive method will wait for a value in ReceiveNow channel to proceed, this is useful in a PubSub scenario
-	ReceiveNow         chan bool       // Used to lock Receive method to simulate a PubSub scenario
-	CloseMock          func() error    // Mock the redigo Close method
-	ErrMock            func() error    // Mock the redigo Err method
-	FlushMock          func() error    // Mock the redigo Flush method
-	FlushSkippableMock func() error    // Mock the redigo Flush method, will be ignore if return with a nil.
-	commands           []*Cmd          // Slice that stores all registered commands for each connection
-	queue              []queueElement  // Slice that stores all queued commands for each connection
-	replies            []replyElement  // Slice that stores all queued replies
-	subResponses       []response      // Queue responses for PubSub
-	stats              map[cmdHash]int // Command calls counter
-	errors             []error         // Storage of all error occured in do functions
-	mu                 sync.RWMutex    // Hold while accessing any mutable fields
+	ReceiveWait        bool                   // When set to true, Receive method will wait for a value in ReceiveNow channel to proceed, this is useful in a PubSub scenario
+	RequireScriptLoad  bool                   // When set to true, EVALSHA commands fail with NOSCRIPT until the script is loaded with SCRIPT LOAD or EVAL
+	ResponsePolicy     ResponsePolicy         // What commands without their own policy return once all their responses were returned, repeating the last one by default (see Cmd.Policy)
+	ValidateCommands   bool                   // When set to true, registered and executed commands are checked against the Redis command table, reporting the violations as errors
+	ReceiveNow         chan bool              // Used to lock Receive method to simulate a PubSub scenario
+	CloseMock          func() error           // Mock the redigo Close method
+	ErrMock            func() error           // Mock the redigo Err method
+	FlushMock          func() error           // Mock the redigo Flush method
+	FlushSkippableMock func() error           // Mock the redigo Flush method, will be ignore if return with a nil.
+	commands           []*Cmd                 // Slice that stores all registered commands for each connection
+	queue              []queueElement         // Slice that stores all queued commands for each connection
+	replies            []replyElement         // Slice that stores all queued replies
+	subResponses       []response             // Queue responses for PubSub
+	pushes             []replyElement         // Extra replies generated by the last executed command
+	channels           map[string]struct{}    // Channels subscribed by the connection
+	patterns           map[string]struct{}    // Patterns subscribed by the connection
+	feed               chan interface{}       // Messages sent by the test to be returned by Receive
+	wakeup             chan struct{}          // Wakes up Receive calls blocked waiting for the feed
+	done               chan struct{}          // Closed when the connection is closed or broken
+	err                error                  // Fatal error, set when the connection is closed or broken
+	closeCount         int                    // Number of Close calls
+	droppedPongs       int                    // Number of pongs to drop in subscription mode
+	registry           *Conn                  // Connection with commands shared by this one
+	proto              int                    // Protocol version, switched by HELLO
+	wire               bool                   // Commands are received from the wire (see Server), so arguments are matched as sent to the Redis server
+	node               *ClusterNode           // Cluster node served by the connection (see Cluster)
+	asking             bool                   // ASKING was sent, so the next command is accepted in an importing slot
+	sentinel           *Sentinel              // Sentinel served by the connection (see Sentinel)
+	replication        *replication           // Replication role, set to simulate replicas (see ReplicaOf)
+	faults             []*Fault               // Fault injection rules
+	rng                *rand.Rand             // Random numbers used by fault injection
+	clock              *Clock                 // Fake clock for time-based behaviours
+	keySpace           *KeySpace              // Data store for commands without a registered response
+	scripts            scriptCache            // Lua scripts loaded in the connection
+	delay              time.Duration          // Delay of the executed commands, waited before returning their replies
+	unlocked           []func()               // Actions run once c.mu is released, like messages published by the executed commands
+	replays            []*replayer            // Recordings replayed by the connection (see Replay)
+	history            []CommandCall          // Commands executed by the connection, oldest first
+	store              map[string]interface{} // Values kept between calls by the handlers (see Call)
+	stats              map[cmdHash]int        // Command calls counter
+	errors             []error                // Storage of all error occured in do functions
+	mu                 sync.RWMutex           // Hold while accessing any mutable fields
 }
 
 // NewConn returns a new mocked connection. Obviously as we are mocking we
@@ -53,8 +93,15 @@ func NewConn() *Conn {
 	}
 }
 
-// Close can be mocked using the Conn struct attributes
+// Close can be mocked using the Conn struct attributes. After closing the
+// connection, all operations return ErrConnClosed and any Receive call
+// blocked waiting for the subscription feed is unblocked
 func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.closeCount++
+	c.fatal(ErrConnClosed)
+	c.mu.Unlock()
+
 	if c.CloseMock == nil {
 		return nil
 	}
@@ -62,10 +109,48 @@ func (c *Conn) Close() error {
 	return c.CloseMock()
 }
 
-// Err can be mocked using the Conn struct attributes
+// Break simulates a broken connection, like a network failure. After that,
+// Err returns the given error and all operations fail with it, so a
+// redis.Pool discards the connection instead of reusing it
+func (c *Conn) Break(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fatal(err)
+}
+
+// fatal stores the error that makes the connection unusable, unblocking any
+// Receive call. Only the first error is stored
+//
+// Caller must hold c.mu.
+func (c *Conn) fatal(err error) {
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	if c.done != nil {
+		close(c.done)
+	}
+}
+
+// CloseCount returns the number of times that the connection was closed,
+// useful to detect connection leaks
+func (c *Conn) CloseCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeCount
+}
+
+// Err can be mocked using the Conn struct attributes. When not mocked, it
+// returns a non-nil error if the connection was closed or broken
 func (c *Conn) Err() error {
 	if c.ErrMock == nil {
-		return nil
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return c.err
 	}
 
 	return c.ErrMock()
@@ -75,29 +160,19 @@ func (c *Conn) Err() error {
 // a Do or Send commands. It will return a registered command object where
 // you can set the response or error
 func (c *Conn) Command(commandName string, args ...interface{}) *Cmd {
-	cmd := &Cmd{
+	return c.addCommand(&Cmd{
 		name: commandName,
 		args: args,
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.removeRelatedCommands(commandName, args)
-	c.commands = append(c.commands, cmd)
-	return cmd
+	})
 }
 
 // Script registers a command in the mock system just like Command method
 // would do. The first argument is a byte array with the script text, next
-// ones are the ones you would pass to redis Script.Do() method
+// ones are the ones you would pass to redis Script.Do() method. The command
+// matches both the EVALSHA and the EVAL forms of the script
 func (c *Conn) Script(scriptData []byte, keyCount int, args ...interface{}) *Cmd {
-	h := sha1.New()
-	h.Write(scriptData)
-	sha1sum := hex.EncodeToString(h.Sum(nil))
-
 	newArgs := make([]interface{}, 2+len(args))
-	newArgs[0] = sha1sum
+	newArgs[0] = scriptSHA(scriptData)
 	newArgs[1] = keyCount
 	copy(newArgs[2:], args)
 
@@ -108,14 +183,25 @@ func (c *Conn) Script(scriptData []byte, keyCount int, args ...interface{}) *Cmd
 // arguments doesn't match with any registered command, it will look for
 // generic commands before throwing an error
 func (c *Conn) GenericCommand(commandName string) *Cmd {
-	cmd := &Cmd{
+	return c.addCommand(&Cmd{
 		name: commandName,
-	}
+	})
+}
 
+// addCommand adds the command to the connection, replacing any command
+// registered with the same name and arguments
+func (c *Conn) addCommand(cmd *Cmd) *Cmd {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.removeRelatedCommands(commandName, nil)
+	// commands without arguments are generic, matching any arguments
+	if c.validating() && len(cmd.args) > 0 {
+		if err := validateCommand(cmd.name, cmd.args); err != nil {
+			c.errors = append(c.errors, err)
+		}
+	}
+
+	c.removeRelatedCommands(cmd.name, cmd.args)
 	c.commands = append(c.commands, cmd)
 	return cmd
 }
@@ -125,14 +211,62 @@ func (c *Conn) GenericCommand(commandName string) *Cmd {
 //
 // Caller must hold c.mu.
 func (c *Conn) find(commandName string, args []interface{}) *Cmd {
-	for _, cmd := range c.commands {
-		if match(commandName, args, cmd) {
+	for _, cmd := range c.registered() {
+		if match(commandName, args, cmd) || ((c.wire || cmd.wire) && matchWire(commandName, args, cmd)) {
 			return cmd
 		}
 	}
 	return nil
 }
 
+// registered returns the commands registered in the connection, followed by
+// the ones registered in the connection that it shares the registry with (see
+// Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) registered() []*Cmd {
+	commands := c.commands
+	c.withRegistry(func(registry *Conn) {
+		commands = make([]*Cmd, 0, len(c.commands)+len(registry.commands))
+		commands = append(commands, c.commands...)
+		commands = append(commands, registry.commands...)
+	})
+	return commands
+}
+
+// withRegistry calls f with the connection that the connection shares the
+// registry with (see Pool), locked so its fields can be read or initialized.
+// If the connection doesn't share a registry f isn't called
+//
+// Caller must hold c.mu.
+func (c *Conn) withRegistry(f func(registry *Conn)) {
+	if c.registry == nil {
+		return
+	}
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	f(c.registry)
+}
+
+// fromRegistry returns the value of the connection, falling back to the value
+// of the connection that it shares the registry with (see Pool) when it's the
+// zero value
+//
+// Caller must hold c.mu.
+func fromRegistry[T comparable](c *Conn, value func(conn *Conn) T) T {
+	v := value(c)
+
+	var zero T
+	if v == zero {
+		c.withRegistry(func(registry *Conn) {
+			v = value(registry)
+		})
+	}
+	return v
+}
+
 // removeRelatedCommands verify if a command is already registered, removing
 // any command already registered with the same name and arguments. This
 // should avoid duplicated mocked commands.
@@ -151,8 +285,9 @@ func (c *Conn) removeRelatedCommands(commandName string, args []interface{}) {
 	c.commands = unique
 }
 
-// Clear removes all registered commands. Useful for connection reuse in test
-// scenarios
+// Clear removes all registered commands and the keys of the connection key
+// space. Useful for connection reuse in test scenarios, as it also reopens a
+// closed or broken connection
 func (c *Conn) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -162,101 +297,410 @@ func (c *Conn) Clear() {
 	c.replies = []replyElement{}
 	c.stats = make(map[cmdHash]int)
 	c.errors = []error{}
+	c.pushes = nil
+	c.channels = nil
+	c.patterns = nil
+	c.droppedPongs = 0
+	c.err = nil
+	c.done = nil
+	c.closeCount = 0
+	c.faults = nil
+	c.scripts = nil
+	c.proto = 0
+	c.asking = false
+	c.replication = nil
+	c.history = nil
+	c.store = nil
+	c.replays = nil
+
+	if c.keySpace != nil {
+		c.keySpace.flush()
+	}
 }
 
 // Do looks in the registered commands (via Command function) if someone
 // matches with the given command name and arguments, if so the corresponding
 // response or error is returned. If no registered command is found an error
 // is returned
+//
+// As in redigo, any pending reply from previous Send calls is consumed before
+// the command is executed, and the first error found among them is returned
+// together with the command reply. When the command name is empty, all
+// pending replies are returned in a slice, with error replies converted to
+// redis.Error values
 func (c *Conn) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
+	return c.doContext(context.Background(), 0, commandName, args...)
+}
+
+// doContext executes the command like Do, waiting for the delay of the
+// executed commands (see Cmd.Delay) until the context is done or the timeout
+// expires
+func (c *Conn) doContext(ctx context.Context, timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := c.doPending(commandName, args...)
+	if waitErr := c.wait(ctx, timeout); waitErr != nil {
+		return nil, waitErr
+	}
+	return reply, err
+}
+
+// doPending consumes the pending replies and executes the command
+func (c *Conn) doPending(commandName string, args ...interface{}) (reply interface{}, err error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 
-	if commandName == "" {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	// as in redigo, the connection is only flushed when there are sent
+	// commands, or when Do is called without a command to flush them
+	if commandName == "" || len(c.queue) != 0 || len(c.replies) != 0 {
 		if err := c.flush(); err != nil {
 			return nil, err
 		}
+	}
+
+	// flush could be mocked, so we make sure that all sent commands have a
+	// reply before consuming them
+	c.flushQueue()
+	if c.err != nil {
+		// a sent command broke the connection
+		c.replies = []replyElement{}
+		return nil, c.err
+	}
+
+	pending := c.replies
+	c.replies = []replyElement{}
 
-		if len(c.replies) == 0 {
+	if commandName == "" {
+		if len(pending) == 0 {
+			// redigo returns nil, not an empty slice, without sent commands
 			return nil, nil
 		}
 
-		replies := []interface{}{}
-		for _, v := range c.replies {
+		replies := make([]interface{}, len(pending))
+		for i, v := range pending {
 			if v.err != nil {
-				return nil, v.err
+				replies[i] = replyError(v.err)
+				continue
 			}
-			replies = append(replies, v.reply)
+			replies[i] = v.reply
 		}
-		c.replies = []replyElement{}
 		return replies, nil
 	}
 
-	if len(c.queue) != 0 || len(c.replies) != 0 {
-		if err := c.flush(); err != nil {
-			return nil, err
-		}
-		for _, v := range c.replies {
-			if v.err != nil {
-				return nil, v.err
-			}
+	for _, v := range pending {
+		if v.err != nil && err == nil {
+			err = v.err
 		}
-		c.replies = []replyElement{}
 	}
 
-	return c.do(commandName, args...)
+	reply, doErr := c.do(commandName, args...)
+	c.queuePushes()
+	if doErr == errNoReply {
+		return nil, timeoutError{}
+	}
+	if doErr != nil && err == nil {
+		// the first error among the pending replies takes precedence
+		err = doErr
+	}
+	return reply, err
+}
+
+// unlock releases c.mu, then runs the actions that must happen without
+// holding it (see runUnlocked)
+func (c *Conn) unlock() {
+	actions := c.unlocked
+	c.unlocked = nil
+	c.mu.Unlock()
+
+	for _, action := range actions {
+		action()
+	}
+}
+
+// runUnlocked delays the action until c.mu is released, for actions that lock
+// other connections, like delivering published messages
+//
+// Caller must hold c.mu.
+func (c *Conn) runUnlocked(action func()) {
+	c.unlocked = append(c.unlocked, action)
+}
+
+// replyError converts an error into the type used by redigo for error
+// replies, so it can be stored among other replies
+func replyError(err error) redis.Error {
+	if redisErr, ok := err.(redis.Error); ok {
+		return redisErr
+	}
+	return redis.Error(err.Error())
 }
 
+// do executes the command, returning the reply with the types decoded by
+// redigo. If the command doesn't produce a reply errNoReply is returned
+//
 // Caller must hold c.mu.
 func (c *Conn) do(commandName string, args ...interface{}) (reply interface{}, err error) {
-	cmd := c.find(commandName, args)
-	if cmd == nil {
-		// Didn't find a specific command, try to get a generic one
-		if cmd = c.find(commandName, nil); cmd == nil {
-			var msg string
-			for _, regCmd := range c.commands {
-				if commandName == regCmd.name {
-					if len(msg) == 0 {
-						msg = ". Possible matches are with the arguments:"
-					}
-					msg += fmt.Sprintf("\n* %#v", regCmd.args)
-				}
-			}
+	r, ok := c.run(commandName, args)
+	if !ok {
+		return nil, errNoReply
+	}
+	return redigoReply(r.reply), r.err
+}
+
+// run executes the command, injecting a registered fault when needed, and
+// records it in the history. The reply keeps the RESP3 types, converted by
+// the callers for redigo or for the wire (see Server). If the command doesn't
+// produce a reply, like when it's dropped by a fault, ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) run(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	fault := c.injectFault(commandName, args)
+	if fault != nil && fault.err != nil {
+		if _, ok := fault.err.(redis.Error); !ok {
+			// failures that aren't error replies break the connection, like
+			// in redigo
+			c.fatal(fault.err)
+		}
+		return replyElement{err: fault.err}, true
+	}
+
+	reply.reply, reply.err = c.exec(commandName, args...)
+	c.recordCall(commandName, args, reply.reply, reply.err)
+	if fault != nil || reply.err == errNoReply {
+		c.pushes = nil
+		return replyElement{}, false
+	}
+	return reply, true
+}
 
-			err := fmt.Errorf("command %s with arguments %#v not registered in redigomock library%s",
-				commandName, args, msg)
+// exec looks for the registered command, returning its response
+//
+// Caller must hold c.mu.
+func (c *Conn) exec(commandName string, args ...interface{}) (reply interface{}, err error) {
+	if c.validating() {
+		if err := validateCommand(commandName, args); err != nil {
 			c.errors = append(c.errors, err)
 			return nil, err
 		}
 	}
 
+	if reply, ok := c.redirect(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if reply, ok := c.readOnly(commandName); ok {
+		return reply.reply, reply.err
+	}
+	defer func() {
+		if err == nil {
+			c.countWrite(commandName)
+		}
+	}()
+
+	cmd := c.find(commandName, args)
+	if cmd == nil {
+		cmd = c.findEval(commandName, args)
+	}
+	if cmd != nil {
+		if reply, ok := c.callCommand(cmd, commandName, args); ok {
+			return reply.reply, reply.err
+		}
+	}
+
+	// Didn't find a specific command, try to get a generic one
+	if generic := c.find(commandName, nil); generic != nil && generic != cmd {
+		if reply, ok := c.callCommand(generic, commandName, args); ok {
+			return reply.reply, reply.err
+		}
+	}
+
+	if replies, ok := c.pubSub(commandName, args); ok {
+		if len(replies) == 0 {
+			return nil, errNoReply
+		}
+		c.pushes = append(c.pushes, replies[1:]...)
+		return replies[0].reply, replies[0].err
+	}
+
+	if reply, ok := c.scriptCommand(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if reply, ok := c.hello(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if reply, ok := c.clusterCommand(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if reply, ok := c.sentinelCommand(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if reply, ok := c.replicationCommand(commandName, args); ok {
+		return reply.reply, reply.err
+	}
+
+	if ks := c.currentKeySpace(); ks != nil {
+		if reply, ok := ks.exec(commandName, args, c.currentClock().Now(), c.isReplica()); ok {
+			return reply.reply, reply.err
+		}
+	}
+
+	var msg string
+	for _, regCmd := range c.registered() {
+		if commandName == regCmd.name {
+			if len(msg) == 0 {
+				msg = ". Possible matches are with the arguments:"
+			}
+			msg += fmt.Sprintf("\n* %#v", regCmd.args)
+		}
+	}
+
+	err = fmt.Errorf("command %s with arguments %#v not registered in redigomock library%s",
+		commandName, args, msg)
+	if cmd != nil {
+		// the command was registered, but all its responses were returned
+		err = exhaustedError(commandName, args)
+	}
+	c.errors = append(c.errors, err)
+	return nil, err
+}
+
+// callCommand returns the next response of the registered command. It isn't
+// ok when all the responses of the command were returned and the call falls
+// through to the next matching command (see FallThrough)
+//
+// Caller must hold c.mu.
+func (c *Conn) callCommand(cmd *Cmd, commandName string, args []interface{}) (reply replyElement, ok bool) {
+	if !c.scriptLoaded(commandName, args) {
+		return replyElement{err: NoScriptError()}, true
+	}
+
+	policy := cmd.responsePolicy(c.responsePolicy())
+	response, index, exceeded, exhausted := cmd.call(policy)
+	if exhausted && policy == FallThrough {
+		return replyElement{}, false
+	}
+
 	c.stats[cmd.hash()]++
+	c.delay += cmd.getDelay()
 
-	response := cmd.getResponse()
+	if exceeded {
+		times, _, _ := cmd.expectedCalls()
+		// reported by ExpectationsWereMet, as the number of calls doesn't match
+		return replyElement{err: fmt.Errorf("command %s with arguments %#v called more than %d time(s)", commandName, args, times)}, true
+	}
+	if exhausted {
+		err := exhaustedError(commandName, args)
+		c.errors = append(c.errors, err)
+		return replyElement{err: err}, true
+	}
 	if response == nil {
-		return nil, nil
+		return replyElement{}, true
 	}
 
 	if response.panicVal != nil {
 		panic(response.panicVal)
 	}
 
-	if handler, ok := response.response.(ResponseHandler); ok {
-		return handler(args)
+	switch handler := response.response.(type) {
+	case ResponseHandler:
+		reply.reply, reply.err = handler(args)
+		return reply, true
+	case CallHandler:
+		reply.reply, reply.err = handler(c.newCall(commandName, args, index))
+		return reply, true
+	}
+	return replyElement{response.response, response.err}, true
+}
+
+// Clock returns the fake clock used by the connection for time-based
+// behaviours, like fault injection windows
+func (c *Conn) Clock() *Clock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fakeClock()
+}
+
+// SetClock replaces the fake clock of the connection, allowing many
+// connections to share the same clock
+func (c *Conn) SetClock(clock *Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock = clock
+}
+
+// fakeClock returns the connection clock, creating it when needed
+//
+// Caller must hold c.mu.
+func (c *Conn) fakeClock() *Clock {
+	if c.clock == nil {
+		c.clock = NewClock(time.Now())
 	}
-	return response.response, response.err
+	return c.clock
+}
+
+// currentClock returns the clock of the connection, falling back to the one of
+// the connection that it shares the registry with (see Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) currentClock() *Clock {
+	return fromRegistry(c, func(conn *Conn) *Clock {
+		// the clock is created in the registry, shared by its connections
+		if conn == c && c.registry != nil {
+			return conn.clock
+		}
+		return conn.fakeClock()
+	})
 }
 
 // DoWithTimeout is a helper function for Do call to satisfy the ConnWithTimeout
 // interface.
 func (c *Conn) DoWithTimeout(readTimeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
-	return c.Do(cmd, args...)
+	return c.doContext(context.Background(), readTimeout, cmd, args...)
 }
 
 // DoContext is a helper function for Do call to satisfy the ConnWithContext
 // interface.
 func (c *Conn) DoContext(ctx context.Context, cmd string, args ...interface{}) (reply interface{}, err error) {
-	return c.Do(cmd, args...)
+	return c.doContext(ctx, 0, cmd, args...)
+}
+
+// wait blocks for the delay of the executed commands (see Cmd.Delay),
+// returning an error if the context is done or the timeout expires first
+func (c *Conn) wait(ctx context.Context, timeout time.Duration) error {
+	c.mu.Lock()
+	delay := c.delay
+	c.delay = 0
+	c.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return timeoutError{}
+	}
 }
 
 // Send stores the command and arguments to be executed later (by the Receive
@@ -265,6 +709,10 @@ func (c *Conn) Send(commandName string, args ...interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.err != nil {
+		return c.err
+	}
+
 	c.queue = append(c.queue, queueElement{
 		commandName: commandName,
 		args:        args,
@@ -275,7 +723,11 @@ func (c *Conn) Send(commandName string, args ...interface{}) error {
 // Flush can be mocked using the Conn struct attributes
 func (c *Conn) Flush() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
+
+	if c.err != nil {
+		return c.err
+	}
 
 	return c.flush()
 }
@@ -291,15 +743,37 @@ func (c *Conn) flush() error {
 		}
 	}
 
-	if len(c.queue) > 0 {
-		for _, cmd := range c.queue {
-			reply, err := c.do(cmd.commandName, cmd.args...)
+	c.flushQueue()
+	return nil
+}
+
+// flushQueue executes all sent commands, storing exactly one reply for each
+// one of them in the same order that they were sent
+//
+// Caller must hold c.mu.
+func (c *Conn) flushQueue() {
+	for _, cmd := range c.queue {
+		if c.err != nil {
+			// the remaining commands are lost with the broken connection
+			break
+		}
+
+		reply, err := c.do(cmd.commandName, cmd.args...)
+		if err != errNoReply {
 			c.replies = append(c.replies, replyElement{reply: reply, err: err})
 		}
-		c.queue = []queueElement{}
+		c.queuePushes()
 	}
+	c.queue = []queueElement{}
+}
 
-	return nil
+// queuePushes moves the extra replies generated by the last executed command
+// to the pending replies, so they can be retrieved by the Receive method
+//
+// Caller must hold c.mu.
+func (c *Conn) queuePushes() {
+	c.replies = append(c.replies, c.pushes...)
+	c.pushes = nil
 }
 
 // AddSubscriptionMessage register a response to be returned by the receive
@@ -312,48 +786,158 @@ func (c *Conn) AddSubscriptionMessage(msg interface{}) {
 	defer c.mu.Unlock()
 
 	c.subResponses = append(c.subResponses, resp)
+	c.notify()
 }
 
-// Receive will process the queue created by the Send method, only one item
-// of the queue is processed by Receive call. It will work as the Do method
+// Receive returns the next pending reply of the commands stored by the Send
+// method, in the same order they were sent. Each sent command produces
+// exactly one reply, so only one item of the queue is consumed by Receive
+// call. Commands that weren't flushed yet are executed before the reply is
+// returned. When the subscription feed is in use (see SubscriptionFeed) and
+// there's no pending reply, Receive blocks until a message arrives or the
+// connection is closed
 func (c *Conn) Receive() (reply interface{}, err error) {
+	return c.receive(context.Background(), 0)
+}
+
+// ReceiveWithTimeout works like the Receive method, but when blocked waiting
+// for a message of the subscription feed it returns a timeout error after the
+// given duration. A zero timeout means no timeout.
+func (c *Conn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return c.receive(context.Background(), timeout)
+}
+
+// ReceiveContext works like the Receive method, but when blocked waiting for
+// a message of the subscription feed it returns the context error as soon as
+// the context is done.
+func (c *Conn) ReceiveContext(ctx context.Context) (reply interface{}, err error) {
+	return c.receive(ctx, 0)
+}
+
+func (c *Conn) receive(ctx context.Context, timeout time.Duration) (interface{}, error) {
 	if c.ReceiveWait {
 		<-c.ReceiveNow
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		c.mu.Lock()
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return nil, err
+		}
 
-	if len(c.queue) == 0 && len(c.replies) == 0 {
-		if len(c.subResponses) > 0 {
-			reply, err = c.subResponses[0].response, c.subResponses[0].err
-			c.subResponses = c.subResponses[1:]
-			return
+		next, ok := c.nextReply()
+		reply, err := next.reply, next.err
+		if err != nil {
+			// error replies are returned like Do returns them in a pipeline
+			err = replyError(err)
+		}
+		if !ok && c.err != nil {
+			// a sent command broke the connection
+			err := c.err
+			c.unlock()
+			return nil, err
 		}
-		return nil, fmt.Errorf("no more items")
+		if !ok && c.feed == nil {
+			err = fmt.Errorf("no more items")
+			c.errors = append(c.errors, err)
+		}
+		feed, wakeup, done := c.feed, c.wakeup, c.doneChan()
+		c.unlock()
+
+		if ok || err != nil {
+			if waitErr := c.wait(ctx, timeout); waitErr != nil {
+				return nil, waitErr
+			}
+			return reply, err
+		}
+
+		select {
+		case msg := <-feed:
+			return feedReply(msg)
+		case <-wakeup:
+			// new messages were added, check them on the next iteration
+		case <-done:
+			c.mu.Lock()
+			err := c.err
+			c.mu.Unlock()
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer:
+			return nil, timeoutError{}
+		}
+	}
+}
+
+// nextReply consumes the next pending reply, giving priority to replies of
+// sent commands over subscription messages. If there's no pending reply false
+// is returned
+//
+// Caller must hold c.mu.
+func (c *Conn) nextReply() (replyElement, bool) {
+	if len(c.replies) == 0 {
+		c.flushQueue()
 	}
 
-	if err := c.flush(); err != nil {
-		return nil, err
+	if len(c.replies) == 0 {
+		if len(c.subResponses) == 0 {
+			return replyElement{}, false
+		}
+
+		next := replyElement{reply: redigoReply(c.subResponses[0].response), err: c.subResponses[0].err}
+		c.subResponses = c.subResponses[1:]
+		return next, true
 	}
 
-	reply, err = c.replies[0].reply, c.replies[0].err
+	next := c.replies[0]
 	c.replies = c.replies[1:]
-	return
+	if next.err != nil {
+		next.reply = nil
+	}
+	return next, true
 }
 
-// ReceiveWithTimeout is a helper function for Receive call to satisfy the
-// ConnWithTimeout interface.
-func (c *Conn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
-	return c.Receive()
+// doneChan returns a channel that is closed when the connection is closed or
+// broken
+//
+// Caller must hold c.mu.
+func (c *Conn) doneChan() chan struct{} {
+	if c.done == nil {
+		c.done = make(chan struct{})
+		if c.err != nil {
+			close(c.done)
+		}
+	}
+	return c.done
 }
 
-// ReceiveContext is a helper function for Receive call to satisfy the
-// ConnWithContext interface.
-func (c *Conn) ReceiveContext(ctx context.Context) (reply interface{}, err error) {
-	return c.Receive()
+// notify wakes up a Receive call blocked waiting for the subscription feed
+//
+// Caller must hold c.mu.
+func (c *Conn) notify() {
+	select {
+	case c.wakeup <- struct{}{}:
+	default:
+	}
 }
 
+// timeoutError is returned when Receive waits longer than the requested
+// timeout. It satisfies the net.Error interface like a network timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
 // Stats returns the number of times that a command was called in the current
 // connection
 func (c *Conn) Stats(cmd *Cmd) int {
@@ -374,8 +958,20 @@ func (c *Conn) ExpectationsWereMet() error {
 		errMsg = fmt.Sprintf("%s%s\n", errMsg, err.Error())
 	}
 
+	if pending := len(c.queue) + len(c.replies); pending > 0 {
+		errMsg = fmt.Sprintf("%s%d sent command(s) without a received reply.\n", errMsg, pending)
+	}
+
+	for _, r := range c.replays {
+		errMsg += r.unmet()
+	}
+
 	for _, cmd := range c.commands {
-		if !cmd.Called() {
+		times, calls, limited := cmd.expectedCalls()
+		if limited && times != calls {
+			errMsg = fmt.Sprintf("%sCommand %s with arguments %#v expected to be called %d time(s) but called %d time(s).\n",
+				errMsg, cmd.name, cmd.args, times, calls)
+		} else if !limited && !cmd.Called() {
 			errMsg = fmt.Sprintf("%sCommand %s with arguments %#v expected but never called.\n", errMsg, cmd.name, cmd.args)
 		}
 	}
@@ -450,10 +450,6 @@ func TestPubSub(t *testing.T) {
 	}
 	defer psc.Unsubscribe(channel)
 
-	if err := conn.ExpectationsWereMet(); err != nil {
-		t.Error(err)
-	}
-
 	messages := [][]byte{
 		[]byte("value1"),
 		[]byte("value2"),
@@ -487,6 +483,10 @@ func TestPubSub(t *testing.T) {
 		t.Errorf("Expected subscribe message type but received '%T'", msg)
 	}
 
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
 	for _, expectedMessage := range messages {
 		go nextMessage()
 
@@ -501,6 +501,162 @@ func TestPubSub(t *testing.T) {
 	}
 }
 
+func TestDoEmptyFlushPipelineWithErrors(t *testing.T) {
+	c := NewConn()
+	c.Command("GET", "key1").Expect("value1")
+	c.Command("GET", "key2").ExpectError(fmt.Errorf("simulated error"))
+	c.Command("GET", "key3").Expect("value3")
+
+	c.Send("GET", "key1")
+	c.Send("GET", "key2")
+	c.Send("GET", "key3")
+
+	reply, err := c.Do("")
+	if err != nil {
+		t.Fatalf("Unexpected error when flushing pipeline: %v", err)
+	}
+
+	expected := []interface{}{"value1", redis.Error("simulated error"), "value3"}
+	if !reflect.DeepEqual(reply, expected) {
+		t.Errorf("Unexpected replies. Expected '%#v' and got '%#v'", expected, reply)
+	}
+
+	if err := c.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDoWithPendingErrors(t *testing.T) {
+	c := NewConn()
+	c.Command("MULTI").ExpectError(fmt.Errorf("simulated error"))
+	cmd := c.Command("EXEC").Expect("OK")
+
+	c.Send("MULTI")
+
+	reply, err := c.Do("EXEC")
+	if err == nil || err.Error() != "simulated error" {
+		t.Errorf("Expected pending error and got '%v'", err)
+	}
+
+	if reply != "OK" {
+		t.Errorf("Expected command reply 'OK' and got '%v'", reply)
+	}
+
+	if counter := c.Stats(cmd); counter != 1 {
+		t.Errorf("Expected EXEC to be called once but was called %d times", counter)
+	}
+}
+
+func TestSendReceiveErrorsInOrder(t *testing.T) {
+	c := NewConn()
+	c.Command("GET", "key1").ExpectError(redis.Error("ERR simulated"))
+	c.Command("GET", "key2").Expect("value2")
+
+	c.Send("GET", "key1")
+	c.Send("GET", "key2")
+	c.Flush()
+
+	if _, err := c.Receive(); err != redis.Error("ERR simulated") {
+		t.Errorf("Expected error reply for the first command and got '%v'", err)
+	}
+
+	reply, err := c.Receive()
+	if err != nil || reply != "value2" {
+		t.Errorf("Expected 'value2' for the second command and got '%v' (%v)", reply, err)
+	}
+}
+
+func TestDoPendingErrorPrecedence(t *testing.T) {
+	c := NewConn()
+	c.Command("GET", "key1").ExpectError(redis.Error("ERR first"))
+	c.Command("GET", "key2").ExpectError(redis.Error("ERR second"))
+
+	c.Send("GET", "key1")
+	if _, err := c.Do("GET", "key2"); err != redis.Error("ERR first") {
+		t.Errorf("Expected the pending error and got '%v'", err)
+	}
+
+	if _, err := c.Do("GET", "key2"); err != redis.Error("ERR second") {
+		t.Errorf("Expected the command error and got '%v'", err)
+	}
+}
+
+func TestDoFlushesOnlySentCommands(t *testing.T) {
+	c := NewConn()
+	c.Command("PING").Expect("PONG")
+
+	flushes := 0
+	c.FlushSkippableMock = func() error {
+		flushes++
+		return nil
+	}
+
+	c.Do("PING")
+	if flushes != 0 {
+		t.Errorf("Expected no flush without sent commands and got %d", flushes)
+	}
+
+	c.Send("PING")
+	c.Do("PING")
+	if flushes != 1 {
+		t.Errorf("Expected flush to be called once and got %d", flushes)
+	}
+
+	c.Do("")
+	if flushes != 2 {
+		t.Errorf("Expected flush to be called by Do without a command and got %d", flushes)
+	}
+}
+
+func TestReceiveDoesNotFlushAgain(t *testing.T) {
+	c := NewConn()
+	c.Command("PING").Expect("PONG")
+
+	flushes := 0
+	c.FlushSkippableMock = func() error {
+		flushes++
+		return nil
+	}
+
+	c.Send("PING")
+	c.Send("PING")
+	c.Flush()
+
+	for i := 0; i < 2; i++ {
+		if reply, err := c.Receive(); err != nil || reply != "PONG" {
+			t.Errorf("Unexpected reply '%v' (%v)", reply, err)
+		}
+	}
+
+	if flushes != 1 {
+		t.Errorf("Expected flush to be called once but was called %d times", flushes)
+	}
+}
+
+func TestSendReceiveMismatch(t *testing.T) {
+	c := NewConn()
+	c.Command("PING").Expect("PONG")
+
+	c.Send("PING")
+	c.Send("PING")
+	c.Flush()
+	c.Receive()
+
+	if err := c.ExpectationsWereMet(); err == nil {
+		t.Error("Should detect a sent command without a received reply")
+	}
+
+	c.Receive()
+	if err := c.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	c.Receive()
+	if err := c.ExpectationsWereMet(); err == nil {
+		t.Error("Should detect a receive without a sent command")
+	}
+}
+
 func TestSendFlushReceiveWithError(t *testing.T) {
 	connection := NewConn()
 
@@ -523,18 +679,6 @@ func TestSendFlushReceiveWithError(t *testing.T) {
 func TestDummyFunctions(t *testing.T) {
 	var conn Conn
 
-	if conn.Close() != nil {
-		t.Error("Close is not dummy!")
-	}
-
-	conn.CloseMock = func() error {
-		return fmt.Errorf("close error")
-	}
-
-	if err := conn.Close(); err == nil || err.Error() != "close error" {
-		t.Errorf("Not mocking Close method correctly. Expected “close error” and got “%v”", err)
-	}
-
 	if conn.Err() != nil {
 		t.Error("Err is not dummy!")
 	}
@@ -558,6 +702,107 @@ func TestDummyFunctions(t *testing.T) {
 	if err := conn.Flush(); err == nil || err.Error() != "flush error" {
 		t.Errorf("Not mocking Flush method correctly. Expected “flush error” and got “%v”", err)
 	}
+
+	if conn.Close() != nil {
+		t.Error("Close is not dummy!")
+	}
+
+	conn.CloseMock = func() error {
+		return fmt.Errorf("close error")
+	}
+
+	if err := conn.Close(); err == nil || err.Error() != "close error" {
+		t.Errorf("Not mocking Close method correctly. Expected “close error” and got “%v”", err)
+	}
+}
+
+func TestClosedConnection(t *testing.T) {
+	connection := NewConn()
+	connection.Command("PING").Expect("PONG")
+
+	if err := connection.Close(); err != nil {
+		t.Fatal(err)
+	}
+	connection.Close()
+
+	if counter := connection.CloseCount(); counter != 2 {
+		t.Errorf("Expected connection to be closed 2 times, but it was closed %d times", counter)
+	}
+
+	if err := connection.Err(); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got “%v”", err)
+	}
+
+	if _, err := connection.Do("PING"); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got “%v”", err)
+	}
+
+	if err := connection.Send("PING"); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got “%v”", err)
+	}
+
+	if err := connection.Flush(); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got “%v”", err)
+	}
+
+	if _, err := connection.Receive(); err != ErrConnClosed {
+		t.Errorf("Expected closed connection error and got “%v”", err)
+	}
+	if ErrConnClosed.Error() != "redigo: closed" {
+		t.Errorf("Expected the closed connection error of redigo and got “%v”", ErrConnClosed)
+	}
+
+	connection.Clear()
+	connection.Command("PING").Expect("PONG")
+
+	if _, err := connection.Do("PING"); err != nil {
+		t.Errorf("Clear should reopen the connection, got “%v”", err)
+	}
+}
+
+func TestBrokenConnection(t *testing.T) {
+	connection := NewConn()
+	connection.Command("PING").Expect("PONG")
+
+	brokenErr := fmt.Errorf("connection reset by peer")
+	connection.Break(brokenErr)
+
+	if err := connection.Err(); err != brokenErr {
+		t.Errorf("Expected broken connection error and got “%v”", err)
+	}
+
+	if _, err := connection.Do("PING"); err != brokenErr {
+		t.Errorf("Expected broken connection error and got “%v”", err)
+	}
+
+	connection.Close()
+	if err := connection.Err(); err != brokenErr {
+		t.Errorf("Close should not replace the broken connection error, got “%v”", err)
+	}
+}
+
+func TestBrokenConnectionUnblocksReceive(t *testing.T) {
+	connection := NewConn()
+	connection.SubscriptionFeed()
+
+	brokenErr := fmt.Errorf("connection reset by peer")
+	errs := make(chan error)
+	go func() {
+		_, err := connection.Receive()
+		errs <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	connection.Break(brokenErr)
+
+	select {
+	case err := <-errs:
+		if err != brokenErr {
+			t.Errorf("Expected broken connection error and got “%v”", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive was not unblocked by Break")
+	}
 }
 
 func TestSkipDummyFlushFunction(t *testing.T) {
@@ -858,3 +1103,28 @@ func TestErrorRace(t *testing.T) {
 		t.Errorf("wanted %v errors, got %v", n, len(connection.Errors()))
 	}
 }
+
+func TestReceiveErrorReply(t *testing.T) {
+	connection := NewConn()
+	connection.Command("GET", "key").ExpectError(fmt.Errorf("failure"))
+
+	connection.Send("GET", "key")
+	connection.Flush()
+	if _, err := connection.Receive(); err != redis.Error("failure") {
+		t.Errorf("Expected error reply from Receive and got %#v", err)
+	}
+
+	connection.Send("GET", "key")
+	replies, err := redis.Values(connection.Do(""))
+	if err != nil || len(replies) != 1 || replies[0] != redis.Error("failure") {
+		t.Errorf("Expected error reply from Do and got %#v (%v)", replies, err)
+	}
+}
+
+func TestDoWithoutSentCommands(t *testing.T) {
+	connection := NewConn()
+
+	if reply, err := connection.Do(""); reply != nil || err != nil {
+		t.Errorf("Expected nil like redigo and got %#v (%v)", reply, err)
+	}
+}
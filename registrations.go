@@ -0,0 +1,363 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"gopkg.in/yaml.v3"
+)
+
+// registration is a command described in a registrations file, validated and
+// ready to be registered
+type registration struct {
+	name      string
+	args      []interface{}
+	generic   bool
+	responses []func(*Cmd)
+	times     *int
+	delay     time.Duration
+}
+
+// registrationError is a validation error pointing at the line of the
+// registrations file
+type registrationError struct {
+	path string
+	line int
+	msg  string
+}
+
+func (e registrationError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.path, e.line, e.msg)
+}
+
+// LoadCommands registers the commands described in a YAML or JSON file,
+// returning them in the same order. It works like calling Command (or
+// GenericCommand) followed by the Expect methods, Times and Delay. No command
+// is registered when the file is invalid, and the error points at the line of
+// the invalid value.
+//
+//	commands:
+//	  - command: GET
+//	    args: [user:1]
+//	    response: alice
+//	  - command: HGETALL
+//	    args: [{glob: "user:*"}]
+//	    responses:
+//	      - {map: {name: alice}}
+//	      - {error: ERR failure}
+//	    times: 2
+//	    delay: 10ms
+//	  - command: PUBLISH
+//	    generic: true
+//
+// Arguments are strings, numbers (stored as int or float64) or matchers:
+// {any: int}, {any: double}, {any: data}, {regexp: expr} and {glob: pattern}.
+// Responses are values, where integers are stored as int64 like redigo
+// replies, or objects with one of the fields value, map (see Cmd.ExpectMap),
+// error (returned as redis.Error) and panic
+func (c *Conn) LoadCommands(path string) ([]*Cmd, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON documents are valid YAML documents, so both formats are decoded
+	// keeping the lines of the values
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	registrations, err := parseRegistrations(path, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*Cmd, len(registrations))
+	for i, r := range registrations {
+		cmds[i] = c.register(r)
+	}
+	return cmds, nil
+}
+
+// register registers the command described in the file
+func (c *Conn) register(r registration) *Cmd {
+	var cmd *Cmd
+	if r.generic {
+		cmd = c.GenericCommand(r.name)
+	} else {
+		cmd = c.Command(r.name, r.args...)
+	}
+
+	for _, expect := range r.responses {
+		expect(cmd)
+	}
+	if r.times != nil {
+		cmd.Times(*r.times)
+	}
+	if r.delay > 0 {
+		cmd.Delay(r.delay)
+	}
+	return cmd
+}
+
+// parseRegistrations validates the registrations file
+func parseRegistrations(path string, document *yaml.Node) ([]registration, error) {
+	fail := func(node *yaml.Node, format string, a ...interface{}) error {
+		return registrationError{path: path, line: node.Line, msg: fmt.Sprintf(format, a...)}
+	}
+
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fail(root, "expected an object with the commands field")
+	}
+
+	var commands *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "commands" {
+			return nil, fail(key, "unknown field '%s'", key.Value)
+		}
+		commands = value
+	}
+
+	if commands == nil {
+		return nil, nil
+	}
+	if commands.Kind != yaml.SequenceNode {
+		return nil, fail(commands, "expected a list of commands")
+	}
+
+	registrations := make([]registration, len(commands.Content))
+	for i, node := range commands.Content {
+		r, err := parseRegistration(node, fail)
+		if err != nil {
+			return nil, err
+		}
+		registrations[i] = r
+	}
+	return registrations, nil
+}
+
+// parseRegistration validates a command of the registrations file
+func parseRegistration(node *yaml.Node, fail func(*yaml.Node, string, ...interface{}) error) (registration, error) {
+	var r registration
+	if node.Kind != yaml.MappingNode {
+		return r, fail(node, "expected a command object")
+	}
+
+	var argsNode, responseNode, responsesNode *yaml.Node
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		switch key.Value {
+		case "command":
+			if value.Kind != yaml.ScalarNode || value.Value == "" {
+				return r, fail(value, "expected a command name")
+			}
+			r.name = value.Value
+
+		case "args":
+			if value.Kind != yaml.SequenceNode {
+				return r, fail(value, "expected a list of arguments")
+			}
+			argsNode = value
+
+		case "generic":
+			if err := value.Decode(&r.generic); err != nil {
+				return r, fail(value, "expected true or false")
+			}
+
+		case "response":
+			responseNode = value
+
+		case "responses":
+			if value.Kind != yaml.SequenceNode {
+				return r, fail(value, "expected a list of responses")
+			}
+			responsesNode = value
+
+		case "times":
+			var times int
+			if err := value.Decode(&times); err != nil || times < 0 {
+				return r, fail(value, "expected a non-negative number of calls")
+			}
+			r.times = &times
+
+		case "delay":
+			d, err := time.ParseDuration(value.Value)
+			if value.Kind != yaml.ScalarNode || err != nil || d < 0 {
+				return r, fail(value, "invalid delay '%s'", value.Value)
+			}
+			r.delay = d
+
+		default:
+			return r, fail(key, "unknown field '%s'", key.Value)
+		}
+	}
+
+	if r.name == "" {
+		return r, fail(node, "missing command name")
+	}
+	if r.generic && argsNode != nil {
+		return r, fail(argsNode, "generic commands can't have arguments")
+	}
+	if responseNode != nil && responsesNode != nil {
+		return r, fail(responsesNode, "response and responses can't be used together")
+	}
+
+	if argsNode != nil {
+		for _, argNode := range argsNode.Content {
+			arg, err := parseArg(argNode, fail)
+			if err != nil {
+				return r, err
+			}
+			r.args = append(r.args, arg)
+		}
+	}
+
+	responses := []*yaml.Node{}
+	if responseNode != nil {
+		responses = append(responses, responseNode)
+	} else if responsesNode != nil {
+		responses = responsesNode.Content
+	}
+
+	for _, responseNode := range responses {
+		expect, err := parseResponse(responseNode, fail)
+		if err != nil {
+			return r, err
+		}
+		r.responses = append(r.responses, expect)
+	}
+	return r, nil
+}
+
+// parseArg converts an argument of the registrations file
+func parseArg(node *yaml.Node, fail func(*yaml.Node, string, ...interface{}) error) (interface{}, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var arg interface{}
+		if err := node.Decode(&arg); err != nil {
+			return nil, fail(node, "invalid argument: %s", err)
+		}
+		return arg, nil
+
+	case yaml.MappingNode:
+		if len(node.Content) != 2 || node.Content[1].Kind != yaml.ScalarNode {
+			return nil, fail(node, "expected a matcher with a single field")
+		}
+
+		kind, value := node.Content[0].Value, node.Content[1]
+		switch kind {
+		case "any":
+			switch value.Value {
+			case "int":
+				return NewAnyInt(), nil
+			case "double":
+				return NewAnyDouble(), nil
+			case "data":
+				return NewAnyData(), nil
+			}
+			return nil, fail(value, "unknown matcher 'any: %s', expected int, double or data", value.Value)
+
+		case "regexp":
+			matcher, err := newRegexpMatcher(value.Value)
+			if err != nil {
+				return nil, fail(value, "invalid regular expression: %s", err)
+			}
+			return matcher, nil
+
+		case "glob":
+			return NewGlob(value.Value), nil
+		}
+		return nil, fail(node.Content[0], "unknown matcher '%s'", kind)
+	}
+	return nil, fail(node, "expected a value or a matcher")
+}
+
+// parseResponse converts a response of the registrations file to the Cmd
+// method that registers it
+func parseResponse(node *yaml.Node, fail func(*yaml.Node, string, ...interface{}) error) (func(*Cmd), error) {
+	if node.Kind != yaml.MappingNode {
+		value, err := parseResponseValue(node, fail)
+		if err != nil {
+			return nil, err
+		}
+		return func(cmd *Cmd) { cmd.Expect(value) }, nil
+	}
+
+	if len(node.Content) != 2 {
+		return nil, fail(node, "expected a response with a single field")
+	}
+
+	kind, value := node.Content[0], node.Content[1]
+	switch kind.Value {
+	case "value":
+		v, err := parseResponseValue(value, fail)
+		if err != nil {
+			return nil, err
+		}
+		return func(cmd *Cmd) { cmd.Expect(v) }, nil
+
+	case "map":
+		var m map[string]string
+		if err := value.Decode(&m); err != nil {
+			return nil, fail(value, "expected an object with string values")
+		}
+		return func(cmd *Cmd) { cmd.ExpectMap(m) }, nil
+
+	case "error":
+		if value.Kind != yaml.ScalarNode || value.Value == "" {
+			return nil, fail(value, "expected an error message")
+		}
+		err := redis.Error(value.Value)
+		return func(cmd *Cmd) { cmd.ExpectError(err) }, nil
+
+	case "panic":
+		if value.Kind != yaml.ScalarNode {
+			return nil, fail(value, "expected a panic message")
+		}
+		msg := value.Value
+		return func(cmd *Cmd) { cmd.ExpectPanic(msg) }, nil
+	}
+	return nil, fail(kind, "unknown response '%s'", kind.Value)
+}
+
+// parseResponseValue converts a response value of the registrations file,
+// storing integers as int64 like redigo replies
+func parseResponseValue(node *yaml.Node, fail func(*yaml.Node, string, ...interface{}) error) (interface{}, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, fail(node, "invalid response: %s", err)
+		}
+		if n, ok := value.(int); ok {
+			return int64(n), nil
+		}
+		return value, nil
+
+	case yaml.SequenceNode:
+		values := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			value, err := parseResponseValue(item, fail)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	return nil, fail(node, "expected a value or a list of values")
+}
@@ -0,0 +1,127 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestLoadCommands(t *testing.T) {
+	for _, name := range []string{"commands.yaml", "commands.json"} {
+		conn := NewConn()
+		cmds, err := conn.LoadCommands(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(cmds) != 5 {
+			t.Fatalf("%s: expected 5 commands and got %d", name, len(cmds))
+		}
+
+		if value, err := redis.String(conn.Do("GET", "user:1")); err != nil || value != "alice" {
+			t.Errorf("%s: unexpected GET reply '%s' (%v)", name, value, err)
+		}
+
+		if values, err := redis.StringMap(conn.Do("HGETALL", "user:2")); err != nil || values["name"] != "alice" {
+			t.Errorf("%s: unexpected HGETALL reply %v (%v)", name, values, err)
+		}
+
+		if _, err := conn.Do("HGETALL", "user:3"); !reflect.DeepEqual(err, redis.Error("ERR failure")) {
+			t.Errorf("%s: expected error reply and got '%v'", name, err)
+		}
+
+		if value, err := redis.Int64(conn.Do("EXPIRE", "session:10", 30)); err != nil || value != 1 {
+			t.Errorf("%s: unexpected EXPIRE reply %d (%v)", name, value, err)
+		}
+
+		if _, err := conn.Do("EXPIRE", "session:abc", 30); err == nil {
+			t.Errorf("%s: expected error for arguments not matching the regular expression", name)
+		}
+
+		if values, err := redis.Strings(conn.Do("LRANGE", "queue", 0, -1)); err != nil || !reflect.DeepEqual(values, []string{"a", "b"}) {
+			t.Errorf("%s: unexpected LRANGE reply %v (%v)", name, values, err)
+		}
+
+		if value, err := redis.Int(conn.Do("PUBLISH", "channel", "message")); err != nil || value != 0 {
+			t.Errorf("%s: unexpected PUBLISH reply %d (%v)", name, value, err)
+		}
+
+		if conn.Stats(cmds[1]) != 2 {
+			t.Errorf("%s: expected HGETALL to be called 2 times and got %d", name, conn.Stats(cmds[1]))
+		}
+	}
+}
+
+func TestLoadCommandsInvalid(t *testing.T) {
+	data := []struct {
+		content  string
+		expected string
+	}{
+		{
+			content:  "commands:\n  - command: GET\n    args: [{any: string}]\n",
+			expected: "commands.yaml:3: unknown matcher 'any: string', expected int, double or data",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    response: a\n    responses: [b]\n",
+			expected: "commands.yaml:4: response and responses can't be used together",
+		},
+		{
+			content:  "commands:\n  - args: [a]\n",
+			expected: "commands.yaml:2: missing command name",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    delay: soon\n",
+			expected: "commands.yaml:3: invalid delay 'soon'",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    times: -1\n",
+			expected: "commands.yaml:3: expected a non-negative number of calls",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    args: [{regexp: \"[\"}]\n",
+			expected: "commands.yaml:3: invalid regular expression: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    response: {bulk: a}\n",
+			expected: "commands.yaml:3: unknown response 'bulk'",
+		},
+		{
+			content:  "commands:\n  - command: GET\n    generic: true\n    args: [a]\n",
+			expected: "commands.yaml:4: generic commands can't have arguments",
+		},
+		{
+			content:  "command: GET\n",
+			expected: "commands.yaml:1: unknown field 'command'",
+		},
+	}
+
+	for i, item := range data {
+		path := filepath.Join(t.TempDir(), "commands.yaml")
+		if err := ioutil.WriteFile(path, []byte(item.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		conn := NewConn()
+		_, err := conn.LoadCommands(path)
+		if err == nil {
+			t.Errorf("Item %d: expected error '%s' and got none", i, item.expected)
+			continue
+		}
+
+		expected := filepath.Join(filepath.Dir(path), item.expected)
+		if err.Error() != expected {
+			t.Errorf("Item %d: expected error '%s' and got '%s'", i, expected, err)
+		}
+
+		if len(conn.commands) != 0 {
+			t.Errorf("Item %d: expected no registered commands and got %d", i, len(conn.commands))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ReplayMode defines how the recorded commands are matched when replayed
+type ReplayMode int
+
+const (
+	// ReplayUnordered matches the commands in any order. Repeated commands
+	// receive the recorded replies in order, and the last one is repeated
+	// after all of them are used
+	ReplayUnordered ReplayMode = iota
+
+	// ReplayOrdered requires the commands to be executed in the same order
+	// they were recorded, returning an error otherwise, also reported by
+	// ExpectationsWereMet
+	ReplayOrdered
+)
+
+// replayer answers the recorded commands with their replies
+type replayer struct {
+	commands []RecordedCommand // Recorded commands
+	used     []bool            // Commands already replayed, by index
+	next     int               // Index of the next command in ordered mode
+	mode     ReplayMode        // How commands are matched
+	errors   []error           // Commands that didn't match the recording
+	mu       sync.Mutex        // Hold while accessing used, next and errors
+}
+
+// Replay registers the recorded commands in the connection, replying them
+// like they were recorded, and adds the recorded subscription messages.
+// Subscription commands aren't registered, as they are handled by the
+// connection. Arguments are matched as they are sent to the Redis server, so
+// the integer 10 matches the recorded argument "10". ExpectationsWereMet
+// reports the recorded commands that weren't replayed and the executed
+// commands that didn't match the recording
+func (c *Conn) Replay(recording *Recording, mode ReplayMode) {
+	r := &replayer{mode: mode}
+	for _, command := range recording.Commands {
+		if !isSubscriptionCommand(command.Command) {
+			r.commands = append(r.commands, command)
+		}
+	}
+	r.used = make([]bool, len(r.commands))
+
+	registered := make(map[string]bool)
+	for _, command := range r.commands {
+		if registered[command.Command] {
+			continue
+		}
+		registered[command.Command] = true
+
+		commandName := command.Command
+		c.GenericCommand(commandName).Handle(func(args []interface{}) (interface{}, error) {
+			return r.reply(commandName, args)
+		})
+	}
+
+	for _, message := range recording.Messages {
+		c.AddSubscriptionMessage(message)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.replays = append(c.replays, r)
+}
+
+// reply returns the recorded reply of the command, keeping the errors of the
+// commands that don't match the recording
+func (r *replayer) reply(commandName string, args []interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	command, err := r.replay(commandName, args)
+	if err != nil {
+		r.errors = append(r.errors, err)
+		return nil, err
+	}
+	return command.Reply, command.Err
+}
+
+// replay looks for the recorded command with the reply of the command,
+// returning an error when it doesn't match the recording
+//
+// Caller must hold r.mu.
+func (r *replayer) replay(commandName string, args []interface{}) (*RecordedCommand, error) {
+	wireArgs := make([]string, len(args))
+	for i, arg := range args {
+		wireArgs[i] = string(formatArg(arg))
+	}
+
+	if r.mode == ReplayOrdered {
+		if r.next >= len(r.commands) {
+			return nil, fmt.Errorf("command %s with arguments %q executed after all recorded commands", commandName, wireArgs)
+		}
+
+		command := &r.commands[r.next]
+		if !command.matches(commandName, wireArgs) {
+			return nil, fmt.Errorf("command %s with arguments %q executed when command %s with arguments %q was expected",
+				commandName, wireArgs, command.Command, command.Args)
+		}
+
+		r.used[r.next] = true
+		r.next++
+		return command, nil
+	}
+
+	last := -1
+	for i, command := range r.commands {
+		if !command.matches(commandName, wireArgs) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.commands[i], nil
+		}
+		last = i
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("command %s with arguments %q not recorded", commandName, wireArgs)
+	}
+	return &r.commands[last], nil
+}
+
+// unmet describes the commands that didn't match the recording and the
+// recorded commands that weren't replayed, one per line
+func (r *replayer) unmet() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg := ""
+	for _, err := range r.errors {
+		msg = fmt.Sprintf("%sReplayed %s.\n", msg, err)
+	}
+	for i, command := range r.commands {
+		if !r.used[i] {
+			msg = fmt.Sprintf("%sRecorded command %s with arguments %q never replayed.\n", msg, command.Command, command.Args)
+		}
+	}
+	return msg
+}
+
+// matches checks if the command was recorded with the given name and
+// arguments
+func (rc RecordedCommand) matches(commandName string, args []string) bool {
+	if rc.Command != commandName || len(rc.Args) != len(args) {
+		return false
+	}
+
+	for i := range args {
+		if rc.Args[i] != args[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestReplayUnordered(t *testing.T) {
+	recording, err := LoadRecording(filepath.Join("testdata", "recording.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := NewConn()
+	conn.Replay(recording, ReplayUnordered)
+
+	if values, err := redis.Strings(conn.Do("LRANGE", "queue", 0, -1)); err != nil || !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Errorf("Unexpected LRANGE reply %v (%v)", values, err)
+	}
+
+	if reply, err := conn.Do("SET", "counter", 10); err != nil || reply != "OK" {
+		t.Errorf("Unexpected SET reply %#v (%v)", reply, err)
+	}
+
+	for _, expected := range []int64{11, 12, 12} {
+		if value, err := redis.Int64(conn.Do("INCR", "counter")); err != nil || value != expected {
+			t.Errorf("Expected INCR reply %d and got %d (%v)", expected, value, err)
+		}
+	}
+
+	if _, err := conn.Do("HGET", "counter", "field"); !reflect.DeepEqual(err, WrongTypeError()) {
+		t.Errorf("Expected wrong type error and got '%v'", err)
+	}
+
+	if reply, err := conn.Do("GET", "missing"); err != nil || reply != nil {
+		t.Errorf("Unexpected GET reply %#v (%v)", reply, err)
+	}
+
+	if _, err := conn.Do("GET", "other"); err == nil {
+		t.Error("Expected error for a command not recorded")
+	}
+
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe("news"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := psc.Receive().(redis.Subscription); !ok {
+		t.Error("Expected subscription confirmation")
+	}
+
+	message, ok := psc.Receive().(redis.Message)
+	if !ok || message.Channel != "news" || string(message.Data) != "hello" {
+		t.Errorf("Unexpected message %#v", message)
+	}
+}
+
+func TestReplayOrdered(t *testing.T) {
+	recording := &Recording{
+		Commands: []RecordedCommand{
+			{Command: "SET", Args: []string{"key", "1"}, Reply: "OK"},
+			{Command: "GET", Args: []string{"key"}, Reply: []byte("1")},
+			{Command: "PING", Err: errors.New("connection reset")},
+		},
+	}
+
+	conn := NewConn()
+	conn.Replay(recording, ReplayOrdered)
+
+	if _, err := conn.Do("GET", "key"); err == nil {
+		t.Error("Expected error for a command out of order")
+	}
+
+	if reply, err := conn.Do("SET", "key", 1); err != nil || reply != "OK" {
+		t.Errorf("Unexpected SET reply %#v (%v)", reply, err)
+	}
+
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "1" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+
+	if _, err := conn.Do("PING"); err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected connection reset error and got '%v'", err)
+	}
+
+	if _, err := conn.Do("GET", "key"); err == nil {
+		t.Error("Expected error after all recorded commands")
+	}
+
+	if err := conn.ExpectationsWereMet(); err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("Expected the 2 commands out of order to be reported and got '%v'", err)
+	}
+}
+
+func TestReplayNotReplayed(t *testing.T) {
+	recording := &Recording{
+		Commands: []RecordedCommand{
+			{Command: "SET", Args: []string{"key", "1"}, Reply: "OK"},
+			{Command: "GET", Args: []string{"key"}, Reply: []byte("1")},
+			{Command: "GET", Args: []string{"key"}, Reply: []byte("2")},
+		},
+	}
+
+	conn := NewConn()
+	conn.Replay(recording, ReplayUnordered)
+
+	if _, err := conn.Do("SET", "key", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("GET", "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := conn.ExpectationsWereMet()
+	if err == nil || !strings.Contains(err.Error(), `Recorded command GET with arguments ["key"] never replayed`) {
+		t.Errorf("Expected the second GET to be reported and got '%v'", err)
+	}
+
+	if _, err := conn.Do("GET", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected error after replaying all commands '%v'", err)
+	}
+
+	conn.Clear()
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected error after clearing the connection '%v'", err)
+	}
+}
+
+func TestReplayRecorded(t *testing.T) {
+	recording := recordTraffic(t)
+
+	conn := NewConn()
+	conn.Replay(recording, ReplayOrdered)
+
+	recorder := NewRecorder(conn)
+	for _, command := range recording.Commands {
+		if command.Command == "SUBSCRIBE" {
+			continue
+		}
+
+		args := make([]interface{}, len(command.Args))
+		for i, arg := range command.Args {
+			args[i] = arg
+		}
+		recorder.Do(command.Command, args...)
+	}
+
+	replayed := recorder.Recording()
+	if !reflect.DeepEqual(replayed.Commands, recording.Commands[:len(recording.Commands)-1]) {
+		t.Errorf("Expected %#v and got %#v", recording.Commands, replayed.Commands)
+	}
+
+	if err := conn.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// IsWriteCommand checks if the command modifies the data set, so it's
+// rejected by replicas. The write commands are flagged in the Redis command
+// table, commands missing from it aren't write commands
+func IsWriteCommand(commandName string) bool {
+	spec, ok := commandSpecs[strings.ToUpper(commandName)]
+	return ok && spec.write
+}
+
+// replication is the replication role of a connection, shared by the
+// connections dialed by a pool
+type replication struct {
+	master   string     // Address of the master, empty when the connection is a master
+	replicas []string   // Addresses of the replicas, reported when the connection is a master
+	offset   int64      // Replication offset, incremented by the write commands
+	mu       sync.Mutex // Hold while accessing any mutable fields
+}
+
+// ReplicaOf makes the connection a replica of the master in the address, so
+// write commands fail with a READONLY error and ROLE and INFO replication
+// describe the replica, unless they are registered. An empty address promotes
+// the connection to master, like REPLICAOF NO ONE
+func (c *Conn) ReplicaOf(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.replication
+	if r == nil {
+		r = &replication{}
+		c.replication = r
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.master = addr
+}
+
+// SetReplicas sets the addresses of the replicas reported by ROLE and INFO
+// replication when the connection is a master
+func (c *Conn) SetReplicas(addrs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.replication
+	if r == nil {
+		r = &replication{}
+		c.replication = r
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replicas = append([]string{}, addrs...)
+}
+
+// Role returns the replication role of the connection, "master" or "slave"
+// like the ROLE command
+func (c *Conn) Role() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isReplica() {
+		return "slave"
+	}
+	return "master"
+}
+
+// isReplica checks if the connection is a replica
+//
+// Caller must hold c.mu.
+func (c *Conn) isReplica() bool {
+	r := c.currentReplication()
+	return r != nil && r.isReplica()
+}
+
+// currentReplication returns the replication role of the connection, falling
+// back to the one of the connection that it shares the registry with (see
+// Pool). Returns nil when the role wasn't set
+//
+// Caller must hold c.mu.
+func (c *Conn) currentReplication() *replication {
+	return fromRegistry(c, func(conn *Conn) *replication {
+		return conn.replication
+	})
+}
+
+// readOnly rejects the write commands when the connection is a replica. If
+// the command is accepted ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) readOnly(commandName string) (reply replyElement, ok bool) {
+	r := c.currentReplication()
+	if r == nil || !IsWriteCommand(commandName) {
+		return replyElement{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.master != "" {
+		return replyElement{err: ReadOnlyError()}, true
+	}
+	return replyElement{}, false
+}
+
+// countWrite increments the replication offset when the write command
+// succeeded on a master
+//
+// Caller must hold c.mu.
+func (c *Conn) countWrite(commandName string) {
+	r := c.currentReplication()
+	if r == nil || !IsWriteCommand(commandName) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.master == "" {
+		r.offset++
+	}
+}
+
+// replicationCommand handles the ROLE, INFO and REPLICAOF commands that
+// weren't registered. ROLE and INFO are handled only when the replication role
+// was set. If the command isn't supported ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) replicationCommand(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	switch strings.ToLower(commandName) {
+	case "replicaof", "slaveof":
+		if len(args) != 2 {
+			return replyElement{err: errWrongArgs(strings.ToLower(commandName))}, true
+		}
+
+		host, port := argString(args[0]), argString(args[1])
+		addr := ""
+		if !strings.EqualFold(host, "no") || !strings.EqualFold(port, "one") {
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				return replyElement{err: redis.Error("ERR Invalid master port")}, true
+			}
+			addr = net.JoinHostPort(host, port)
+		}
+
+		// the role is shared with the connections of the same registry
+		r := fromRegistry(c, func(conn *Conn) *replication {
+			if conn == c && c.registry != nil {
+				return nil
+			}
+			if conn.replication == nil {
+				conn.replication = &replication{}
+			}
+			return conn.replication
+		})
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.master = addr
+		return replyElement{reply: "OK"}, true
+
+	case "role":
+		if r := c.currentReplication(); r != nil {
+			return replyElement{reply: r.role()}, true
+		}
+
+	case "info":
+		r := c.currentReplication()
+		if r == nil {
+			break
+		}
+
+		section := "default"
+		if len(args) > 0 {
+			section = strings.ToLower(argString(args[0]))
+		}
+
+		switch section {
+		case "default", "all", "everything", "replication":
+			return replyElement{reply: []byte(r.info())}, true
+		}
+	}
+	return replyElement{}, false
+}
+
+// isReplica checks if the connection is a replica
+func (r *replication) isReplica() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.master != ""
+}
+
+// role returns the reply of ROLE
+func (r *replication) role() []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.master != "" {
+		host, port := splitAddr(r.master)
+		return []interface{}{[]byte("slave"), []byte(host), port, []byte("connected"), r.offset}
+	}
+
+	replicas := []interface{}{}
+	for _, addr := range r.replicas {
+		host, port := splitAddr(addr)
+		replicas = append(replicas, []interface{}{
+			[]byte(host),
+			[]byte(strconv.FormatInt(port, 10)),
+			[]byte(strconv.FormatInt(r.offset, 10)),
+		})
+	}
+	return []interface{}{[]byte("master"), r.offset, replicas}
+}
+
+// info returns the replication section of INFO
+func (r *replication) info() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := []string{"# Replication"}
+	if r.master != "" {
+		host, port := splitAddr(r.master)
+		lines = append(lines,
+			"role:slave",
+			"master_host:"+host,
+			fmt.Sprintf("master_port:%d", port),
+			"master_link_status:up",
+			"master_last_io_seconds_ago:0",
+			"master_sync_in_progress:0",
+			fmt.Sprintf("slave_repl_offset:%d", r.offset),
+			"slave_priority:100",
+			"slave_read_only:1",
+			"replica_announced:1",
+			"connected_slaves:0",
+		)
+	} else {
+		lines = append(lines, "role:master", fmt.Sprintf("connected_slaves:%d", len(r.replicas)))
+		for i, addr := range r.replicas {
+			host, port := splitAddr(addr)
+			lines = append(lines, fmt.Sprintf("slave%d:ip=%s,port=%d,state=online,offset=%d,lag=0", i, host, port, r.offset))
+		}
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("master_repl_offset:%d", r.offset),
+		"repl_backlog_active:1",
+		"repl_backlog_size:1048576",
+	)
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
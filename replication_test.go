@@ -0,0 +1,193 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestReplicaReadOnly(t *testing.T) {
+	conn := NewConn()
+	conn.Command("GET", "key").Expect("value")
+	conn.Command("SET", "key", "value").Expect("OK")
+	conn.ReplicaOf("127.0.0.1:6379")
+
+	if conn.Role() != "slave" {
+		t.Errorf("Expected role slave and got %s", conn.Role())
+	}
+
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+	if _, err := conn.Do("SET", "key", "value"); err != ReadOnlyError() {
+		t.Errorf("Expected READONLY error and got %v", err)
+	}
+	if _, err := conn.Do("set", "key", "value"); err != ReadOnlyError() {
+		t.Errorf("Expected READONLY error for lowercase command and got %v", err)
+	}
+
+	conn.ReplicaOf("")
+
+	if conn.Role() != "master" {
+		t.Errorf("Expected role master after promotion and got %s", conn.Role())
+	}
+	if value, err := redis.String(conn.Do("SET", "key", "value")); err != nil || value != "OK" {
+		t.Errorf("Unexpected SET reply after promotion '%s' (%v)", value, err)
+	}
+}
+
+func TestReplicaReadOnlyScript(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+	conn.Do("SET", "key", "value")
+	conn.ReplicaOf("127.0.0.1:6379")
+
+	if value, err := redis.String(conn.Do("EVAL", "return redis.call('GET', KEYS[1])", 1, "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected script reply '%s' (%v)", value, err)
+	}
+	if _, err := conn.Do("EVAL", "return redis.call('SET', KEYS[1], 'other')", 1, "key"); err != ReadOnlyError() {
+		t.Errorf("Expected READONLY error and got %v", err)
+	}
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Expected key unchanged by the script and got '%s' (%v)", value, err)
+	}
+
+	conn.ReplicaOf("")
+
+	if _, err := conn.Do("EVAL", "return redis.call('SET', KEYS[1], 'other')", 1, "key"); err != nil {
+		t.Errorf("Unexpected error after promotion %v", err)
+	}
+}
+
+func TestReplicaRole(t *testing.T) {
+	conn := NewConn()
+	conn.ReplicaOf("127.0.0.1:6379")
+
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{[]byte("slave"), []byte("127.0.0.1"), int64(6379), []byte("connected"), int64(0)}
+	if !reflect.DeepEqual(role, expected) {
+		t.Errorf("Unexpected ROLE reply %v", role)
+	}
+
+	info, err := redis.String(conn.Do("INFO", "replication"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"role:slave", "master_host:127.0.0.1", "master_port:6379", "slave_read_only:1"} {
+		if !strings.Contains(info, line+"\r\n") {
+			t.Errorf("Expected INFO line '%s' and got:\n%s", line, info)
+		}
+	}
+
+	if _, err := conn.Do("REPLICAOF", "NO", "ONE"); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReplicas("127.0.0.1:6380")
+	conn.Command("SET", "key", "value").Expect("OK")
+	conn.Do("SET", "key", "value")
+
+	role, err = redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []interface{}{
+		[]byte("master"),
+		int64(1),
+		[]interface{}{[]interface{}{[]byte("127.0.0.1"), []byte("6380"), []byte("1")}},
+	}
+	if !reflect.DeepEqual(role, expected) {
+		t.Errorf("Unexpected ROLE reply after promotion %v", role)
+	}
+
+	info, err = redis.String(conn.Do("INFO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"role:master", "connected_slaves:1", "slave0:ip=127.0.0.1,port=6380,state=online,offset=1,lag=0"} {
+		if !strings.Contains(info, line+"\r\n") {
+			t.Errorf("Expected INFO line '%s' and got:\n%s", line, info)
+		}
+	}
+
+	if _, err := conn.Do("INFO", "memory"); err == nil {
+		t.Error("Expected error for INFO section not registered")
+	}
+}
+
+func TestReplicaOffset(t *testing.T) {
+	conn := NewConn()
+	conn.ReplicaOf("")
+	conn.Command("SET", "key", "value").Expect("OK")
+	conn.Command("SET", "key", "other").ExpectError(WrongTypeError())
+	conn.Command("GET", "key").Expect("value")
+
+	conn.Do("SET", "key", "value")
+	conn.Do("SET", "key", "other")
+	conn.Do("SET", "key", "unknown")
+	conn.Do("GET", "key")
+
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := redis.Int64(role[1], nil); offset != 1 {
+		t.Errorf("Expected replication offset 1 counting only the successful writes and got %d", offset)
+	}
+}
+
+func TestIsWriteCommand(t *testing.T) {
+	data := []struct {
+		commandName string
+		write       bool
+	}{
+		{commandName: "SET", write: true},
+		{commandName: "del", write: true},
+		{commandName: "GET", write: false},
+		{commandName: "PING", write: false},
+		{commandName: "UNKNOWN", write: false},
+	}
+
+	for _, item := range data {
+		if write := IsWriteCommand(item.commandName); write != item.write {
+			t.Errorf("Expected %s write to be %t and got %t", item.commandName, item.write, write)
+		}
+	}
+}
+
+func TestReplicaOfCommand(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.GenericCommand("SET").Expect("OK")
+
+	conn, err := mockPool.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("REPLICAOF", "127.0.0.1", "6379"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("REPLICAOF", "127.0.0.1", "port"); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+
+	other, err := mockPool.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Do("SET", "key", "value"); err != ReadOnlyError() {
+		t.Errorf("Expected READONLY error in other connection of the pool and got %v", err)
+	}
+	if mockPool.Role() != "slave" {
+		t.Errorf("Expected pool role slave and got %s", mockPool.Role())
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// maxBulkLength is the maximum size of a bulk string accepted from clients,
+// the same default limit of the Redis server
+const maxBulkLength = 512 * 1024 * 1024
+
+// errProtocol is returned when a client sends data that doesn't follow the
+// Redis protocol
+type errProtocol string
+
+func (e errProtocol) Error() string {
+	return "ERR Protocol error: " + string(e)
+}
+
+// respReader reads the commands sent by clients in the Redis protocol
+type respReader struct {
+	r *bufio.Reader
+}
+
+// readCommand reads the next command, sent as an array of bulk strings or as
+// an inline command (space separated words, like the ones sent by telnet)
+func (r respReader) readCommand() ([]string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > 1024*1024 {
+		return nil, errProtocol("invalid multibulk length")
+	}
+
+	command := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errProtocol(fmt.Sprintf("expected '$', got '%.1s'", line))
+		}
+
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 || length > maxBulkLength {
+			return nil, errProtocol("invalid bulk length")
+		}
+
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return nil, err
+		}
+		if data[length] != '\r' || data[length+1] != '\n' {
+			return nil, errProtocol("invalid bulk string terminator")
+		}
+		command = append(command, string(data[:length]))
+	}
+	return command, nil
+}
+
+// readLine reads a line without the CRLF terminator
+func (r respReader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// respWriter writes replies in the Redis protocol. Version 3 of the protocol
+// is used when proto is 3, otherwise version 2
+type respWriter struct {
+	w     *bufio.Writer
+	proto int
+}
+
+// writeReply encodes the reply returned by a mocked command. Strings without
+// line breaks are written as status replies, errors as error replies and
+// maps as RESP3 maps (or flat arrays in RESP2). Other values are written as
+// bulk strings, as sent to the Redis server
+func (w respWriter) writeReply(reply interface{}, err error) {
+	if err != nil {
+		w.writeError(err)
+		return
+	}
+
+	switch value := reply.(type) {
+	case nil:
+		w.writeNil()
+	case string:
+		if strings.ContainsAny(value, "\r\n") {
+			w.writeBulk([]byte(value))
+			return
+		}
+		fmt.Fprintf(w.w, "+%s\r\n", value)
+	case []byte:
+		w.writeBulk(value)
+	case int:
+		w.writeInteger(int64(value))
+	case int8:
+		w.writeInteger(int64(value))
+	case int16:
+		w.writeInteger(int64(value))
+	case int32:
+		w.writeInteger(int64(value))
+	case int64:
+		w.writeInteger(value)
+	case uint8:
+		w.writeInteger(int64(value))
+	case uint16:
+		w.writeInteger(int64(value))
+	case uint32:
+		w.writeInteger(int64(value))
+	case bool:
+		if w.proto == 3 {
+			if value {
+				w.w.WriteString("#t\r\n")
+			} else {
+				w.w.WriteString("#f\r\n")
+			}
+			return
+		}
+		if value {
+			w.writeInteger(1)
+		} else {
+			w.writeInteger(0)
+		}
+	case float64:
+		if w.proto == 3 {
+			fmt.Fprintf(w.w, ",%s\r\n", formatArg(value))
+			return
+		}
+		w.writeBulk(formatArg(value))
+	case Double:
+		w.writeReply(float64(value), nil)
+	case BigNumber:
+		if w.proto == 3 {
+			fmt.Fprintf(w.w, "(%s\r\n", value.String())
+			return
+		}
+		w.writeBulk([]byte(value.String()))
+	case VerbatimString:
+		if w.proto == 3 {
+			fmt.Fprintf(w.w, "=%d\r\n%s:%s\r\n", len(value.Text)+4, value.Format, value.Text)
+			return
+		}
+		w.writeBulk([]byte(value.Text))
+	case Set:
+		w.writeAggregate('~', value)
+	case Push:
+		w.writeAggregate('>', value)
+	case error:
+		w.writeError(value)
+	case []interface{}:
+		fmt.Fprintf(w.w, "*%d\r\n", len(value))
+		for _, item := range value {
+			w.writeReply(item, nil)
+		}
+	case []string:
+		fmt.Fprintf(w.w, "*%d\r\n", len(value))
+		for _, item := range value {
+			w.writeBulk([]byte(item))
+		}
+	default:
+		if v := reflect.ValueOf(reply); v.Kind() == reflect.Map {
+			w.writeMap(v)
+			return
+		}
+		w.writeBulk(formatArg(reply))
+	}
+}
+
+// writeMap writes the map with the keys sorted, so the replies are
+// deterministic
+func (w respWriter) writeMap(v reflect.Value) {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return argString(keys[i].Interface()) < argString(keys[j].Interface())
+	})
+
+	if w.proto == 3 {
+		fmt.Fprintf(w.w, "%%%d\r\n", len(keys))
+	} else {
+		fmt.Fprintf(w.w, "*%d\r\n", len(keys)*2)
+	}
+
+	for _, key := range keys {
+		w.writeBulk(formatArg(key.Interface()))
+		w.writeReply(v.MapIndex(key).Interface(), nil)
+	}
+}
+
+// writeAggregate writes the values with the RESP3 aggregate type, or as an
+// array in RESP2
+func (w respWriter) writeAggregate(kind byte, values []interface{}) {
+	if w.proto != 3 {
+		kind = '*'
+	}
+
+	fmt.Fprintf(w.w, "%c%d\r\n", kind, len(values))
+	for _, value := range values {
+		w.writeReply(value, nil)
+	}
+}
+
+// writePush writes a message delivered to a subscribed client, as a push in
+// RESP3 and as an array in RESP2
+func (w respWriter) writePush(message interface{}) {
+	values, ok := message.([]interface{})
+	if !ok {
+		w.writeReply(message, nil)
+		return
+	}
+	w.writeAggregate('>', values)
+}
+
+// writeError writes the error, without line breaks that aren't allowed in
+// error replies
+func (w respWriter) writeError(err error) {
+	var redisErr redis.Error
+	msg := err.Error()
+	if !errors.As(err, &redisErr) && !strings.HasPrefix(msg, "ERR ") {
+		msg = "ERR " + msg
+	}
+
+	msg = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(msg)
+	fmt.Fprintf(w.w, "-%s\r\n", msg)
+}
+
+func (w respWriter) writeNil() {
+	if w.proto == 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w respWriter) writeInteger(n int64) {
+	fmt.Fprintf(w.w, ":%d\r\n", n)
+}
+
+func (w respWriter) writeBulk(data []byte) {
+	fmt.Fprintf(w.w, "$%d\r\n", len(data))
+	w.w.Write(data)
+	w.w.WriteString("\r\n")
+}
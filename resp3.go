@@ -0,0 +1,238 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RESP3Map is a map reply of the RESP3 protocol. The connection returns it as a
+// flat slice of keys and values, sorted by key (see SetProtocol)
+type RESP3Map map[string]interface{}
+
+// Set is a set reply of the RESP3 protocol. The connection returns it as a
+// slice
+type Set []interface{}
+
+// Double is a floating point reply of the RESP3 protocol. The connection
+// returns it as a bulk string
+type Double float64
+
+// BigNumber is a big number reply of the RESP3 protocol. The connection
+// returns it as a bulk string
+type BigNumber struct {
+	*big.Int
+}
+
+// VerbatimString is a verbatim string reply of the RESP3 protocol, like the
+// ones returned by INFO. The connection returns only the text, as a bulk
+// string
+type VerbatimString struct {
+	Format string // Format of the text, like "txt" or "mkd"
+	Text   string // Content of the string
+}
+
+// Push is an out of band message of the RESP3 protocol, like the messages
+// delivered to subscribed connections. The connection returns it as a slice
+type Push []interface{}
+
+// ExpectRESP3Map works in the same way of the Expect command, returning a
+// RESP3 map (see RESP3Map)
+func (c *Cmd) ExpectRESP3Map(resp map[string]interface{}) *Cmd {
+	c.expect(response{RESP3Map(resp), nil, nil})
+	return c
+}
+
+// ExpectSet works in the same way of the Expect command, returning a RESP3
+// set (see Set)
+func (c *Cmd) ExpectSet(resp ...interface{}) *Cmd {
+	c.expect(response{append(Set{}, resp...), nil, nil})
+	return c
+}
+
+// ExpectDouble works in the same way of the Expect command, returning a RESP3
+// double (see Double)
+func (c *Cmd) ExpectDouble(resp float64) *Cmd {
+	c.expect(response{Double(resp), nil, nil})
+	return c
+}
+
+// ExpectBigNumber works in the same way of the Expect command, returning a
+// RESP3 big number (see BigNumber)
+func (c *Cmd) ExpectBigNumber(resp *big.Int) *Cmd {
+	c.expect(response{BigNumber{resp}, nil, nil})
+	return c
+}
+
+// ExpectVerbatim works in the same way of the Expect command, returning a
+// RESP3 verbatim string (see VerbatimString)
+func (c *Cmd) ExpectVerbatim(format, text string) *Cmd {
+	c.expect(response{VerbatimString{Format: format, Text: text}, nil, nil})
+	return c
+}
+
+// ExpectPush works in the same way of the Expect command, returning a RESP3
+// push message (see Push)
+func (c *Cmd) ExpectPush(resp ...interface{}) *Cmd {
+	c.expect(response{append(Push{}, resp...), nil, nil})
+	return c
+}
+
+// Protocol returns the version of the protocol used by the connection, 2 or 3
+func (c *Conn) Protocol() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.protocol()
+}
+
+// SetProtocol switches the version of the protocol used by the connection,
+// like the HELLO command does. The connection always returns the RESP3 replies
+// (RESP3Map, Set, Double, BigNumber, VerbatimString and Push) converted to the
+// types decoded by redigo, but a Server writes them unchanged to the clients
+// using version 3
+func (c *Conn) SetProtocol(version int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.proto = version
+}
+
+// protocol returns the version of the protocol, 2 by default
+//
+// Caller must hold c.mu.
+func (c *Conn) protocol() int {
+	if c.proto == 3 {
+		return 3
+	}
+	return 2
+}
+
+// hello handles the HELLO command that wasn't registered, switching the
+// protocol version. If the command isn't HELLO ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) hello(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	if !strings.EqualFold(commandName, "HELLO") {
+		return replyElement{}, false
+	}
+
+	if len(args) > 0 {
+		version, err := strconv.Atoi(argString(args[0]))
+		if err != nil {
+			return replyElement{err: redis.Error("ERR Protocol version is not an integer or out of range")}, true
+		}
+		if version != 2 && version != 3 {
+			return replyElement{err: redis.Error("NOPROTO unsupported protocol version")}, true
+		}
+		c.proto = version
+	}
+
+	return replyElement{reply: helloReply(c.protocol(), 1, c.isReplica())}, true
+}
+
+// helloReply returns the server properties replied by HELLO
+func helloReply(version int, id int64, replica bool) RESP3Map {
+	role := "master"
+	if replica {
+		role = "replica"
+	}
+
+	return RESP3Map{
+		"server":  "redis",
+		"version": "7.0.0",
+		"proto":   int64(version),
+		"id":      id,
+		"mode":    "standalone",
+		"role":    role,
+		"modules": []interface{}{},
+	}
+}
+
+// protocolReply converts the RESP3 replies to the types of RESP2 replies
+// when the connection uses RESP2, used to write the replies of a Server
+//
+// Caller must hold c.mu.
+func (c *Conn) protocolReply(reply interface{}) interface{} {
+	if c.protocol() == 3 {
+		return reply
+	}
+	return redigoReply(reply)
+}
+
+// redigoReply converts the RESP3 replies to the types decoded by redigo, so
+// the helpers like redis.Strings or redis.Float64 accept them. Maps, sets and
+// pushes become slices, and the other replies bulk strings, like in RESP2
+func redigoReply(reply interface{}) interface{} {
+	if !hasRESP3Reply(reply) {
+		return reply
+	}
+	return resp2Reply(reply)
+}
+
+// hasRESP3Reply checks if the reply is, or contains, a RESP3 reply
+func hasRESP3Reply(reply interface{}) bool {
+	switch value := reply.(type) {
+	case RESP3Map, Set, Push, Double, BigNumber, VerbatimString:
+		return true
+	case []interface{}:
+		for _, item := range value {
+			if hasRESP3Reply(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resp2Reply converts the RESP3 replies, including the ones inside slices, to
+// the types of RESP2 replies, like the Redis server does for RESP2 clients
+func resp2Reply(reply interface{}) interface{} {
+	switch value := reply.(type) {
+	case RESP3Map:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, 0, len(value)*2)
+		for _, key := range keys {
+			values = append(values, []byte(key), resp2Reply(value[key]))
+		}
+		return values
+	case Set:
+		return resp2Slice(value)
+	case Push:
+		return resp2Slice(value)
+	case []interface{}:
+		return resp2Slice(value)
+	case Double:
+		return formatArg(float64(value))
+	case BigNumber:
+		return []byte(value.String())
+	case VerbatimString:
+		return []byte(value.Text)
+	}
+	return reply
+}
+
+// resp2Slice converts the items of the slice (see resp2Reply)
+func resp2Slice(items []interface{}) []interface{} {
+	if items == nil {
+		return nil
+	}
+
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = resp2Reply(item)
+	}
+	return values
+}
@@ -0,0 +1,207 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bufio"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestRESP3RepliesWithRESP2(t *testing.T) {
+	number, _ := new(big.Int).SetString("1234567890123456789012345678901234567890", 10)
+
+	conn := NewConn()
+	conn.Command("HGETALL", "person").ExpectRESP3Map(map[string]interface{}{
+		"name": []byte("alice"),
+		"age":  int64(30),
+	})
+	conn.Command("SMEMBERS", "tags").ExpectSet([]byte("a"), []byte("b"))
+	conn.Command("ZSCORE", "scores", "alice").ExpectDouble(1.5)
+	conn.Command("GET", "big").ExpectBigNumber(number)
+	conn.Command("INFO").ExpectVerbatim("txt", "# Server")
+	conn.Command("PUSH").ExpectPush([]byte("message"), []byte("news"), []byte("hello"))
+	conn.Command("NESTED").Expect([]interface{}{Double(0.5), Set{[]byte("a")}})
+
+	data := []struct {
+		command  string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"HGETALL", []interface{}{"person"}, []interface{}{[]byte("age"), int64(30), []byte("name"), []byte("alice")}},
+		{"SMEMBERS", []interface{}{"tags"}, bulks("a", "b")},
+		{"ZSCORE", []interface{}{"scores", "alice"}, []byte("1.5")},
+		{"GET", []interface{}{"big"}, []byte("1234567890123456789012345678901234567890")},
+		{"INFO", nil, []byte("# Server")},
+		{"PUSH", nil, bulks("message", "news", "hello")},
+		{"NESTED", nil, []interface{}{[]byte("0.5"), bulks("a")}},
+	}
+
+	for i, item := range data {
+		reply, err := conn.Do(item.command, item.args...)
+		if err != nil {
+			t.Errorf("Item %d: unexpected error '%s'", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(reply, item.expected) {
+			t.Errorf("Item %d: expected %#v and got %#v", i, item.expected, reply)
+		}
+	}
+
+	if score, err := redis.Float64(conn.Do("ZSCORE", "scores", "alice")); err != nil || score != 1.5 {
+		t.Errorf("Unexpected score %f (%v)", score, err)
+	}
+}
+
+func TestHelloSwitchesProtocol(t *testing.T) {
+	conn := NewConn()
+	conn.Command("ZSCORE", "scores", "alice").ExpectDouble(1.5)
+
+	if conn.Protocol() != 2 {
+		t.Errorf("Expected protocol 2 by default and got %d", conn.Protocol())
+	}
+
+	properties, err := redis.Values(conn.Do("HELLO", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto := helloProperty(properties, "proto"); proto != int64(3) {
+		t.Errorf("Expected proto 3 in HELLO reply and got %#v", proto)
+	}
+
+	if conn.Protocol() != 3 {
+		t.Errorf("Expected protocol 3 and got %d", conn.Protocol())
+	}
+
+	if score, err := redis.Float64(conn.Do("ZSCORE", "scores", "alice")); err != nil || score != 1.5 {
+		t.Errorf("Expected score 1.5 and got %f (%v)", score, err)
+	}
+
+	if _, err := conn.Do("HELLO", 4); !reflect.DeepEqual(err, redis.Error("NOPROTO unsupported protocol version")) {
+		t.Errorf("Expected NOPROTO error and got '%v'", err)
+	}
+
+	properties, err = redis.Values(conn.Do("HELLO", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto := helloProperty(properties, "proto"); proto != int64(2) {
+		t.Errorf("Expected proto 2 in HELLO reply and got %#v", proto)
+	}
+
+	conn.SetProtocol(3)
+	conn.Clear()
+	if conn.Protocol() != 2 {
+		t.Errorf("Expected protocol 2 after clearing the connection and got %d", conn.Protocol())
+	}
+}
+
+// helloProperty returns the value of the property in the flattened HELLO reply
+func helloProperty(properties []interface{}, name string) interface{} {
+	for i := 0; i+1 < len(properties); i += 2 {
+		if key, ok := properties[i].([]byte); ok && string(key) == name {
+			return properties[i+1]
+		}
+	}
+	return nil
+}
+
+func TestRESP3RepliesWithRedigoHelpers(t *testing.T) {
+	conn := NewConn()
+	conn.SetProtocol(3)
+	conn.Command("HGETALL", "person").ExpectRESP3Map(map[string]interface{}{
+		"name": []byte("alice"),
+		"city": []byte("paris"),
+	})
+	conn.Command("SMEMBERS", "tags").ExpectSet([]byte("a"), []byte("b"))
+	conn.Command("ZSCORE", "scores", "alice").ExpectDouble(1.5)
+	conn.Command("GET", "big").ExpectBigNumber(big.NewInt(42))
+	conn.Command("INFO").ExpectVerbatim("txt", "# Server")
+	conn.Command("PUSH").ExpectPush([]byte("message"), []byte("news"), []byte("hello"))
+
+	person, err := redis.StringMap(conn.Do("HGETALL", "person"))
+	if err != nil || !reflect.DeepEqual(person, map[string]string{"name": "alice", "city": "paris"}) {
+		t.Errorf("Unexpected map %#v (%v)", person, err)
+	}
+	if tags, err := redis.Strings(conn.Do("SMEMBERS", "tags")); err != nil || !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("Unexpected set %#v (%v)", tags, err)
+	}
+	if score, err := redis.Float64(conn.Do("ZSCORE", "scores", "alice")); err != nil || score != 1.5 {
+		t.Errorf("Unexpected double %f (%v)", score, err)
+	}
+	if number, err := redis.Int64(conn.Do("GET", "big")); err != nil || number != 42 {
+		t.Errorf("Unexpected big number %d (%v)", number, err)
+	}
+	if info, err := redis.String(conn.Do("INFO")); err != nil || info != "# Server" {
+		t.Errorf("Unexpected verbatim string '%s' (%v)", info, err)
+	}
+	if values, err := redis.Values(conn.Do("PUSH")); err != nil || len(values) != 3 {
+		t.Errorf("Unexpected push %#v (%v)", values, err)
+	}
+}
+
+func TestRESP3SubscriptionMessage(t *testing.T) {
+	conn := NewConn()
+	conn.AddSubscriptionMessage(Push{[]byte("message"), []byte("news"), []byte("hello")})
+
+	message, ok := redis.PubSubConn{Conn: conn}.Receive().(redis.Message)
+	if !ok || message.Channel != "news" || string(message.Data) != "hello" {
+		t.Errorf("Unexpected message %#v", message)
+	}
+}
+
+func TestServerRESP3Types(t *testing.T) {
+	s := newTestServer(t)
+	s.Command("ZSCORE", "scores", "alice").ExpectDouble(1.5)
+	s.Command("GET", "big").ExpectBigNumber(big.NewInt(12345))
+	s.Command("SMEMBERS", "tags").ExpectSet([]byte("a"))
+	s.Command("INFO").ExpectVerbatim("txt", "# Server")
+
+	netConn, err := net.Dial(s.Network(), s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netConn.Close()
+	netConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(netConn)
+	readLines := func(n int) string {
+		var lines []string
+		for i := 0; i < n; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\r\n"))
+		}
+		return strings.Join(lines, " ")
+	}
+
+	data := []struct {
+		command  string
+		lines    int
+		expected string
+	}{
+		{"ZSCORE scores alice", 2, "$3 1.5"},
+		{"HELLO 3", 22, ""},
+		{"ZSCORE scores alice", 1, ",1.5"},
+		{"GET big", 1, "(12345"},
+		{"SMEMBERS tags", 3, "~1 $1 a"},
+		{"INFO", 2, "=12 txt:# Server"},
+	}
+
+	for i, item := range data {
+		netConn.Write([]byte(item.command + "\r\n"))
+		if lines := readLines(item.lines); item.expected != "" && lines != item.expected {
+			t.Errorf("Item %d: expected '%s' and got '%s'", i, item.expected, lines)
+		}
+	}
+}
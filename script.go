@@ -0,0 +1,162 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// scriptCache stores Lua scripts by SHA1 digest, like the Redis server does
+// for scripts sent with SCRIPT LOAD or EVAL
+type scriptCache map[string]string
+
+// scriptSHA returns the SHA1 digest of the script, used by EVALSHA
+func scriptSHA(script []byte) string {
+	h := sha1.New()
+	h.Write(script)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load stores the script, returning its SHA1 digest
+func (s scriptCache) load(script []byte) string {
+	sha := scriptSHA(script)
+	s[sha] = string(script)
+	return sha
+}
+
+// get returns the script with the SHA1 digest, ignoring the letter case like
+// the Redis server
+func (s scriptCache) get(sha string) (string, bool) {
+	script, ok := s[strings.ToLower(sha)]
+	return script, ok
+}
+
+// withScripts calls the function with the scripts loaded in the connection.
+// When the connection uses a key space, its scripts are used instead, so
+// scripts loaded by the connection can be executed by the key space
+//
+// Caller must hold c.mu.
+func (c *Conn) withScripts(f func(scripts scriptCache)) {
+	if ks := c.currentKeySpace(); ks != nil {
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
+		f(ks.scripts)
+		return
+	}
+
+	if c.scripts == nil {
+		c.scripts = make(scriptCache)
+	}
+	f(c.scripts)
+}
+
+// findEval looks for a script registered with Script that matches an EVAL
+// command, which sends the script text instead of its SHA1 digest. The script
+// is loaded when found, like the Redis server does
+//
+// Caller must hold c.mu.
+func (c *Conn) findEval(commandName string, args []interface{}) *Cmd {
+	if !strings.EqualFold(commandName, "EVAL") || len(args) == 0 {
+		return nil
+	}
+
+	script := formatArg(args[0])
+
+	shaArgs := make([]interface{}, len(args))
+	copy(shaArgs, args)
+	shaArgs[0] = scriptSHA(script)
+
+	cmd := c.find("EVALSHA", shaArgs)
+	if cmd != nil {
+		c.withScripts(func(scripts scriptCache) {
+			scripts.load(script)
+		})
+	}
+	return cmd
+}
+
+// scriptLoaded checks if the script executed by an EVALSHA command was
+// loaded. Scripts are only checked when the connection requires them to be
+// loaded (see RequireScriptLoad)
+//
+// Caller must hold c.mu.
+func (c *Conn) scriptLoaded(commandName string, args []interface{}) bool {
+	if !c.RequireScriptLoad || !strings.EqualFold(commandName, "EVALSHA") || len(args) == 0 {
+		return true
+	}
+
+	loaded := false
+	c.withScripts(func(scripts scriptCache) {
+		_, loaded = scripts.get(argString(args[0]))
+	})
+	return loaded
+}
+
+// scriptCommand handles the SCRIPT LOAD, SCRIPT EXISTS and SCRIPT FLUSH
+// commands that weren't registered, updating the scripts loaded in the
+// connection. If the command isn't one of them ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) scriptCommand(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	if !strings.EqualFold(commandName, "SCRIPT") || len(args) == 0 {
+		return replyElement{}, false
+	}
+
+	subcommand := strings.ToLower(argString(args[0]))
+	args = args[1:]
+
+	switch subcommand {
+	case "load":
+		if len(args) != 1 {
+			return replyElement{err: errWrongArgs("script|load")}, true
+		}
+
+		c.withScripts(func(scripts scriptCache) {
+			reply.reply = []byte(scripts.load(formatArg(args[0])))
+		})
+
+	case "exists":
+		if len(args) == 0 {
+			return replyElement{err: errWrongArgs("script|exists")}, true
+		}
+
+		exists := make([]interface{}, len(args))
+		c.withScripts(func(scripts scriptCache) {
+			for i, arg := range args {
+				exists[i] = int64(0)
+				if _, ok := scripts.get(argString(arg)); ok {
+					exists[i] = int64(1)
+				}
+			}
+		})
+		reply.reply = exists
+
+	case "flush":
+		if len(args) > 1 {
+			return replyElement{err: errWrongArgs("script|flush")}, true
+		}
+		if len(args) == 1 {
+			if mode := strings.ToLower(argString(args[0])); mode != "async" && mode != "sync" {
+				return replyElement{err: redis.Error("ERR SCRIPT FLUSH only support SYNC|ASYNC option")}, true
+			}
+		}
+
+		c.withScripts(func(scripts scriptCache) {
+			for sha := range scripts {
+				delete(scripts, sha)
+			}
+		})
+		reply.reply = "OK"
+
+	default:
+		return replyElement{}, false
+	}
+	return reply, true
+}
@@ -0,0 +1,132 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestScriptMatchesEval(t *testing.T) {
+	source := "return redis.call('GET', KEYS[1])"
+
+	conn := NewConn()
+	cmd := conn.Script([]byte(source), 1, "key").Expect("value")
+
+	script := redis.NewScript(1, source)
+	if err := script.Load(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := redis.String(conn.Do("EVAL", source, 1, "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected EVAL reply '%s' (%v)", value, err)
+	}
+
+	if value, err := redis.String(conn.Do("eval", source, 1, "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected lowercase EVAL reply '%s' (%v)", value, err)
+	}
+
+	if value, err := redis.String(script.Do(conn, "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected EVALSHA reply '%s' (%v)", value, err)
+	}
+
+	if counter := conn.Stats(cmd); counter != 3 {
+		t.Errorf("Expected script to be called 3 times and got %d", counter)
+	}
+}
+
+func TestRequireScriptLoad(t *testing.T) {
+	source := "return ARGV[1]"
+
+	conn := NewConn()
+	conn.RequireScriptLoad = true
+	cmd := conn.Script([]byte(source), 0, "a").Expect("a")
+
+	script := redis.NewScript(0, source)
+	if _, err := conn.Do("EVALSHA", script.Hash(), 0, "a"); !reflect.DeepEqual(err, NoScriptError()) {
+		t.Errorf("Expected NOSCRIPT error and got '%v'", err)
+	}
+
+	// redis.Script falls back to EVAL, loading the script
+	if value, err := redis.String(script.Do(conn, "a")); err != nil || value != "a" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+
+	if value, err := redis.String(conn.Do("EVALSHA", script.Hash(), 0, "a")); err != nil || value != "a" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+
+	if counter := conn.Stats(cmd); counter != 2 {
+		t.Errorf("Expected script to be called 2 times and got %d", counter)
+	}
+
+	if _, err := conn.Do("SCRIPT", "FLUSH"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Do("EVALSHA", script.Hash(), 0, "a"); !reflect.DeepEqual(err, NoScriptError()) {
+		t.Errorf("Expected NOSCRIPT error after flush and got '%v'", err)
+	}
+
+	if err := script.Load(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := redis.String(conn.Do("EVALSHA", script.Hash(), 0, "a")); err != nil || value != "a" {
+		t.Errorf("Unexpected reply after load '%s' (%v)", value, err)
+	}
+}
+
+func TestScriptCommands(t *testing.T) {
+	conn := NewConn()
+	script := redis.NewScript(0, "return 1")
+
+	sha, err := redis.String(conn.Do("SCRIPT", "LOAD", "return 1"))
+	if err != nil || sha != script.Hash() {
+		t.Errorf("Unexpected SHA1 '%s' (%v)", sha, err)
+	}
+
+	exists, err := redis.Ints(conn.Do("SCRIPT", "EXISTS", script.Hash(), "unknown"))
+	if err != nil || !reflect.DeepEqual(exists, []int{1, 0}) {
+		t.Errorf("Unexpected exists reply %v (%v)", exists, err)
+	}
+
+	if _, err := conn.Do("SCRIPT", "FLUSH", "WRONG"); err == nil {
+		t.Error("Expected error for invalid flush mode")
+	}
+
+	if reply, err := conn.Do("SCRIPT", "FLUSH", "ASYNC"); err != nil || reply != "OK" {
+		t.Errorf("Unexpected flush reply %v (%v)", reply, err)
+	}
+
+	exists, err = redis.Ints(conn.Do("SCRIPT", "EXISTS", script.Hash()))
+	if err != nil || !reflect.DeepEqual(exists, []int{0}) {
+		t.Errorf("Unexpected exists reply after flush %v (%v)", exists, err)
+	}
+
+	if _, err := conn.Do("SCRIPT", "LOAD"); !reflect.DeepEqual(err, errWrongArgs("script|load")) {
+		t.Errorf("Expected wrong number of arguments error and got '%v'", err)
+	}
+
+	if _, err := conn.Do("SCRIPT", "KILL"); err == nil {
+		t.Error("Expected error for a subcommand not registered")
+	}
+}
+
+func TestScriptLoadWithKeySpace(t *testing.T) {
+	conn := NewConn()
+	conn.UseKeySpace(NewKeySpace())
+
+	script := redis.NewScript(0, "return 'loaded'")
+	if err := script.Load(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := redis.String(conn.Do("EVALSHA", script.Hash(), 0)); err != nil || value != "loaded" {
+		t.Errorf("Unexpected reply '%s' (%v)", value, err)
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// switchMasterChannel is the channel where sentinels announce failovers
+const switchMasterChannel = "+switch-master"
+
+// Sentinel simulates a Redis Sentinel monitoring groups of nodes, where each
+// node is a Pool with its own registered commands. The sentinel answers the
+// SENTINEL and ROLE commands with the monitored topology, unless they are
+// registered, and the nodes have their replication role in the group (see
+// Conn.ReplicaOf), so the replicas reject write commands. A
+// failover (see Failover) promotes a replica and publishes the +switch-master
+// message to the sentinel connections subscribed to it.
+//
+// Connections to the sentinel are dialed with the embedded Pool, and the ones
+// to any node with Dial.
+type Sentinel struct {
+	*Pool                     // Pool where the commands answered by the sentinel are registered
+	Addr    string            // Sentinel address
+	masters []*SentinelMaster // Monitored groups in the order they were added
+	peers   []string          // Addresses of other sentinels monitoring the groups
+	mu      sync.Mutex        // Hold while accessing any mutable fields
+}
+
+// SentinelMaster is a group of nodes monitored by the sentinel, with a master
+// and its replicas
+type SentinelMaster struct {
+	Name     string // Name used by clients to discover the master
+	Quorum   int    // Quorum reported by the sentinel, 2 by default
+	master   *SentinelNode
+	replicas []*SentinelNode
+	sentinel *Sentinel
+}
+
+// SentinelNode is a node of a group monitored by the sentinel. Commands
+// answered by the node are registered in the embedded Pool
+type SentinelNode struct {
+	*Pool        // Pool where the commands answered by the node are registered
+	Addr  string // Node address, returned by the sentinel
+}
+
+// NewSentinel returns a sentinel with the address that doesn't monitor any
+// group yet (see Monitor)
+func NewSentinel(addr string) *Sentinel {
+	s := &Sentinel{
+		Pool: NewPool(),
+		Addr: addr,
+	}
+	s.Conn.sentinel = s
+	return s
+}
+
+// Monitor adds a group of nodes with the name to the sentinel, with the master
+// and replicas in the addresses
+func (s *Sentinel) Monitor(name, masterAddr string, replicaAddrs ...string) *SentinelMaster {
+	m := &SentinelMaster{
+		Name:     name,
+		Quorum:   2,
+		sentinel: s,
+	}
+
+	m.master = newSentinelNode(masterAddr)
+	for _, addr := range replicaAddrs {
+		m.replicas = append(m.replicas, newSentinelNode(addr))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.setRoles()
+	s.masters = append(s.masters, m)
+	return m
+}
+
+// AddSentinel adds the address of another sentinel monitoring the groups,
+// returned by SENTINEL SENTINELS
+func (s *Sentinel) AddSentinel(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.peers = append(s.peers, addr)
+}
+
+// Master returns the group with the name, or nil if there's no such group
+func (s *Sentinel) Master(name string) *SentinelMaster {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.group(name)
+}
+
+// Dial returns a connection to the sentinel or to a node of the monitored
+// groups with the address, with the same signature of redis.Dial, so it can
+// replace it in sentinel clients. The options are ignored
+func (s *Sentinel) Dial(network, addr string, options ...redis.DialOption) (redis.Conn, error) {
+	if addr == s.Addr {
+		return s.Pool.Dial()
+	}
+
+	s.mu.Lock()
+	var node *SentinelNode
+	for _, m := range s.masters {
+		if node = m.node(addr); node != nil {
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if node == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("unknown sentinel node %s", addr)}
+	}
+	return node.Dial()
+}
+
+// Failover promotes the replica with the address to master of the group,
+// turning the previous master into a replica, and publishes the
+// +switch-master message. When the address is empty the first replica is
+// promoted
+func (s *Sentinel) Failover(name, addr string) error {
+	s.mu.Lock()
+	msg, err := s.failover(name, addr)
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	s.Publish(switchMasterChannel, msg)
+	return nil
+}
+
+// failover switches the master of the group, returning the +switch-master
+// message
+//
+// Caller must hold s.mu.
+func (s *Sentinel) failover(name, addr string) (string, error) {
+	m := s.group(name)
+	if m == nil {
+		return "", redis.Error("ERR No such master with that name")
+	}
+
+	promoted := -1
+	for i, replica := range m.replicas {
+		if addr == "" || replica.Addr == addr {
+			promoted = i
+			break
+		}
+	}
+	if promoted < 0 {
+		return "", redis.Error("NOGOODSLAVE No suitable replica to promote")
+	}
+
+	old := m.master
+	m.master = m.replicas[promoted]
+	m.replicas[promoted] = old
+	m.setRoles()
+
+	oldHost, oldPort := splitAddr(old.Addr)
+	newHost, newPort := splitAddr(m.master.Addr)
+	return fmt.Sprintf("%s %s %d %s %d", m.Name, oldHost, oldPort, newHost, newPort), nil
+}
+
+// group returns the group with the name, or nil if there's no such group
+//
+// Caller must hold s.mu.
+func (s *Sentinel) group(name string) *SentinelMaster {
+	for _, m := range s.masters {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// command answers the SENTINEL subcommands with the monitored topology,
+// executed by conn. If the subcommand isn't supported ok is false
+//
+// Caller must hold conn.mu.
+func (s *Sentinel) command(conn *Conn, args []interface{}) (reply replyElement, ok bool) {
+	if len(args) == 0 {
+		return replyElement{}, false
+	}
+
+	subcommand := strings.ToLower(argString(args[0]))
+	switch subcommand {
+	case "masters":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		masters := []interface{}{}
+		for _, m := range s.masters {
+			masters = append(masters, m.masterInfo())
+		}
+		return replyElement{reply: masters}, true
+
+	case "get-master-addr-by-name", "master", "replicas", "slaves", "sentinels":
+		if len(args) != 2 {
+			return replyElement{err: errWrongArgs("sentinel|" + subcommand)}, true
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		m := s.group(argString(args[1]))
+		if m == nil {
+			if subcommand == "get-master-addr-by-name" {
+				return replyElement{}, true
+			}
+			return replyElement{err: redis.Error("ERR No such master with that name")}, true
+		}
+
+		switch subcommand {
+		case "get-master-addr-by-name":
+			host, port := splitAddr(m.master.Addr)
+			return replyElement{reply: []interface{}{[]byte(host), []byte(strconv.FormatInt(port, 10))}}, true
+		case "master":
+			return replyElement{reply: m.masterInfo()}, true
+		case "replicas", "slaves":
+			replicas := []interface{}{}
+			for _, replica := range m.replicas {
+				replicas = append(replicas, m.replicaInfo(replica))
+			}
+			return replyElement{reply: replicas}, true
+		}
+
+		sentinels := []interface{}{}
+		for _, addr := range s.peers {
+			sentinels = append(sentinels, nodeInfo(addr, "sentinel"))
+		}
+		return replyElement{reply: sentinels}, true
+
+	case "failover":
+		if len(args) != 2 {
+			return replyElement{err: errWrongArgs("sentinel|failover")}, true
+		}
+
+		s.mu.Lock()
+		msg, err := s.failover(argString(args[1]), "")
+		s.mu.Unlock()
+
+		if err != nil {
+			return replyElement{err: err}, true
+		}
+
+		// the message is delivered once the connection executing the command
+		// is unlocked, as it may be one of the subscribers
+		conn.runUnlocked(func() { s.Publish(switchMasterChannel, msg) })
+		return replyElement{reply: "OK"}, true
+	}
+	return replyElement{}, false
+}
+
+// role returns the reply of ROLE for the sentinel
+func (s *Sentinel) role() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := []interface{}{}
+	for _, m := range s.masters {
+		names = append(names, []byte(m.Name))
+	}
+	return []interface{}{[]byte("sentinel"), names}
+}
+
+// newSentinelNode returns a node of a group with the address
+func newSentinelNode(addr string) *SentinelNode {
+	return &SentinelNode{
+		Pool: NewPool(),
+		Addr: addr,
+	}
+}
+
+// setRoles sets the replication role of the nodes (see Conn.ReplicaOf), so
+// they answer ROLE and INFO replication and the replicas reject writes
+//
+// Caller must hold m.sentinel.mu.
+func (m *SentinelMaster) setRoles() {
+	addrs := make([]string, len(m.replicas))
+	for i, replica := range m.replicas {
+		replica.ReplicaOf(m.master.Addr)
+		replica.SetReplicas()
+		addrs[i] = replica.Addr
+	}
+
+	m.master.ReplicaOf("")
+	m.master.SetReplicas(addrs...)
+}
+
+// Master returns the current master of the group
+func (m *SentinelMaster) Master() *SentinelNode {
+	m.sentinel.mu.Lock()
+	defer m.sentinel.mu.Unlock()
+
+	return m.master
+}
+
+// Replicas returns the current replicas of the group
+func (m *SentinelMaster) Replicas() []*SentinelNode {
+	m.sentinel.mu.Lock()
+	defer m.sentinel.mu.Unlock()
+
+	replicas := make([]*SentinelNode, len(m.replicas))
+	copy(replicas, m.replicas)
+	return replicas
+}
+
+// Node returns the node of the group with the address, or nil if there's no
+// such node
+func (m *SentinelMaster) Node(addr string) *SentinelNode {
+	m.sentinel.mu.Lock()
+	defer m.sentinel.mu.Unlock()
+
+	return m.node(addr)
+}
+
+// node returns the node of the group with the address
+//
+// Caller must hold m.sentinel.mu.
+func (m *SentinelMaster) node(addr string) *SentinelNode {
+	if m.master.Addr == addr {
+		return m.master
+	}
+	for _, replica := range m.replicas {
+		if replica.Addr == addr {
+			return replica
+		}
+	}
+	return nil
+}
+
+// masterInfo returns the master description of SENTINEL MASTER
+//
+// Caller must hold m.sentinel.mu.
+func (m *SentinelMaster) masterInfo() RESP3Map {
+	info := nodeInfo(m.master.Addr, "master")
+	info["name"] = []byte(m.Name)
+	info["num-slaves"] = []byte(strconv.Itoa(len(m.replicas)))
+	info["num-other-sentinels"] = []byte(strconv.Itoa(len(m.sentinel.peers)))
+	info["quorum"] = []byte(strconv.Itoa(m.Quorum))
+	return info
+}
+
+// replicaInfo returns the replica description of SENTINEL REPLICAS
+//
+// Caller must hold m.sentinel.mu.
+func (m *SentinelMaster) replicaInfo(replica *SentinelNode) RESP3Map {
+	host, port := splitAddr(m.master.Addr)
+
+	info := nodeInfo(replica.Addr, "slave")
+	info["master-host"] = []byte(host)
+	info["master-port"] = []byte(strconv.FormatInt(port, 10))
+	info["master-link-status"] = []byte("ok")
+	return info
+}
+
+// nodeInfo returns the fields describing a node in the SENTINEL replies
+func nodeInfo(addr, flags string) RESP3Map {
+	digest := sha1.Sum([]byte(addr))
+	host, port := splitAddr(addr)
+
+	return RESP3Map{
+		"name":  []byte(addr),
+		"ip":    []byte(host),
+		"port":  []byte(strconv.FormatInt(port, 10)),
+		"runid": []byte(hex.EncodeToString(digest[:])),
+		"flags": []byte(flags),
+	}
+}
+
+// sentinelCommand handles the SENTINEL and ROLE commands that weren't
+// registered. If the connection isn't a sentinel or the command isn't
+// supported ok is false
+//
+// Caller must hold c.mu.
+func (c *Conn) sentinelCommand(commandName string, args []interface{}) (reply replyElement, ok bool) {
+	s := fromRegistry(c, func(conn *Conn) *Sentinel {
+		return conn.sentinel
+	})
+
+	switch {
+	case s != nil && strings.EqualFold(commandName, "SENTINEL"):
+		return s.command(c, args)
+	case s != nil && strings.EqualFold(commandName, "ROLE"):
+		return replyElement{reply: s.role()}, true
+	}
+	return replyElement{}, false
+}
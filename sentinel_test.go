@@ -0,0 +1,164 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelMasterAddr(t *testing.T) {
+	sentinel := NewSentinel("127.0.0.1:26379")
+	sentinel.Monitor("mymaster", "127.0.0.1:6379", "127.0.0.1:6380", "127.0.0.1:6381")
+	sentinel.AddSentinel("127.0.0.1:26380")
+
+	conn, err := sentinel.Dial("tcp", "127.0.0.1:26379")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", "mymaster"))
+	if err != nil || !reflect.DeepEqual(addr, []string{"127.0.0.1", "6379"}) {
+		t.Errorf("Unexpected master address %v (%v)", addr, err)
+	}
+
+	if reply, err := conn.Do("SENTINEL", "get-master-addr-by-name", "unknown"); reply != nil || err != nil {
+		t.Errorf("Expected nil reply for unknown master and got %v (%v)", reply, err)
+	}
+
+	replicas, err := redis.Values(conn.Do("SENTINEL", "replicas", "mymaster"))
+	if err != nil || len(replicas) != 2 {
+		t.Fatalf("Unexpected replicas %v (%v)", replicas, err)
+	}
+
+	replica, err := redis.StringMap(replicas[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replica["name"] != "127.0.0.1:6380" || replica["flags"] != "slave" || replica["master-port"] != "6379" {
+		t.Errorf("Unexpected replica %v", replica)
+	}
+
+	sentinels, err := redis.Values(conn.Do("SENTINEL", "sentinels", "mymaster"))
+	if err != nil || len(sentinels) != 1 {
+		t.Errorf("Unexpected sentinels %v (%v)", sentinels, err)
+	}
+
+	if _, err := conn.Do("SENTINEL", "replicas", "unknown"); err == nil {
+		t.Error("Expected error for unknown master")
+	}
+
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil || !reflect.DeepEqual(role, []interface{}{[]byte("sentinel"), []interface{}{[]byte("mymaster")}}) {
+		t.Errorf("Unexpected ROLE reply %v (%v)", role, err)
+	}
+}
+
+func TestSentinelNodeRole(t *testing.T) {
+	sentinel := NewSentinel("127.0.0.1:26379")
+	sentinel.Monitor("mymaster", "127.0.0.1:6379", "127.0.0.1:6380")
+
+	master, err := sentinel.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := redis.Values(master.Do("ROLE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{
+		[]byte("master"),
+		int64(0),
+		[]interface{}{[]interface{}{[]byte("127.0.0.1"), []byte("6380"), []byte("0")}},
+	}
+	if !reflect.DeepEqual(role, expected) {
+		t.Errorf("Unexpected master ROLE reply %v", role)
+	}
+
+	replica, err := sentinel.Dial("tcp", "127.0.0.1:6380")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, err = redis.Values(replica.Do("ROLE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []interface{}{[]byte("slave"), []byte("127.0.0.1"), int64(6379), []byte("connected"), int64(0)}
+	if !reflect.DeepEqual(role, expected) {
+		t.Errorf("Unexpected replica ROLE reply %v", role)
+	}
+
+	if _, err := replica.Do("SET", "key", "value"); err != ReadOnlyError() {
+		t.Errorf("Expected READONLY error in replica and got %v", err)
+	}
+
+	if _, err := sentinel.Dial("tcp", "127.0.0.1:6390"); err == nil {
+		t.Error("Expected error when dialing unknown node")
+	}
+}
+
+func TestSentinelFailover(t *testing.T) {
+	sentinel := NewSentinel("127.0.0.1:26379")
+	group := sentinel.Monitor("mymaster", "127.0.0.1:6379", "127.0.0.1:6380", "127.0.0.1:6381")
+
+	conn, err := sentinel.Dial("tcp", "127.0.0.1:26379")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	psc := redis.PubSubConn{Conn: conn}
+	psc.Subscribe("+switch-master")
+	psc.Receive()
+
+	if err := sentinel.Failover("mymaster", "127.0.0.1:6381"); err != nil {
+		t.Fatal(err)
+	}
+
+	message := redis.Message{Channel: "+switch-master", Data: []byte("mymaster 127.0.0.1 6379 127.0.0.1 6381")}
+	if msg := psc.Receive(); !reflect.DeepEqual(msg, message) {
+		t.Errorf("Expected message '%#v' and got '%#v'", message, msg)
+	}
+
+	if master := group.Master(); master.Addr != "127.0.0.1:6381" {
+		t.Errorf("Expected master 127.0.0.1:6381 and got %s", master.Addr)
+	}
+	if node := group.Node("127.0.0.1:6379"); node == nil || node == group.Master() {
+		t.Error("Expected previous master to become a replica")
+	}
+	if len(group.Replicas()) != 2 {
+		t.Errorf("Expected 2 replicas and got %d", len(group.Replicas()))
+	}
+
+	if err := sentinel.Failover("mymaster", "127.0.0.1:6390"); err == nil {
+		t.Error("Expected error when promoting unknown replica")
+	}
+	if err := sentinel.Failover("unknown", ""); err == nil {
+		t.Error("Expected error when failing over unknown master")
+	}
+
+	other, err := sentinel.Dial("tcp", "127.0.0.1:26379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Do("SENTINEL", "failover", "mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if master := group.Master(); master.Addr != "127.0.0.1:6380" {
+		t.Errorf("Expected master 127.0.0.1:6380 after SENTINEL FAILOVER and got %s", master.Addr)
+	}
+
+	// the message must be delivered when the command returns
+	message = redis.Message{Channel: "+switch-master", Data: []byte("mymaster 127.0.0.1 6381 127.0.0.1 6380")}
+	if msg := psc.ReceiveWithTimeout(time.Nanosecond); !reflect.DeepEqual(msg, message) {
+		t.Errorf("Expected message '%#v' and got '%#v'", message, msg)
+	}
+}
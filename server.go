@@ -0,0 +1,501 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Server exposes mocked connections to clients speaking the Redis protocol
+// (RESP2 and RESP3), so code that dials an address, or clients written in
+// other languages, can be tested against the registered commands. Commands are
+// registered in the embedded Conn and are shared by all clients, while each
+// client has its own connection state, like in Pool. Arguments received from
+// clients are strings, so they are matched as sent to the Redis server (the
+// registered integer 10 matches the argument "10").
+//
+// The AUTH, HELLO, SELECT, QUIT and CLIENT SETNAME/GETNAME/ID commands are
+// handled by the server. SELECT only validates the database index, as all
+// databases share the same key space.
+type Server struct {
+	*Conn                       // Connection where the commands are registered
+	password string             // When set, clients must authenticate with AUTH or HELLO
+	listener net.Listener       // Listener accepting the clients
+	clients  []*serverClient    // Connected clients, including the ones already disconnected
+	lastID   int64              // Identifier of the last connected client
+	closed   bool               // State for this server closed or not, rejecting new clients
+	ctx      context.Context    // Done when the server is closed
+	cancel   context.CancelFunc // Closes the server context
+	wg       sync.WaitGroup     // Waits for the client goroutines
+	mu       sync.Mutex         // Hold while accessing any mutable fields
+}
+
+// serverClient is a client connected to the server
+type serverClient struct {
+	server        *Server    // Server that accepted the client
+	conn          *Conn      // Mocked connection executing the commands
+	netConn       net.Conn   // Network connection of the client
+	reader        respReader // Reads the commands of the client
+	writer        respWriter // Writes the replies to the client
+	id            int64      // Identifier of the client, returned by CLIENT ID
+	name          string     // Name set by CLIENT SETNAME
+	authenticated bool       // State for this client authenticated or not
+	mu            sync.Mutex // Hold while writing to the client
+}
+
+// NewServer returns a server listening on the given network address, like
+// "127.0.0.1:0" for a random loopback port or a file path for a Unix socket.
+// Clients can dial the address returned by the Addr method
+func NewServer(network, address string) (*Server, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		Conn:     NewConn(),
+		listener: listener,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// SetPassword makes the clients authenticate with AUTH or HELLO before
+// sending commands. An empty password disables the authentication for new
+// clients
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.password = password
+}
+
+// checkPassword checks if the password is the one set in the server
+func (s *Server) checkPassword(password string) (ok, required bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return password == s.password, s.password != ""
+}
+
+// Network returns the network of the server address, "tcp" or "unix"
+func (s *Server) Network() string {
+	return s.listener.Addr().Network()
+}
+
+// Addr returns the address where the server is listening
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, disconnecting all clients
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.cancel()
+	err := s.listener.Close()
+	clients := make([]*serverClient, len(s.clients))
+	copy(clients, s.clients)
+	s.mu.Unlock()
+
+	for _, client := range clients {
+		client.netConn.Close()
+	}
+
+	s.wg.Wait()
+	return err
+}
+
+// Conns returns the connections of the clients, in the order they connected
+func (s *Server) Conns() []*Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*Conn, len(s.clients))
+	for i, client := range s.clients {
+		conns[i] = client.conn
+	}
+	return conns
+}
+
+// Publish delivers a message to the subscribed clients, returning the number
+// of delivered messages like the PUBLISH command (see Conn.Publish)
+func (s *Server) Publish(channel string, payload interface{}) int {
+	s.mu.Lock()
+	clients := make([]*serverClient, len(s.clients))
+	copy(clients, s.clients)
+	s.mu.Unlock()
+
+	delivered := 0
+	for _, client := range clients {
+		if n := client.conn.Publish(channel, payload); n > 0 {
+			delivered += n
+			client.deliver()
+		}
+	}
+	return delivered
+}
+
+// Stats returns the number of times that a command was called by all clients
+func (s *Server) Stats(cmd *Cmd) int {
+	total := s.Conn.Stats(cmd)
+	for _, conn := range s.Conns() {
+		total += conn.Stats(cmd)
+	}
+	return total
+}
+
+// ExpectationsWereMet works like the Conn method, but checks the
+// expectations in the connections of all clients
+func (s *Server) ExpectationsWereMet() error {
+	errMsg := ""
+	if err := s.Conn.ExpectationsWereMet(); err != nil {
+		errMsg = err.Error()
+	}
+
+	for i, conn := range s.Conns() {
+		if err := conn.ExpectationsWereMet(); err != nil {
+			errMsg = fmt.Sprintf("%sClient %d: %s", errMsg, i, err.Error())
+		}
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf("%s", errMsg)
+	}
+
+	return nil
+}
+
+// accept accepts the clients until the server is closed
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		conn := NewConn()
+		conn.registry = s.Conn
+		conn.wire = true
+
+		s.mu.Lock()
+		if s.closed {
+			// accepted while the server was closed
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+
+		s.lastID++
+		client := &serverClient{
+			server:        s,
+			conn:          conn,
+			netConn:       netConn,
+			reader:        respReader{r: bufio.NewReader(netConn)},
+			writer:        respWriter{w: bufio.NewWriter(netConn), proto: 2},
+			id:            s.lastID,
+			authenticated: s.password == "",
+		}
+		s.clients = append(s.clients, client)
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go client.serve()
+	}
+}
+
+// serve reads the commands of the client until it disconnects
+func (c *serverClient) serve() {
+	defer c.server.wg.Done()
+	defer c.conn.Close()
+	defer c.netConn.Close()
+
+	for {
+		command, err := c.reader.readCommand()
+		if err != nil {
+			if protocolErr, ok := err.(errProtocol); ok {
+				c.mu.Lock()
+				c.writer.writeError(protocolErr)
+				c.writer.w.Flush()
+				c.mu.Unlock()
+			}
+			return
+		}
+
+		if len(command) == 0 {
+			continue
+		}
+
+		if !c.handle(command[0], command[1:]) {
+			return
+		}
+	}
+}
+
+// handle executes the command, writing its replies. It returns false when the
+// client must be disconnected
+func (c *serverClient) handle(commandName string, args []string) bool {
+	replies, err := c.execute(commandName, args)
+	if err != nil {
+		// failures injected in the connection disconnect the client
+		return false
+	}
+
+	if err := c.conn.wait(c.server.ctx, 0); err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	push := isSubscriptionCommand(commandName)
+	for _, reply := range replies {
+		if push && reply.err == nil {
+			c.writer.writePush(reply.reply)
+			continue
+		}
+		c.writer.writeReply(reply.reply, reply.err)
+	}
+	c.writeMessages()
+
+	// pipelined commands are answered together
+	if c.reader.r.Buffered() == 0 {
+		if err := c.writer.w.Flush(); err != nil {
+			return false
+		}
+	}
+	return !strings.EqualFold(commandName, "quit")
+}
+
+// deliver writes the pending subscription messages to the client
+func (c *serverClient) deliver() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeMessages()
+	c.writer.w.Flush()
+}
+
+// writeMessages writes the pending subscription messages of the connection
+//
+// Caller must hold c.mu.
+func (c *serverClient) writeMessages() {
+	for _, message := range c.conn.takeMessages() {
+		if message.err != nil {
+			c.writer.writeError(message.err)
+			continue
+		}
+		c.writer.writePush(message.reply)
+	}
+}
+
+// execute runs the command, handling the connection commands in the server
+// and dispatching the other ones to the mocked connection. An error is
+// returned when the client must be disconnected
+func (c *serverClient) execute(commandName string, args []string) ([]replyElement, error) {
+	name := strings.ToLower(commandName)
+	switch name {
+	case "auth":
+		return []replyElement{c.auth(args)}, nil
+	case "hello":
+		return []replyElement{c.hello(args)}, nil
+	case "quit":
+		return []replyElement{{reply: "OK"}}, nil
+	}
+
+	if !c.authenticated {
+		return []replyElement{{err: NoAuthError()}}, nil
+	}
+
+	switch name {
+	case "select":
+		return []replyElement{c.selectDB(args)}, nil
+	case "client":
+		if reply, ok := c.client(args); ok {
+			return []replyElement{reply}, nil
+		}
+	}
+
+	wireArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		wireArgs[i] = arg
+	}
+	return c.conn.serve(commandName, wireArgs)
+}
+
+// auth handles the AUTH command, accepting only the default user
+func (c *serverClient) auth(args []string) replyElement {
+	if len(args) == 0 || len(args) > 2 {
+		return replyElement{err: errWrongArgs("auth")}
+	}
+
+	if _, required := c.server.checkPassword(""); !required {
+		return replyElement{err: redis.Error("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")}
+	}
+
+	user, password := "default", args[0]
+	if len(args) == 2 {
+		user, password = args[0], args[1]
+	}
+
+	if !c.login(user, password) {
+		return replyElement{err: redis.Error("WRONGPASS invalid username-password pair or user is disabled.")}
+	}
+	return replyElement{reply: "OK"}
+}
+
+// login authenticates the client, checking the credentials
+func (c *serverClient) login(user, password string) bool {
+	if ok, _ := c.server.checkPassword(password); !ok || user != "default" {
+		return false
+	}
+
+	c.authenticated = true
+	return true
+}
+
+// hello handles the HELLO command, which switches the protocol version,
+// optionally authenticating the client and setting its name
+func (c *serverClient) hello(args []string) replyElement {
+	proto := c.writer.proto
+	if len(args) > 0 {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return replyElement{err: redis.Error("ERR Protocol version is not an integer or out of range")}
+		}
+		if version != 2 && version != 3 {
+			return replyElement{err: redis.Error("NOPROTO unsupported protocol version")}
+		}
+		proto = version
+	}
+
+	name := c.name
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToLower(args[i]); {
+		case option == "auth" && i+2 < len(args):
+			if !c.login(args[i+1], args[i+2]) {
+				return replyElement{err: redis.Error("WRONGPASS invalid username-password pair or user is disabled.")}
+			}
+			i += 2
+		case option == "setname" && i+1 < len(args):
+			name = args[i+1]
+			i++
+		default:
+			return replyElement{err: redis.Error(fmt.Sprintf("ERR Syntax error in HELLO option '%s'", args[i]))}
+		}
+	}
+
+	if !c.authenticated {
+		return replyElement{err: redis.Error("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")}
+	}
+
+	c.mu.Lock()
+	c.writer.proto = proto
+	c.mu.Unlock()
+	c.conn.SetProtocol(proto)
+	c.name = name
+
+	return replyElement{reply: helloReply(proto, c.id, c.conn.Role() == "slave")}
+}
+
+// selectDB handles the SELECT command, validating the database index
+func (c *serverClient) selectDB(args []string) replyElement {
+	if len(args) != 1 {
+		return replyElement{err: errWrongArgs("select")}
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return replyElement{err: errNotInteger}
+	}
+	if index < 0 || index >= 16 {
+		return replyElement{err: redis.Error("ERR DB index is out of range")}
+	}
+	return replyElement{reply: "OK"}
+}
+
+// client handles the CLIENT SETNAME, GETNAME and ID commands. Other
+// subcommands are dispatched to the mocked connection, so ok is false
+func (c *serverClient) client(args []string) (reply replyElement, ok bool) {
+	if len(args) == 0 {
+		return replyElement{}, false
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "setname":
+		if len(args) != 2 {
+			return replyElement{err: errWrongArgs("client|setname")}, true
+		}
+		if strings.ContainsAny(args[1], " \n") {
+			return replyElement{err: redis.Error("ERR Client names cannot contain spaces, newlines or special characters.")}, true
+		}
+		c.name = args[1]
+		return replyElement{reply: "OK"}, true
+
+	case "getname":
+		if c.name == "" {
+			return replyElement{}, true
+		}
+		return replyElement{reply: []byte(c.name)}, true
+
+	case "id":
+		return replyElement{reply: c.id}, true
+	}
+	return replyElement{}, false
+}
+
+// serve executes a command received from the wire, returning all the replies
+// that it produced. No reply is returned when it was dropped (see Fault.Drop).
+// An error is returned when the connection must be disconnected, because it
+// was closed or a failure that isn't a Redis error was injected
+func (c *Conn) serve(commandName string, args []interface{}) ([]replyElement, error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	reply, ok := c.run(commandName, args)
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	reply.reply = c.protocolReply(reply.reply)
+	pushes := c.pushes
+	c.pushes = nil
+	return append([]replyElement{reply}, pushes...), nil
+}
+
+// takeMessages consumes the pending subscription messages
+func (c *Conn) takeMessages() []replyElement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := make([]replyElement, len(c.subResponses))
+	for i, message := range c.subResponses {
+		messages[i] = replyElement{reply: c.protocolReply(message.response), err: message.err}
+	}
+	c.subResponses = nil
+	return messages
+}
@@ -0,0 +1,358 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// newTestServer returns a server listening on a loopback port, closed when
+// the test finishes
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	s, err := NewServer("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// dialTestServer returns a redigo connection to the server, closed when the
+// test finishes
+func dialTestServer(t *testing.T, s *Server, options ...redis.DialOption) redis.Conn {
+	t.Helper()
+
+	options = append(options, redis.DialReadTimeout(5*time.Second))
+	conn, err := redis.Dial(s.Network(), s.Addr(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer(t *testing.T) {
+	s := newTestServer(t)
+	cmd := s.Command("GET", "key").Expect("value")
+	s.Command("EXPIRE", "key", 10).Expect(int64(1))
+	s.Command("HGETALL", "person").ExpectMap(map[string]string{"name": "alice"})
+	s.Command("INCR", NewAnyInt()).Expect(int64(2))
+
+	conn := dialTestServer(t, s)
+
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+
+	if value, err := redis.Int(conn.Do("EXPIRE", "key", 10)); err != nil || value != 1 {
+		t.Errorf("Unexpected EXPIRE reply %d (%v)", value, err)
+	}
+
+	if values, err := redis.StringMap(conn.Do("HGETALL", "person")); err != nil || values["name"] != "alice" {
+		t.Errorf("Unexpected HGETALL reply %v (%v)", values, err)
+	}
+
+	if value, err := redis.Int(conn.Do("INCR", 1)); err != nil || value != 2 {
+		t.Errorf("Unexpected INCR reply %d (%v)", value, err)
+	}
+
+	if _, err := conn.Do("GET", "other"); err == nil || !strings.HasPrefix(err.Error(), "ERR command GET") {
+		t.Errorf("Expected error reply for a command not registered and got '%v'", err)
+	}
+
+	if counter := s.Stats(cmd); counter != 1 {
+		t.Errorf("Expected GET to be called once and got %d", counter)
+	}
+
+	if conns := s.Conns(); len(conns) != 1 {
+		t.Errorf("Expected 1 client connection and got %d", len(conns))
+	}
+}
+
+func TestServerPipeline(t *testing.T) {
+	s := newTestServer(t)
+	s.UseKeySpace(NewKeySpace())
+
+	conn := dialTestServer(t, s)
+	conn.Send("SET", "counter", 10)
+	conn.Send("INCR", "counter")
+	conn.Send("GET", "counter")
+
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{"OK", int64(11), []byte("11")}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Errorf("Expected %#v and got %#v", expected, replies)
+	}
+
+	if snapshot := s.Snapshot(); snapshot["counter"].Value != "11" {
+		t.Errorf("Expected key space shared with the server and got %#v", snapshot)
+	}
+}
+
+func TestServerHandshake(t *testing.T) {
+	s := newTestServer(t)
+	s.SetPassword("secret")
+	s.Command("PING").Expect("PONG")
+
+	conn := dialTestServer(t, s,
+		redis.DialPassword("secret"),
+		redis.DialDatabase(3),
+		redis.DialClientName("worker"),
+	)
+
+	if value, err := redis.String(conn.Do("PING")); err != nil || value != "PONG" {
+		t.Errorf("Unexpected PING reply '%s' (%v)", value, err)
+	}
+
+	if name, err := redis.String(conn.Do("CLIENT", "GETNAME")); err != nil || name != "worker" {
+		t.Errorf("Unexpected client name '%s' (%v)", name, err)
+	}
+
+	if _, err := redis.Dial(s.Network(), s.Addr(), redis.DialPassword("wrong")); err == nil {
+		t.Error("Expected error for a wrong password")
+	}
+
+	if _, err := redis.Dial(s.Network(), s.Addr(), redis.DialPassword("secret"), redis.DialDatabase(20)); err == nil {
+		t.Error("Expected error for an invalid database")
+	}
+
+	unauthenticated := dialTestServer(t, s)
+	if _, err := unauthenticated.Do("PING"); !reflect.DeepEqual(err, NoAuthError()) {
+		t.Errorf("Expected NOAUTH error and got '%v'", err)
+	}
+
+	if value, err := redis.String(unauthenticated.Do("AUTH", "default", "secret")); err != nil || value != "OK" {
+		t.Errorf("Unexpected AUTH reply '%s' (%v)", value, err)
+	}
+
+	if value, err := redis.String(unauthenticated.Do("PING")); err != nil || value != "PONG" {
+		t.Errorf("Unexpected PING reply '%s' (%v)", value, err)
+	}
+}
+
+func TestServerPubSub(t *testing.T) {
+	s := newTestServer(t)
+	psc := redis.PubSubConn{Conn: dialTestServer(t, s)}
+
+	if err := psc.Subscribe("news"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sub, ok := psc.Receive().(redis.Subscription); !ok || sub.Channel != "news" || sub.Count != 1 {
+		t.Fatalf("Unexpected subscription %#v", sub)
+	}
+
+	if delivered := s.Publish("news", "hello"); delivered != 1 {
+		t.Errorf("Expected 1 delivered message and got %d", delivered)
+	}
+
+	message, ok := psc.Receive().(redis.Message)
+	if !ok || message.Channel != "news" || string(message.Data) != "hello" {
+		t.Errorf("Unexpected message %#v", message)
+	}
+}
+
+func TestServerRESP3(t *testing.T) {
+	s := newTestServer(t)
+	s.Command("GET", "missing").Expect(nil)
+
+	netConn, err := net.Dial(s.Network(), s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netConn.Close()
+	netConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(netConn)
+	readLine := func() string {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSuffix(line, "\r\n")
+	}
+
+	netConn.Write([]byte("HELLO 3\r\n"))
+	if line := readLine(); line != "%7" {
+		t.Fatalf("Expected RESP3 map with 7 fields and got '%s'", line)
+	}
+	for i := 0; i < 7; i++ {
+		readLine() // key length
+		readLine() // key
+		if line := readLine(); strings.HasPrefix(line, "$") {
+			readLine()
+		}
+	}
+
+	netConn.Write([]byte("*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n"))
+	if line := readLine(); line != "_" {
+		t.Errorf("Expected RESP3 null and got '%s'", line)
+	}
+
+	netConn.Write([]byte("SUBSCRIBE news\r\n"))
+	if line := readLine(); line != ">3" {
+		t.Errorf("Expected RESP3 push and got '%s'", line)
+	}
+
+	netConn.Write([]byte("HELLO 4\r\n"))
+	for i := 0; i < 5; i++ {
+		// remaining subscription confirmation
+		readLine()
+	}
+	if line := readLine(); line != "-NOPROTO unsupported protocol version" {
+		t.Errorf("Expected NOPROTO error and got '%s'", line)
+	}
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	s, err := NewServer("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Command("GET", "key").Expect("value")
+
+	conn, err := redis.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	s := newTestServer(t)
+	s.Command("GET", "key").Expect("value")
+	conn := dialTestServer(t, s)
+
+	if _, err := conn.Do("GET", "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Do("GET", "key"); err == nil {
+		t.Error("Expected error after closing the server")
+	}
+
+	if _, err := redis.Dial(s.Network(), s.Addr()); err == nil {
+		t.Error("Expected error dialing a closed server")
+	}
+}
+
+func TestServerCloseWhileDialing(t *testing.T) {
+	s := newTestServer(t)
+
+	// clients keep connecting while the server is closed
+	done := make(chan struct{})
+	dialed := make(chan struct{})
+	go func() {
+		defer close(dialed)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if netConn, err := net.Dial(s.Network(), s.Addr()); err == nil {
+				defer netConn.Close()
+			}
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	closed := make(chan error)
+	go func() { closed <- s.Close() }()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked by a client accepted while closing")
+	}
+	close(done)
+	<-dialed
+
+	for i, conn := range s.Conns() {
+		if conn.Err() == nil {
+			t.Errorf("Expected client %d to be disconnected", i)
+		}
+	}
+}
+
+func TestServerInvalidMultibulkLength(t *testing.T) {
+	s := newTestServer(t)
+
+	netConn, err := net.Dial(s.Network(), s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netConn.Close()
+	netConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	netConn.Write([]byte("*-1\r\n"))
+
+	reader := bufio.NewReader(netConn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "-ERR Protocol error: invalid multibulk length\r\n" {
+		t.Errorf("Expected protocol error and got '%s'", line)
+	}
+
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Error("Expected the connection to be closed after the protocol error")
+	}
+}
+
+func TestServerHistory(t *testing.T) {
+	s := newTestServer(t)
+	s.Command("SET", "key", "value").Expect("OK")
+	s.GenericCommand("GET").HandleCtx(func(call *Call) (interface{}, error) {
+		if len(call.History) != 1 || call.History[0].Name != "SET" {
+			return nil, fmt.Errorf("unexpected history %v", call.History)
+		}
+		return "value", nil
+	})
+	conn := dialTestServer(t, s)
+
+	if _, err := conn.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("Unexpected GET reply '%s' (%v)", value, err)
+	}
+
+	conns := s.Conns()
+	if len(conns) != 1 {
+		t.Fatalf("Expected 1 client and got %d", len(conns))
+	}
+	history := conns[0].History()
+	if len(history) != 2 || history[0].Name != "SET" || history[1].Name != "GET" {
+		t.Errorf("Unexpected client history %+v", history)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// commandSpec is the specification of a command in the Redis command table
+// (see commands.json)
+type commandSpec struct {
+	arity     int       // Number of arguments including the command name, negative values are the minimum
+	write     bool      // The command modifies the data set
+	keySpecs  []keySpec // Positions of the keys in the arguments
+	arguments []argSpec // Arguments accepted by the command
+}
+
+// keySpec describes the position of the keys in the arguments of a command
+type keySpec struct {
+	pos     int  // Position of the first key, where the command name is at 0
+	lastKey int  // Position of the last key relative to the first one, negative values are relative to the end
+	step    int  // Distance between the keys
+	keyNum  bool // The number of keys is the argument at pos, followed by the keys
+}
+
+// argSpec is the specification of an argument of a command
+type argSpec struct {
+	name      string    // Name of the argument
+	kind      string    // Type of the argument, like key, integer, pure-token, oneof or block
+	token     string    // Token that precedes the argument value, or the token itself for pure-token
+	optional  bool      // The argument can be omitted
+	multiple  bool      // The argument can be repeated
+	arguments []argSpec // Alternatives of oneof or the arguments of block
+}
+
+// validating checks if the commands should be validated, falling back to the
+// connection that it shares the registry with (see Pool)
+//
+// Caller must hold c.mu.
+func (c *Conn) validating() bool {
+	return fromRegistry(c, func(conn *Conn) bool {
+		return conn.ValidateCommands
+	})
+}
+
+// validateCommand checks the arguments of the command against its
+// specification. Commands missing from the Redis command table aren't
+// validated
+func validateCommand(commandName string, args []interface{}) error {
+	spec, ok := commandSpecs[strings.ToUpper(commandName)]
+	if !ok {
+		return nil
+	}
+
+	if err := spec.validate(args); err != nil {
+		return fmt.Errorf("command %s with arguments %#v doesn't match the Redis command table: %s",
+			commandName, args, err)
+	}
+	return nil
+}
+
+// validate checks the number of arguments, the key positions and the
+// arguments against the specification
+func (spec *commandSpec) validate(args []interface{}) error {
+	n := len(args) + 1
+	if (spec.arity > 0 && n != spec.arity) || (spec.arity < 0 && n < -spec.arity) {
+		return fmt.Errorf("wrong number of arguments")
+	}
+
+	for _, ks := range spec.keySpecs {
+		if err := ks.validate(args); err != nil {
+			return err
+		}
+	}
+
+	m := argMatcher{args: args, failPos: -1}
+	ends := m.sequence(spec.arguments, 0)
+
+	maxEnd := -1
+	for _, end := range ends {
+		if end == len(args) {
+			return nil
+		}
+		if end > maxEnd {
+			maxEnd = end
+		}
+	}
+
+	if m.failPos > maxEnd || maxEnd < 0 {
+		return fmt.Errorf("%s", m.failMsg)
+	}
+	return fmt.Errorf("unexpected argument %q", argString(args[maxEnd]))
+}
+
+// validate checks that the keys are in the arguments
+func (ks keySpec) validate(args []interface{}) error {
+	first := ks.pos - 1
+	if first >= len(args) {
+		return fmt.Errorf("missing key at position %d", ks.pos)
+	}
+
+	if ks.keyNum {
+		if implementsFuzzy(args[first]) {
+			return nil
+		}
+
+		n, err := strconv.Atoi(argString(args[first]))
+		switch {
+		case err != nil:
+			return fmt.Errorf("number of keys must be an integer")
+		case n < 0:
+			return fmt.Errorf("number of keys can't be negative")
+		case first+1+n > len(args):
+			return fmt.Errorf("number of keys can't be greater than number of arguments")
+		}
+		return nil
+	}
+
+	if ks.lastKey < 0 && ks.step > 1 && (len(args)-first+ks.lastKey+1)%ks.step != 0 {
+		return fmt.Errorf("wrong number of arguments")
+	}
+	return nil
+}
+
+// keys returns the keys in the arguments. Keys beyond the arguments are
+// ignored, as they are reported by validate
+func (ks keySpec) keys(args []interface{}) []string {
+	first := ks.pos - 1
+	if first >= len(args) {
+		return nil
+	}
+
+	last, step := first+ks.lastKey, ks.step
+	if ks.lastKey < 0 {
+		last = len(args) + ks.lastKey
+	}
+	if ks.keyNum {
+		n, err := strconv.Atoi(argString(args[first]))
+		if err != nil || n < 0 {
+			return nil
+		}
+		first, last, step = first+1, first+n, 1
+	}
+	if last >= len(args) {
+		last = len(args) - 1
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	var keys []string
+	for i := first; i <= last; i += step {
+		keys = append(keys, argString(args[i]))
+	}
+	return keys
+}
+
+// argMatcher matches the arguments of a command against the specification,
+// keeping the failure found at the furthest position to describe it
+type argMatcher struct {
+	args    []interface{}
+	failPos int
+	failMsg string
+}
+
+// fail records the reason why the argument at the position doesn't match
+func (m *argMatcher) fail(pos int, format string, a ...interface{}) {
+	if pos >= m.failPos {
+		m.failPos = pos
+		m.failMsg = fmt.Sprintf(format, a...)
+	}
+}
+
+// sequence returns the positions where the arguments can end, starting at
+// pos. Consecutive optional arguments are accepted in any order, like the
+// Redis server does with options
+func (m *argMatcher) sequence(specs []argSpec, pos int) []int {
+	positions := []int{pos}
+	for i := 0; i < len(specs) && len(positions) > 0; i++ {
+		if specs[i].optional {
+			j := i
+			for j < len(specs) && specs[j].optional {
+				j++
+			}
+			positions = m.unordered(specs[i:j], positions)
+			i = j - 1
+			continue
+		}
+
+		spec := specs[i]
+		var next []int
+		for _, p := range positions {
+			next = append(next, m.repeated(spec, p)...)
+		}
+		positions = uniquePositions(next)
+	}
+	return positions
+}
+
+// unordered returns the positions where the optional arguments can end, each
+// one used at most once in any order
+func (m *argMatcher) unordered(specs []argSpec, positions []int) []int {
+	type state struct {
+		pos  int
+		used int
+	}
+
+	queue := make([]state, 0, len(positions))
+	for _, p := range positions {
+		queue = append(queue, state{pos: p})
+	}
+
+	visited := make(map[state]bool)
+	var ends []int
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if visited[s] {
+			continue
+		}
+		visited[s] = true
+		ends = append(ends, s.pos)
+
+		for i, spec := range specs {
+			if s.used&(1<<uint(i)) != 0 {
+				continue
+			}
+			for _, end := range m.repeated(spec, s.pos) {
+				if end > s.pos {
+					queue = append(queue, state{pos: end, used: s.used | 1<<uint(i)})
+				}
+			}
+		}
+	}
+	return uniquePositions(ends)
+}
+
+// repeated returns the positions where the argument can end, repeating it
+// when it accepts multiple values
+func (m *argMatcher) repeated(spec argSpec, pos int) []int {
+	ends := m.single(spec, pos)
+	if !spec.multiple {
+		return ends
+	}
+
+	visited := make(map[int]bool)
+	for i := 0; i < len(ends); i++ {
+		if visited[ends[i]] {
+			continue
+		}
+		visited[ends[i]] = true
+
+		for _, end := range m.single(spec, ends[i]) {
+			if end > ends[i] {
+				ends = append(ends, end)
+			}
+		}
+	}
+	return uniquePositions(ends)
+}
+
+// single returns the positions where one value of the argument can end
+func (m *argMatcher) single(spec argSpec, pos int) []int {
+	if spec.token != "" {
+		if pos >= len(m.args) {
+			m.fail(pos, "missing %s", spec.token)
+			return nil
+		}
+		if arg := m.args[pos]; !implementsFuzzy(arg) && !strings.EqualFold(argString(arg), spec.token) {
+			m.fail(pos, "unexpected argument %q", argString(arg))
+			return nil
+		}
+		pos++
+	}
+
+	switch spec.kind {
+	case "pure-token":
+		return []int{pos}
+	case "oneof":
+		var ends []int
+		for _, alternative := range spec.arguments {
+			ends = append(ends, m.repeated(alternative, pos)...)
+		}
+		return uniquePositions(ends)
+	case "block":
+		return m.sequence(spec.arguments, pos)
+	}
+
+	if pos >= len(m.args) {
+		m.fail(pos, "missing argument %s", spec.name)
+		return nil
+	}
+
+	arg := m.args[pos]
+	if implementsFuzzy(arg) {
+		return []int{pos + 1}
+	}
+
+	switch spec.kind {
+	case "integer", "unix-time":
+		if _, err := strconv.ParseInt(argString(arg), 10, 64); err != nil {
+			m.fail(pos, "argument %s must be an integer, got %q", spec.name, argString(arg))
+			return nil
+		}
+	case "double":
+		if _, err := strconv.ParseFloat(argString(arg), 64); err != nil {
+			m.fail(pos, "argument %s must be a number, got %q", spec.name, argString(arg))
+			return nil
+		}
+	}
+	return []int{pos + 1}
+}
+
+// uniquePositions removes the repeated positions, sorting them
+func uniquePositions(positions []int) []int {
+	sort.Ints(positions)
+
+	var unique []int
+	for i, p := range positions {
+		if i == 0 || p != positions[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
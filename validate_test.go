@@ -0,0 +1,103 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redigomock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCommand(t *testing.T) {
+	data := []struct {
+		name string
+		args []interface{}
+		err  string
+	}{
+		{name: "GET", args: []interface{}{"k"}},
+		{name: "get", args: []interface{}{"k", "other"}, err: "wrong number of arguments"},
+		{name: "SETEX", args: []interface{}{"k", 10, "v"}},
+		{name: "SETEX", args: []interface{}{"k", "v", 10}, err: `argument seconds must be an integer, got "v"`},
+		{name: "SET", args: []interface{}{"k", "v"}},
+		{name: "SET", args: []interface{}{"k", "v", "NX", "EX", 10}},
+		{name: "SET", args: []interface{}{"k", "v", "ex", 10, "nx", "GET"}},
+		{name: "SET", args: []interface{}{"k", "v", "EX", "ten"}, err: `argument seconds must be an integer, got "ten"`},
+		{name: "SET", args: []interface{}{"k", "v", "EX"}, err: "missing argument seconds"},
+		{name: "SET", args: []interface{}{"k", "v", "NX", "NX"}, err: `unexpected argument "NX"`},
+		{name: "SET", args: []interface{}{"k", "v", "FOREVER"}, err: `unexpected argument "FOREVER"`},
+		{name: "MSET", args: []interface{}{"a", 1, "b", 2}},
+		{name: "MSET", args: []interface{}{"a", 1, "b"}, err: "wrong number of arguments"},
+		{name: "HSET", args: []interface{}{"h", "f1", "v1", "f2", "v2"}},
+		{name: "HSET", args: []interface{}{"h", "f1", "v1", "f2"}, err: "missing argument value"},
+		{name: "ZADD", args: []interface{}{"z", "CH", "NX", 1.5, "a", 2, "b"}},
+		{name: "ZADD", args: []interface{}{"z", "a", 1}, err: `argument score must be a number, got "a"`},
+		{name: "ZRANGEBYSCORE", args: []interface{}{"z", "-inf", "(10", "LIMIT", 0, 10, "WITHSCORES"}},
+		{name: "ZRANGEBYSCORE", args: []interface{}{"z", "-inf", "+inf", "LIMIT", 0}, err: "missing argument count"},
+		{name: "BLPOP", args: []interface{}{"a", "b", 0.5}},
+		{name: "EXPIRE", args: []interface{}{"k", NewAnyInt(), "XX"}},
+		{name: "EVAL", args: []interface{}{"return 1", 2, "a", "b", "arg"}},
+		{name: "EVAL", args: []interface{}{"return 1", 3, "a", "b"}, err: "number of keys can't be greater than number of arguments"},
+		{name: "EVAL", args: []interface{}{"return 1", -1, "a"}, err: "number of keys can't be negative"},
+		{name: "DBSIZE", args: []interface{}{"now"}, err: "wrong number of arguments"},
+		{name: "UNKNOWN", args: []interface{}{"anything"}},
+	}
+
+	for _, item := range data {
+		err := validateCommand(item.name, item.args)
+		if item.err == "" {
+			if err != nil {
+				t.Errorf("Unexpected error for %s %v: %s", item.name, item.args, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.HasSuffix(err.Error(), ": "+item.err) {
+			t.Errorf("Expected error '%s' for %s %v and got %v", item.err, item.name, item.args, err)
+		}
+	}
+}
+
+func TestValidateCommands(t *testing.T) {
+	c := NewConn()
+	c.ValidateCommands = true
+	c.Command("SETEX", "k", "v", 10).Expect("OK")
+	c.Command("GET", "k").Expect("v")
+	c.GenericCommand("SET").Expect("OK")
+
+	errs := c.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "command SETEX") {
+		t.Fatalf("Expected error for the registered SETEX command and got %v", errs)
+	}
+
+	if _, err := c.Do("GET", "k"); err != nil {
+		t.Errorf("Unexpected GET error %v", err)
+	}
+	if _, err := c.Do("SET", "k", "v", "EX"); err == nil {
+		t.Error("Expected error for invalid SET command")
+	}
+	if _, err := c.Do("SET", "k", "v", "EX", 10); err != nil {
+		t.Errorf("Unexpected SET error %v", err)
+	}
+
+	if errs := c.Errors(); len(errs) != 2 {
+		t.Errorf("Expected 2 errors and got %v", errs)
+	}
+}
+
+func TestValidateCommandsPool(t *testing.T) {
+	mockPool := NewPool()
+	mockPool.ValidateCommands = true
+	mockPool.GenericCommand("INCRBY").Expect(int64(1))
+
+	conn, err := mockPool.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("INCRBY", "k", "one"); err == nil {
+		t.Error("Expected error for invalid INCRBY command")
+	}
+	if err := mockPool.ExpectationsWereMet(); err == nil {
+		t.Error("Expected the invalid command to be reported")
+	}
+}